@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/bevly/bevly/model"
+	"github.com/bevly/bevly/repository"
+)
+
+// exportData is the configuration and curation a repository can't rebuild
+// by syncing: its providers, and staff overrides. Overrides name their
+// beverage, since beverage IDs differ between repositories.
+type exportData struct {
+	Providers []exportProvider `json:"providers"`
+	Overrides []exportOverride `json:"overrides"`
+}
+
+type exportProvider struct {
+	ID      string            `json:"id"`
+	Name    string            `json:"name"`
+	URL     string            `json:"url"`
+	Format  string            `json:"format"`
+	Options map[string]string `json:"options,omitempty"`
+}
+
+type exportOverride struct {
+	Drink       string            `json:"drink"`
+	Fields      map[string]string `json:"fields,omitempty"`
+	ProfileURLs map[string]string `json:"profileUrls,omitempty"`
+	Blocked     []string          `json:"blocked,omitempty"`
+	Note        string            `json:"note,omitempty"`
+	UpdatedAt   time.Time         `json:"updatedAt"`
+}
+
+func exportCommand(args []string) int {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	verbose := flags.Bool("v", false, "log repository access to stderr")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s export [-v] [file]\n\nWrites to stdout if no file is given.\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	setVerbose(*verbose)
+
+	out := io.Writer(os.Stdout)
+	if flags.NArg() > 0 {
+		file, err := os.Create(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "export: %s\n", err)
+			return 1
+		}
+		defer file.Close()
+		out = file
+	}
+
+	data := exportRepository(openRepository())
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(data); err != nil {
+		fmt.Fprintf(os.Stderr, "export: %s\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d providers and %d overrides\n",
+		len(data.Providers), len(data.Overrides))
+	return 0
+}
+
+func exportRepository(repo repository.Repository) exportData {
+	data := exportData{Providers: []exportProvider{}, Overrides: []exportOverride{}}
+	for _, provider := range repo.MenuProviders() {
+		data.Providers = append(data.Providers, exportProvider{
+			ID:      provider.ID(),
+			Name:    provider.Name(),
+			URL:     provider.URL(),
+			Format:  provider.MenuFormat(),
+			Options: provider.Options(),
+		})
+	}
+	for _, override := range repo.Overrides() {
+		bev := repo.BeverageByID(override.BeverageID)
+		if bev == nil {
+			fmt.Fprintf(os.Stderr, "export: skipping override of missing beverage %s\n", override.BeverageID)
+			continue
+		}
+		data.Overrides = append(data.Overrides, exportOverride{
+			Drink:       bev.DisplayName(),
+			Fields:      override.Fields,
+			ProfileURLs: override.ProfileURLs,
+			Blocked:     override.Blocked,
+			Note:        override.Note,
+			UpdatedAt:   override.UpdatedAt,
+		})
+	}
+	return data
+}
+
+func importCommand(args []string) int {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	verbose := flags.Bool("v", false, "log repository access to stderr")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s import [-v] [file]\n\nReads from stdin if no file is given. "+
+			"Providers and overrides replace those with the same ID or beverage.\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	setVerbose(*verbose)
+
+	in := io.Reader(os.Stdin)
+	if flags.NArg() > 0 {
+		file, err := os.Open(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "import: %s\n", err)
+			return 1
+		}
+		defer file.Close()
+		in = file
+	}
+	var data exportData
+	if err := json.NewDecoder(in).Decode(&data); err != nil {
+		fmt.Fprintf(os.Stderr, "import: %s\n", err)
+		return 1
+	}
+
+	repo := openRepository()
+	failed := 0
+	for _, p := range data.Providers {
+		provider := model.CreateMenuProvider(p.ID, p.Name, p.URL, p.Format)
+		for name, value := range p.Options {
+			provider.SetOption(name, value)
+		}
+		if err := repo.SaveProvider(provider); err != nil {
+			fmt.Fprintf(os.Stderr, "import: provider %s: %s\n", p.ID, err)
+			failed++
+		}
+	}
+	for _, o := range data.Overrides {
+		if err := importOverride(repo, o); err != nil {
+			fmt.Fprintf(os.Stderr, "import: override of %s: %s\n", o.Drink, err)
+			failed++
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Imported %d providers and %d overrides with %d errors\n",
+		len(data.Providers), len(data.Overrides), failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// importOverride saves an override of the beverage it names, adding the
+// beverage if the repository doesn't have it yet.
+func importOverride(repo repository.Repository, o exportOverride) error {
+	bev := repo.BeverageByName(o.Drink)
+	if bev == nil {
+		repo.SaveBeverage(model.CreateBeverage(o.Drink))
+		if bev = repo.BeverageByName(o.Drink); bev == nil {
+			return repository.ErrNoSuchBeverage
+		}
+	}
+	override := &model.Override{
+		BeverageID:  bev.ID(),
+		Fields:      o.Fields,
+		ProfileURLs: o.ProfileURLs,
+		Blocked:     o.Blocked,
+		Note:        o.Note,
+	}
+	if err := override.Validate(); err != nil {
+		return err
+	}
+	return repo.SaveOverride(override)
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bevly/bevly/fetch/metadata"
+	"github.com/bevly/bevly/fetch/metadata/beeradvocate"
+	"github.com/bevly/bevly/fetch/metadata/openbrewerydb"
+	"github.com/bevly/bevly/model"
+	bevsync "github.com/bevly/bevly/sync"
+)
+
+// sourceAliases names sources by more than their registered names, which
+// are otherwise matched regardless of case.
+var sourceAliases = map[string]string{
+	"beeradvocate": beeradvocate.Source,
+}
+
+func lookupCommand(args []string) int {
+	flags := flag.NewFlagSet("lookup", flag.ExitOnError)
+	sources := flags.String("sources", os.Getenv(metadata.SourcesEnv),
+		"comma-separated metadata sources to fetch from, in order; all if empty: "+
+			strings.Join(metadata.DefaultSourceOrder, ","))
+	search := flags.String("search", os.Getenv(metadata.SearchEnv),
+		"search backend, optionally per source, in the form of "+metadata.SearchEnv)
+	scores := flags.String("scores", os.Getenv(metadata.ScoresEnv),
+		"source accuracy scores, in the form of "+metadata.ScoresEnv)
+	brewer := flags.String("brewer", "", "the beverage's brewer, if its name lacks it")
+	jsonOutput := flags.Bool("json", false, "print the result as JSON")
+	save := flags.Bool("save", false, "sync the beverage as stored in the repository, and save it")
+	verbose := flags.Bool("v", false, "log searches and fetches to stderr")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s lookup [flags] <beverage name>\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	setVerbose(*verbose)
+
+	config := metadata.ParseConfig(*sources, *search, *scores)
+	names, err := sourceNames(config.Sources)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lookup: %s\n", err)
+		return 2
+	}
+	config.Sources = names
+	fetcher := metadata.NewFetcher(config)
+
+	name := strings.Join(flags.Args(), " ")
+	var bev model.Beverage
+	var report *metadata.Report
+	if *save {
+		repo := openRepository()
+		if bev = repo.BeverageByName(name); bev == nil {
+			bev = createBeverage(name, *brewer)
+		}
+		breweryLookup, err := openbrewerydb.Default()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "lookup: brewery enrichment disabled: %s\n", err)
+		}
+		report = bevsync.SyncBeverage(repo, fetcher, bev, breweryLookup)
+	} else {
+		bev = createBeverage(name, *brewer)
+		report = fetcher.Fetch(bev, nil)
+	}
+
+	result := lookupResultModel(bev, report)
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(result); err != nil {
+			fmt.Fprintf(os.Stderr, "Can't write JSON: %s\n", err)
+			return 1
+		}
+	} else {
+		printLookupResult(os.Stdout, result)
+	}
+	if report.Err() != nil {
+		return 1
+	}
+	return 0
+}
+
+func createBeverage(name, brewer string) model.Beverage {
+	if brewer != "" {
+		return model.CreateBeverageBrewer(name, brewer)
+	}
+	return model.CreateBeverage(name)
+}
+
+// sourceNames resolves the names of sources given on the command line to
+// their registered names.
+func sourceNames(names []string) ([]string, error) {
+	resolved := []string{}
+	for _, name := range names {
+		if alias, ok := sourceAliases[strings.ToLower(name)]; ok {
+			name = alias
+		}
+		found := ""
+		for _, registered := range metadata.DefaultSourceOrder {
+			if strings.EqualFold(name, registered) {
+				found = registered
+			}
+		}
+		if found == "" {
+			return nil, errors.New("unknown metadata source " + strconv.Quote(name))
+		}
+		resolved = append(resolved, found)
+	}
+	return resolved, nil
+}
+
+type sourceInfo struct {
+	Source   string `json:"source"`
+	Search   string `json:"search"`
+	Outcome  string `json:"outcome"`
+	Skipped  string `json:"skipped,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+type candidateInfo struct {
+	Source      string  `json:"source"`
+	URL         string  `json:"url"`
+	Title       string  `json:"title"`
+	Confidence  float64 `json:"confidence"`
+	Explanation string  `json:"explanation"`
+	Chosen      bool    `json:"chosen"`
+	Pending     bool    `json:"pending"`
+}
+
+type fieldInfo struct {
+	Field  string `json:"field"`
+	Value  string `json:"value"`
+	Source string `json:"source,omitempty"`
+}
+
+type lookupResult struct {
+	Query      string            `json:"query"`
+	ID         string            `json:"id,omitempty"`
+	Sources    []sourceInfo      `json:"sources"`
+	Candidates []candidateInfo   `json:"candidates"`
+	Fields     []fieldInfo       `json:"fields"`
+	Category   string            `json:"category,omitempty"`
+	Ratings    map[string]int    `json:"ratings"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+func lookupResultModel(bev model.Beverage, report *metadata.Report) lookupResult {
+	result := lookupResult{
+		Query:      bev.DisplayName(),
+		ID:         bev.ID(),
+		Sources:    []sourceInfo{},
+		Candidates: []candidateInfo{},
+		Fields:     []fieldInfo{},
+		Category:   bev.Category(),
+		Ratings:    map[string]int{},
+		Attributes: bev.Attributes(),
+	}
+	for _, r := range report.Results {
+		info := sourceInfo{Source: r.Source, Search: r.Search, Outcome: "fetched",
+			Duration: r.Duration.Round(time.Millisecond).String()}
+		switch {
+		case r.Skipped != "":
+			info.Outcome, info.Skipped = "skipped", r.Skipped
+		case r.Err != nil:
+			info.Outcome, info.Error = "failed", r.Err.Error()
+		}
+		result.Sources = append(result.Sources, info)
+	}
+	for _, c := range bev.ProfileCandidates() {
+		result.Candidates = append(result.Candidates, candidateInfo{
+			Source:      c.Source,
+			URL:         c.URL,
+			Title:       c.Title,
+			Confidence:  c.Confidence,
+			Explanation: c.Explanation,
+			Chosen:      c.Chosen,
+			Pending:     c.Pending,
+		})
+	}
+	for _, field := range model.ProvenanceFields {
+		value := model.FieldText(bev, field)
+		if field == model.FieldAbv && bev.HasAbv() {
+			value = strconv.FormatFloat(bev.Abv(), 'f', -1, 64)
+		}
+		if value != "" {
+			result.Fields = append(result.Fields, fieldInfo{
+				Field: field, Value: value, Source: bev.Provenance(field).Source})
+		}
+	}
+	for _, rating := range bev.Ratings() {
+		result.Ratings[rating.Source()] = rating.PercentageRating()
+	}
+	return result
+}
+
+func printLookupResult(out io.Writer, result lookupResult) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\n\nSources:\n", result.Query)
+	for _, s := range result.Sources {
+		switch s.Outcome {
+		case "skipped":
+			fmt.Fprintf(w, "  %s\tskipped\t%s\n", s.Source, s.Skipped)
+		case "failed":
+			fmt.Fprintf(w, "  %s\tfailed in %s\t%s\n", s.Source, s.Duration, s.Error)
+		default:
+			fmt.Fprintf(w, "  %s\tfetched in %s\t\n", s.Source, s.Duration)
+		}
+	}
+
+	if len(result.Candidates) > 0 {
+		fmt.Fprintf(w, "\nCandidates (* chosen, ? pending review):\n")
+		for _, c := range result.Candidates {
+			mark := " "
+			if c.Chosen {
+				mark = "*"
+			} else if c.Pending {
+				mark = "?"
+			}
+			fmt.Fprintf(w, "  %s %s\t%.2f\t%s\t%s\n", mark, c.Source, c.Confidence, c.URL, c.Title)
+		}
+	}
+
+	fmt.Fprintf(w, "\nBeverage:\n")
+	for _, f := range result.Fields {
+		source := ""
+		if f.Source != "" {
+			source = "(" + f.Source + ")"
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", f.Field, strings.Join(strings.Fields(f.Value), " "), source)
+	}
+	if result.Category != "" {
+		fmt.Fprintf(w, "  category\t%s\t\n", result.Category)
+	}
+	for _, source := range sortedKeys(result.Ratings) {
+		fmt.Fprintf(w, "  rating\t%d\t(%s)\n", result.Ratings[source], source)
+	}
+	w.Flush()
+}
+
+func sortedKeys(ratings map[string]int) []string {
+	keys := []string{}
+	for key := range ratings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
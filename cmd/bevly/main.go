@@ -0,0 +1,74 @@
+// Command bevly runs bevly's maintenance tasks from the command line,
+// against the repository at MONGO_HOST:
+//
+//	bevly lookup [flags] <beverage name>
+//	bevly providers list|add|set|remove
+//	bevly sync [-provider id,...] [-dry-run]
+//	bevly menu show <provider id>
+//	bevly gc
+//	bevly purge -confirm
+//	bevly export [file]
+//	bevly import [file]
+//
+// Run a command with -h for its flags.
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/bevly/bevly/repository"
+	"github.com/bevly/bevly/repository/mongorepo"
+)
+
+type command struct {
+	name    string
+	summary string
+	// run runs the command with the arguments following its name, and
+	// returns the exit status.
+	run func(args []string) int
+}
+
+var commands = []command{
+	{"lookup", "fetch a beverage's metadata from metadata sources", lookupCommand},
+	{"providers", "list, add, configure or remove menu providers", providersCommand},
+	{"sync", "sync menus and their beverages' metadata", syncCommand},
+	{"menu", "show a provider's stored menu", menuCommand},
+	{"gc", "discard beverages no menu lists", gcCommand},
+	{"purge", "delete everything in the repository", purgeCommand},
+	{"export", "write providers and overrides as JSON", exportCommand},
+	{"import", "read providers and overrides written by export", importCommand},
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		for _, cmd := range commands {
+			if cmd.name == os.Args[1] {
+				os.Exit(cmd.run(os.Args[2:]))
+			}
+		}
+	}
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags] [arguments]\n\nCommands:\n", os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+}
+
+// setVerbose shows the log of sources and searches, which is otherwise
+// discarded to keep output readable.
+func setVerbose(verbose bool) {
+	if !verbose {
+		log.SetOutput(ioutil.Discard)
+	}
+}
+
+func openRepository() repository.Repository {
+	return mongorepo.DefaultRepository()
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func gcCommand(args []string) int {
+	flags := flag.NewFlagSet("gc", flag.ExitOnError)
+	verbose := flags.Bool("v", false, "log repository access to stderr")
+	flags.Parse(args)
+	setVerbose(*verbose)
+
+	openRepository().GarbageCollect()
+	fmt.Println("Discarded beverages no menu lists that have not changed recently")
+	return 0
+}
+
+func purgeCommand(args []string) int {
+	flags := flag.NewFlagSet("purge", flag.ExitOnError)
+	confirm := flags.Bool("confirm", false, "confirm deleting every provider, beverage, brewery and override")
+	verbose := flags.Bool("v", false, "log repository access to stderr")
+	flags.Parse(args)
+	setVerbose(*verbose)
+	if !*confirm {
+		fmt.Fprintf(os.Stderr, "purge deletes everything in the repository; run with -confirm to do it\n")
+		return 2
+	}
+
+	openRepository().Purge()
+	fmt.Println("Purged the repository")
+	return 0
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bevly/bevly/httpfilestub"
+	"github.com/bevly/bevly/model"
+	bevsync "github.com/bevly/bevly/sync"
+)
+
+func menuCommand(args []string) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "show":
+			return showMenu(args[1:])
+		case "diff":
+			return diffMenu(args[1:])
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Usage: %s menu show|diff [flags] <provider id>\n", os.Args[0])
+	return 2
+}
+
+// showMenu prints a provider's stored menu, by section in menu order.
+func showMenu(args []string) int {
+	flags := flag.NewFlagSet("menu show", flag.ExitOnError)
+	verbose := flags.Bool("v", false, "log repository access to stderr")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s menu show [-v] <provider id>\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	setVerbose(*verbose)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	repo := openRepository()
+	provider := repo.ProviderByID(flags.Arg(0))
+	if provider == nil {
+		fmt.Fprintf(os.Stderr, "menu show: no provider %q\n", flags.Arg(0))
+		return 1
+	}
+	fmt.Printf("%s (%s): %s\n", provider.Name(), provider.ID(), provider.URL())
+	sections := []string{}
+	bySection := map[string][]model.Beverage{}
+	for _, beverage := range repo.ProviderBeverages(provider) {
+		section := beverage.MenuSection()
+		if _, seen := bySection[section]; !seen {
+			sections = append(sections, section)
+		}
+		bySection[section] = append(bySection[section], beverage)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, section := range sections {
+		fmt.Fprintf(w, "\n%s\n", sectionName(section))
+		for _, beverage := range bySection[section] {
+			abv := ""
+			if beverage.HasAbv() {
+				abv = strconv.FormatFloat(beverage.Abv(), 'f', -1, 64) + "%"
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", beverage.Tap(), beverage.DisplayName(),
+				beverage.Brewer(), abv, beverage.Type())
+		}
+	}
+	w.Flush()
+	return 0
+}
+
+// diffMenu fetches a provider's menu, from its page, another URL or a saved
+// copy, and prints the beverages parsed and how they differ from the stored
+// menu. Nothing is saved.
+func diffMenu(args []string) int {
+	flags := flag.NewFlagSet("menu diff", flag.ExitOnError)
+	url := flags.String("url", "", "fetch the menu from this URL instead of the provider's")
+	fixture := flags.String("fixture", "", "fetch the menu from this saved copy of the provider's page")
+	jsonOutput := flags.Bool("json", false, "print the beverages and diff as JSON")
+	verbose := flags.Bool("v", false, "log the menu fetch to stderr")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s menu diff [flags] <provider id>\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	setVerbose(*verbose)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	repo := openRepository()
+	provider := repo.ProviderByID(flags.Arg(0))
+	if provider == nil {
+		fmt.Fprintf(os.Stderr, "menu diff: no provider %q\n", flags.Arg(0))
+		return 1
+	}
+	if *fixture != "" {
+		if _, err := os.Stat(*fixture); err != nil {
+			fmt.Fprintf(os.Stderr, "menu diff: %s\n", err)
+			return 1
+		}
+		server := httpfilestub.Server(*fixture)
+		defer server.Close()
+		*url = server.URL
+	}
+	if *url != "" {
+		provider = model.CreateMenuProviderAt(provider, *url)
+	}
+
+	fetched, diff := bevsync.PreviewMenu(repo, provider)
+	if fetched.Err != nil {
+		fmt.Fprintf(os.Stderr, "menu diff: fetching %s failed: %s\n", provider.URL(), fetched.Err)
+		return 1
+	}
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(menuPreviewModel(fetched, diff)); err != nil {
+			fmt.Fprintf(os.Stderr, "Can't write JSON: %s\n", err)
+			return 1
+		}
+		return 0
+	}
+	fmt.Printf("%s (%s): %d beverages from %s\n\n", provider.Name(), provider.ID(),
+		len(fetched.Beverages), provider.URL())
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, beverage := range fetched.Beverages {
+		printMenuBeverage(w, " ", beverage)
+	}
+	w.Flush()
+	fmt.Println()
+	printMenuDiff(os.Stdout, diff)
+	return 0
+}
+
+func printMenuBeverage(w io.Writer, mark string, beverage model.Beverage) {
+	abv := ""
+	if beverage.HasAbv() {
+		abv = strconv.FormatFloat(beverage.Abv(), 'f', -1, 64) + "%"
+	}
+	fmt.Fprintf(w, " %s %s\t%s\t%s\t%s\t%s\n", mark, beverage.DisplayName(),
+		beverage.Brewer(), abv, beverage.Type(), beverage.MenuSection())
+}
+
+// printMenuDiff prints added beverages marked "+", removed ones marked "-",
+// and changed ones marked "~".
+func printMenuDiff(out io.Writer, diff bevsync.MenuDiff) {
+	if diff.Empty() {
+		fmt.Fprintf(out, "No changes to the stored menu (%d beverages)\n", diff.Unchanged)
+		return
+	}
+	fmt.Fprintf(out, "%d added, %d removed, %d changed, %d unchanged\n",
+		len(diff.Added), len(diff.Removed), len(diff.Changed), diff.Unchanged)
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, beverage := range diff.Added {
+		printMenuBeverage(w, "+", beverage)
+	}
+	for _, beverage := range diff.Removed {
+		printMenuBeverage(w, "-", beverage)
+	}
+	for _, change := range diff.Changed {
+		fields := []string{}
+		for _, field := range change.Changes {
+			fields = append(fields, fmt.Sprintf("%s %q -> %q", field.Field, field.Stored, field.Fetched))
+		}
+		fmt.Fprintf(w, " ~ %s\t%s\n", change.Name, strings.Join(fields, ", "))
+	}
+	w.Flush()
+}
+
+type menuBeverageInfo struct {
+	Name    string  `json:"name"`
+	Brewer  string  `json:"brewer,omitempty"`
+	Abv     float64 `json:"abv,omitempty"`
+	Type    string  `json:"type,omitempty"`
+	Section string  `json:"section,omitempty"`
+	Tap     string  `json:"tap,omitempty"`
+}
+
+type fieldChangeInfo struct {
+	Field   string `json:"field"`
+	Stored  string `json:"stored"`
+	Fetched string `json:"fetched"`
+}
+
+type beverageChangeInfo struct {
+	Name    string            `json:"name"`
+	Changes []fieldChangeInfo `json:"changes"`
+}
+
+type menuPreview struct {
+	Provider  string               `json:"provider"`
+	URL       string               `json:"url"`
+	Beverages []menuBeverageInfo   `json:"beverages"`
+	Added     []menuBeverageInfo   `json:"added"`
+	Removed   []menuBeverageInfo   `json:"removed"`
+	Changed   []beverageChangeInfo `json:"changed"`
+	Unchanged int                  `json:"unchanged"`
+}
+
+func menuPreviewModel(fetched bevsync.MenuFetch, diff bevsync.MenuDiff) menuPreview {
+	preview := menuPreview{
+		Provider:  fetched.Provider.ID(),
+		URL:       fetched.Provider.URL(),
+		Beverages: menuBeverageInfos(fetched.Beverages),
+		Added:     menuBeverageInfos(diff.Added),
+		Removed:   menuBeverageInfos(diff.Removed),
+		Changed:   []beverageChangeInfo{},
+		Unchanged: diff.Unchanged,
+	}
+	for _, change := range diff.Changed {
+		info := beverageChangeInfo{Name: change.Name, Changes: []fieldChangeInfo{}}
+		for _, field := range change.Changes {
+			info.Changes = append(info.Changes, fieldChangeInfo(field))
+		}
+		preview.Changed = append(preview.Changed, info)
+	}
+	return preview
+}
+
+func menuBeverageInfos(beverages []model.Beverage) []menuBeverageInfo {
+	infos := []menuBeverageInfo{}
+	for _, beverage := range beverages {
+		infos = append(infos, menuBeverageInfo{
+			Name:    beverage.DisplayName(),
+			Brewer:  beverage.Brewer(),
+			Abv:     beverage.Abv(),
+			Type:    beverage.Type(),
+			Section: beverage.MenuSection(),
+			Tap:     beverage.Tap(),
+		})
+	}
+	return infos
+}
+
+func sectionName(section string) string {
+	if section == "" {
+		return "(no section)"
+	}
+	return section
+}
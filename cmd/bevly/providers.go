@@ -0,0 +1,188 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bevly/bevly/fetch/menu"
+	"github.com/bevly/bevly/model"
+	"github.com/bevly/bevly/repository"
+)
+
+func providersCommand(args []string) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "list":
+			return listProviders(args[1:])
+		case "add":
+			return addProvider(args[1:])
+		case "set":
+			return setProviderOptions(args[1:])
+		case "remove":
+			return removeProvider(args[1:])
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Usage: %s providers list|add|set|remove [flags]\n", os.Args[0])
+	return 2
+}
+
+func listProviders(args []string) int {
+	flags := flag.NewFlagSet("providers list", flag.ExitOnError)
+	verbose := flags.Bool("v", false, "log repository access to stderr")
+	flags.Parse(args)
+	setVerbose(*verbose)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "ID\tNAME\tFORMAT\tURL\tOPTIONS\n")
+	for _, provider := range openRepository().MenuProviders() {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", provider.ID(), provider.Name(),
+			provider.MenuFormat(), provider.URL(), formatOptions(provider.Options()))
+	}
+	w.Flush()
+	return 0
+}
+
+func formatOptions(options map[string]string) string {
+	pairs := []string{}
+	for name, value := range options {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
+
+// optionsFlag collects repeated -option name=value flags.
+type optionsFlag map[string]string
+
+func (o optionsFlag) String() string {
+	return formatOptions(o)
+}
+
+func (o optionsFlag) Set(value string) error {
+	eq := strings.Index(value, "=")
+	if eq <= 0 {
+		return fmt.Errorf("option %q is not name=value", value)
+	}
+	o[value[:eq]] = value[eq+1:]
+	return nil
+}
+
+func addProvider(args []string) int {
+	flags := flag.NewFlagSet("providers add", flag.ExitOnError)
+	id := flags.String("id", "", "the provider's ID, as in its menu URLs (required)")
+	name := flags.String("name", "", "the provider's name; the ID if empty")
+	url := flags.String("url", "", "the menu's URL (required)")
+	format := flags.String("format", "", "the menu's format: "+strings.Join(menu.Formats(), ", ")+" (required)")
+	options := optionsFlag{}
+	flags.Var(options, "option", "a provider option as name=value, such as latitude=39.2; may be repeated")
+	verbose := flags.Bool("v", false, "log repository access to stderr")
+	flags.Parse(args)
+	setVerbose(*verbose)
+	if *id == "" || *url == "" || *format == "" {
+		fmt.Fprintf(os.Stderr, "providers add: -id, -url and -format are required\n")
+		flags.Usage()
+		return 2
+	}
+	if !knownFormat(*format) {
+		fmt.Fprintf(os.Stderr, "providers add: unknown menu format %q; known formats: %s\n",
+			*format, strings.Join(menu.Formats(), ", "))
+		return 2
+	}
+	if *name == "" {
+		*name = *id
+	}
+
+	provider := model.CreateMenuProvider(*id, *name, *url, *format)
+	for option, value := range options {
+		provider.SetOption(option, value)
+	}
+	if err := openRepository().SaveProvider(provider); err != nil {
+		fmt.Fprintf(os.Stderr, "providers add: %s\n", err)
+		return 1
+	}
+	fmt.Printf("Saved provider %s\n", *id)
+	return 0
+}
+
+// setProviderOptions changes the options of a provider, such as the CSV
+// columns of a menu whose headers aren't the usual ones, keeping its other
+// options and its menu.
+func setProviderOptions(args []string) int {
+	flags := flag.NewFlagSet("providers set", flag.ExitOnError)
+	options := optionsFlag{}
+	flags.Var(options, "option", "a provider option as name=value, or name= to clear it; may be repeated")
+	verbose := flags.Bool("v", false, "log repository access to stderr")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s providers set -option name=value... [-v] <provider id>\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	setVerbose(*verbose)
+	if flags.NArg() != 1 || len(options) == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	repo := openRepository()
+	stored := repo.ProviderByID(flags.Arg(0))
+	if stored == nil {
+		fmt.Fprintf(os.Stderr, "providers set: no provider %q\n", flags.Arg(0))
+		return 1
+	}
+	provider := model.CreateMenuProvider(stored.ID(), stored.Name(), stored.URL(), stored.MenuFormat())
+	for option, value := range stored.Options() {
+		if _, changed := options[option]; !changed {
+			provider.SetOption(option, value)
+		}
+	}
+	for option, value := range options {
+		if value != "" {
+			provider.SetOption(option, value)
+		}
+	}
+	if err := repo.SaveProvider(provider); err != nil {
+		fmt.Fprintf(os.Stderr, "providers set: %s\n", err)
+		return 1
+	}
+	fmt.Printf("Saved provider %s: %s\n", provider.ID(), formatOptions(provider.Options()))
+	return 0
+}
+
+func knownFormat(format string) bool {
+	for _, known := range menu.Formats() {
+		if format == known {
+			return true
+		}
+	}
+	return false
+}
+
+func removeProvider(args []string) int {
+	flags := flag.NewFlagSet("providers remove", flag.ExitOnError)
+	verbose := flags.Bool("v", false, "log repository access to stderr")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s providers remove [-v] <provider id>\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	setVerbose(*verbose)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	err := openRepository().RemoveProvider(flags.Arg(0))
+	if err == repository.ErrNoSuchProvider {
+		fmt.Fprintf(os.Stderr, "providers remove: no provider %q\n", flags.Arg(0))
+		return 1
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "providers remove: %s\n", err)
+		return 1
+	}
+	fmt.Printf("Removed provider %s and its menu\n", flags.Arg(0))
+	return 0
+}
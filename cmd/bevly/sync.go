@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bevly/bevly/model"
+	"github.com/bevly/bevly/repository"
+	bevsync "github.com/bevly/bevly/sync"
+)
+
+func syncCommand(args []string) int {
+	flags := flag.NewFlagSet("sync", flag.ExitOnError)
+	providerIDs := flags.String("provider", "", "comma-separated IDs of the providers to sync; all if empty")
+	dryRun := flags.Bool("dry-run", false, "fetch menus and show how they differ from the stored menus, saving nothing")
+	verbose := flags.Bool("v", false, "log menu and metadata fetches to stderr")
+	flags.Parse(args)
+	setVerbose(*verbose)
+
+	repo := openRepository()
+	providers, err := selectProviders(repo, *providerIDs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sync: %s\n", err)
+		return 2
+	}
+
+	if *dryRun {
+		status := 0
+		for _, provider := range providers {
+			fetched, diff := bevsync.PreviewMenu(repo, provider)
+			if fetched.Err != nil {
+				fmt.Printf("%s: failed: %s\n", provider.ID(), fetched.Err)
+				status = 1
+				continue
+			}
+			fmt.Printf("%s: %d beverages\n", provider.ID(), len(fetched.Beverages))
+			printMenuDiff(os.Stdout, diff)
+		}
+		return status
+	}
+
+	errs := bevsync.SyncProviders(repo, providers)
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "sync: %s\n", err)
+	}
+	fmt.Printf("Synced %d providers with %d errors\n", len(providers), len(errs))
+	if len(errs) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// selectProviders finds the providers with the comma-separated IDs, or
+// lists all providers if ids is empty.
+func selectProviders(repo repository.Repository, ids string) ([]model.MenuProvider, error) {
+	if strings.TrimSpace(ids) == "" {
+		return repo.MenuProviders(), nil
+	}
+	providers := []model.MenuProvider{}
+	for _, id := range strings.Split(ids, ",") {
+		id = strings.TrimSpace(id)
+		provider := repo.ProviderByID(id)
+		if provider == nil {
+			return nil, errors.New("no provider " + id)
+		}
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/bevly/bevly/fetch/menu/pdfmenu"
+	"github.com/bevly/bevly/httpagent"
+	"github.com/bevly/bevly/pdftext"
+)
+
+const maxSamples = 3
+const maxSampleLen = 60
+
+type fragmentInfo struct {
+	Page int     `json:"page"`
+	Font string  `json:"font"`
+	Size float64 `json:"size"`
+	X    float64 `json:"x"`
+	Y    float64 `json:"y"`
+	Text string  `json:"text"`
+}
+
+type fontInfo struct {
+	Font    string   `json:"font"`
+	Size    float64  `json:"size"`
+	Pattern string   `json:"pattern"`
+	Count   int      `json:"count"`
+	Samples []string `json:"samples"`
+}
+
+type report struct {
+	Fragments []fragmentInfo `json:"fragments"`
+	Fonts     []*fontInfo    `json:"fonts"`
+}
+
+func main() {
+	jsonOutput := flag.Bool("json", false, "print the report as JSON")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [--json] [pdf file or URL]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	pdfFile, cleanup, err := localPDF(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't fetch %s: %s\n", flag.Arg(0), err)
+		os.Exit(1)
+	}
+	defer cleanup()
+
+	scanner, err := pdftext.NewFileScanner(pdfFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't read %s: %s\n", flag.Arg(0), err)
+		os.Exit(1)
+	}
+
+	rep := inspect(scanner)
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(rep); err != nil {
+			fmt.Fprintf(os.Stderr, "Can't write JSON: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	printReport(rep)
+}
+
+// localPDF returns a local path for a PDF file or URL, downloading it if
+// necessary.
+func localPDF(source string) (string, func(), error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return source, func() {}, nil
+	}
+	tempF, err := ioutil.TempFile("", "pdfmenu-inspect")
+	if err != nil {
+		return "", nil, err
+	}
+	path := tempF.Name()
+	tempF.Close()
+	cleanup := func() { os.Remove(path) }
+	if _, err = httpagent.New().GetFile(source, path); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return path, cleanup, nil
+}
+
+func inspect(scanner *pdftext.Scanner) *report {
+	rep := &report{Fragments: []fragmentInfo{}}
+	fonts := map[pdftext.Font]*fontInfo{}
+	for {
+		frag := scanner.NextText()
+		if frag == nil {
+			break
+		}
+		text := frag.Text()
+		rep.Fragments = append(rep.Fragments, fragmentInfo{
+			Page: frag.Page,
+			Font: frag.Font.Name,
+			Size: frag.Font.Size,
+			X:    frag.LX,
+			Y:    frag.Y,
+			Text: text,
+		})
+
+		info := fonts[frag.Font]
+		if info == nil {
+			info = &fontInfo{
+				Font:    frag.Font.Name,
+				Size:    frag.Font.Size,
+				Pattern: pdfmenu.FontRuleFor(pdfmenu.RoleNone, frag.Font).String(),
+				Samples: []string{},
+			}
+			fonts[frag.Font] = info
+			rep.Fonts = append(rep.Fonts, info)
+		}
+		info.Count++
+		if len(info.Samples) < maxSamples {
+			info.Samples = append(info.Samples, sample(text))
+		}
+	}
+
+	sort.SliceStable(rep.Fonts, func(i, j int) bool {
+		return rep.Fonts[i].Count > rep.Fonts[j].Count
+	})
+	return rep
+}
+
+func sample(text string) string {
+	runes := []rune(text)
+	if len(runes) > maxSampleLen {
+		return string(runes[:maxSampleLen]) + "..."
+	}
+	return text
+}
+
+func printReport(rep *report) {
+	for _, frag := range rep.Fragments {
+		fmt.Printf("p%-3d %-24s %5.1f  x=%6.1f y=%6.1f  %s\n",
+			frag.Page, frag.Font, frag.Size, frag.X, frag.Y, frag.Text)
+	}
+
+	fmt.Printf("\nFonts (%d):\n", len(rep.Fonts))
+	for _, font := range rep.Fonts {
+		fmt.Printf("%6d  %s\n", font.Count, font.Pattern)
+		for _, s := range font.Samples {
+			fmt.Printf("          %q\n", s)
+		}
+	}
+}
@@ -31,7 +31,7 @@ var bevTests = []struct {
 		name:    "Jailbreak Cafe Kavorka",
 		bevtype: "Porter",
 		abv:     5.5,
-		desc:    "A lightly roasted porter fermented with sweet cherries and a late addition of tart, black cherries. The result is a semisweet & slightly tart perfectly dark porter with loads of complexity & depth of \"avor.",
+		desc:    "A lightly roasted porter fermented with sweet cherries and a late addition of tart, black cherries. The result is a semisweet & slightly tart perfectly dark porter with loads of complexity & depth of flavor.",
 	},
 	{
 		name: "Firestone Easy Jack",
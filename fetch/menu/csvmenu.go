@@ -0,0 +1,220 @@
+package menu
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bevly/bevly/httpagent"
+	"github.com/bevly/bevly/model"
+	"github.com/bevly/bevly/text"
+)
+
+// Provider options naming the CSV header for each beverage field. Fields
+// without an option fall back to the usual header names in csvDefaultColumns.
+// Options are stored with the provider in the repository, and set with
+// "bevly providers add -option" or "bevly providers set -option".
+const (
+	CSVNameColumn    = "csvNameColumn"
+	CSVBrewerColumn  = "csvBrewerColumn"
+	CSVStyleColumn   = "csvStyleColumn"
+	CSVAbvColumn     = "csvAbvColumn"
+	CSVPriceColumn   = "csvPriceColumn"
+	CSVSizeColumn    = "csvSizeColumn"
+	CSVTapColumn     = "csvTapColumn"
+	CSVSectionColumn = "csvSectionColumn"
+	CSVDelimiter     = "csvDelimiter"
+)
+
+const CSVServingProperty = "csvServingSize"
+const CSVTapProperty = "csvTap"
+
+var csvDefaultColumns = map[string][]string{
+	CSVNameColumn:    {"name", "beer", "beverage", "drink"},
+	CSVBrewerColumn:  {"brewer", "brewery", "producer"},
+	CSVStyleColumn:   {"style", "type"},
+	CSVAbvColumn:     {"abv", "abv%", "abv (%)", "alcohol"},
+	CSVPriceColumn:   {"price", "cost"},
+	CSVSizeColumn:    {"size", "serving", "pour"},
+	CSVTapColumn:     {"tap", "tap #", "tap number", "line"},
+	CSVSectionColumn: {"section", "menu section", "heading"},
+}
+
+func init() {
+	menuFetcherRegistry["csv"] = csvMenu
+}
+
+func csvMenu(provider model.MenuProvider) ([]model.Beverage, error) {
+	body, err := openMenuURL(provider.URL())
+	if err != nil {
+		log.Printf("csvMenu: Get(%s) failed: %s\n", provider.URL(), err)
+		return nil, err
+	}
+	defer body.Close()
+
+	beverages, err := csvBeverages(provider, body)
+	if err != nil {
+		log.Printf("csvMenu: Failed to parse menu: %s\n", err)
+		return nil, err
+	}
+	log.Printf("csvMenu: parsed %d beverages from %s\n",
+		len(beverages), provider.URL())
+	return beverages, nil
+}
+
+// openMenuURL opens an http(s) or file URL. File URLs let a provider point
+// at a local export of the menu.
+func openMenuURL(menuURL string) (io.ReadCloser, error) {
+	parsedURL, err := url.Parse(menuURL)
+	if err != nil {
+		return nil, err
+	}
+	if parsedURL.Scheme == "file" {
+		return os.Open(parsedURL.Path)
+	}
+	res, err := httpagent.New().Get(menuURL)
+	if err != nil {
+		if res != nil && res.Body != nil {
+			res.Body.Close()
+		}
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+// csvColumns maps each column option to its index in header, or -1 if the
+// header has no such column.
+func csvColumns(provider model.MenuProvider, header []string) map[string]int {
+	normalizedHeader := make([]string, len(header))
+	for i, name := range header {
+		normalizedHeader[i] = strings.ToLower(text.Normalize(name))
+	}
+	indexOf := func(name string) int {
+		name = strings.ToLower(text.Normalize(name))
+		for i, headerName := range normalizedHeader {
+			if headerName == name {
+				return i
+			}
+		}
+		return -1
+	}
+
+	columns := map[string]int{}
+	for option, defaults := range csvDefaultColumns {
+		columns[option] = -1
+		if configured := provider.Option(option); configured != "" {
+			columns[option] = indexOf(configured)
+			continue
+		}
+		for _, name := range defaults {
+			if i := indexOf(name); i != -1 {
+				columns[option] = i
+				break
+			}
+		}
+	}
+	return columns
+}
+
+func csvBeverages(provider model.MenuProvider, r io.Reader) ([]model.Beverage, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+	if delim := provider.Option(CSVDelimiter); delim != "" {
+		reader.Comma = []rune(delim)[0]
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("can't read CSV header: %s", err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	columns := csvColumns(provider, header)
+	if columns[CSVNameColumn] == -1 {
+		return nil, fmt.Errorf("no name column in CSV header %v", header)
+	}
+
+	beverages := []model.Beverage{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if bev := csvBeverage(columns, record); bev != nil {
+			beverages = append(beverages, bev)
+		}
+	}
+	if len(beverages) == 0 {
+		return nil, ErrEmptyMenu
+	}
+	return beverages, nil
+}
+
+func csvBeverage(columns map[string]int, record []string) model.Beverage {
+	field := func(option string) string {
+		i := columns[option]
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return text.Normalize(record[i])
+	}
+
+	name := field(CSVNameColumn)
+	if name == "" {
+		return nil
+	}
+	brewer := field(CSVBrewerColumn)
+	displayName := name
+	if brewer != "" && !strings.HasPrefix(strings.ToLower(name), strings.ToLower(brewer)) {
+		displayName = brewer + " " + name
+	}
+
+	bev := model.CreateBeverageBrewer(displayName, brewer)
+	bev.SetName(name)
+	bev.SetType(field(CSVStyleColumn))
+	if abv := parseABV(field(CSVAbvColumn)); abv > 0 {
+		bev.SetAbv(abv)
+	}
+	if tap := field(CSVTapColumn); tap != "" {
+		bev.SetAttribute(CSVTapProperty, tap)
+		bev.SetTap(tap)
+	}
+	bev.SetMenuSection(field(CSVSectionColumn))
+	size, price := field(CSVSizeColumn), field(CSVPriceColumn)
+	if serving := csvServing(size, price); serving != "" {
+		bev.SetAttribute(CSVServingProperty, serving)
+		bev.AddServing(model.ParseServing(size, csvPrice(price)))
+	}
+	return bev
+}
+
+// csvPrice assumes that bare numeric prices are in dollars.
+func csvPrice(price string) string {
+	if _, err := strconv.ParseFloat(price, 64); err == nil {
+		return "$" + price
+	}
+	return price
+}
+
+func csvServing(size, price string) string {
+	price = csvPrice(price)
+	switch {
+	case price == "":
+		return size
+	case size == "":
+		return price
+	default:
+		return size + ": " + price
+	}
+}
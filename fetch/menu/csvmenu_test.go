@@ -0,0 +1,66 @@
+package menu
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/bevly/bevly/httpfilestub"
+	"github.com/bevly/bevly/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVMenu(t *testing.T) {
+	ts := httpfilestub.Server("csv_test.csv")
+	defer ts.Close()
+
+	provider := model.CreateMenuProvider("taps", "Taps", ts.URL, "csv")
+	bevs, err := FetchMenu(provider)
+	assert.Nil(t, err, "fetch from stub server must not fail")
+	if !assert.Equal(t, 4, len(bevs), "must find all beers, skipping blank rows") {
+		return
+	}
+
+	assert.Equal(t, "Jailbreak Desserted", bevs[0].DisplayName())
+	assert.Equal(t, "Desserted", bevs[0].Name())
+	assert.Equal(t, "Jailbreak", bevs[0].Brewer())
+	assert.Equal(t, "Chocolate Coconut Porter", bevs[0].Type())
+	assert.Equal(t, 6.9, bevs[0].Abv())
+	assert.Equal(t, "Drafts", bevs[0].MenuSection(), "section must be read despite BOM")
+	assert.Equal(t, "1", bevs[0].Attribute(CSVTapProperty))
+	assert.Equal(t, "1", bevs[0].Tap())
+	assert.Equal(t, "16oz: $6.95", bevs[0].Attribute(CSVServingProperty))
+	assert.Equal(t, []model.Serving{
+		{Name: "16oz", Size: 16, Unit: "oz", Price: 6.95, Currency: "USD"},
+	}, bevs[0].Servings())
+
+	assert.Equal(t, `Oliver Draft Punk, "Nitro"`, bevs[1].DisplayName(),
+		"must handle quoted fields")
+	assert.Equal(t, "10oz: $5", bevs[1].Attribute(CSVServingProperty))
+
+	assert.Equal(t, "Allagash White", bevs[2].DisplayName(), "no brewer column value")
+	assert.Equal(t, "$7", bevs[2].Attribute(CSVServingProperty))
+
+	assert.Equal(t, "Bell's Two Hearted", bevs[3].DisplayName(),
+		"must not repeat brewer already in name")
+	assert.Equal(t, 7.0, bevs[3].Abv(), "must tolerate extra columns")
+	assert.Equal(t, "Cans", bevs[3].MenuSection())
+}
+
+func TestCSVMenuFileURLOptions(t *testing.T) {
+	path, err := filepath.Abs("csv_test.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := model.CreateMenuProvider("taps", "Taps", "file://"+path, "csv")
+	provider.SetOption(CSVNameColumn, "Beer")
+	provider.SetOption(CSVStyleColumn, "Notes")
+	provider.SetOption(CSVBrewerColumn, "No Such Column")
+
+	bevs, err := csvMenu(provider)
+	assert.Nil(t, err, "fetch from file URL must not fail")
+	if !assert.Equal(t, 4, len(bevs), "must find all beers") {
+		return
+	}
+	assert.Equal(t, "Desserted", bevs[0].DisplayName(), "brewer column not mapped")
+	assert.Equal(t, "Rich, coconut-y", bevs[0].Type(), "style from configured column")
+}
@@ -75,6 +75,7 @@ func friscoDrafts(doc *goquery.Document, url *url.URL) ([]model.Beverage, error)
 		beer.SetAttribute(frisco.ProfileURLProperty, url.String())
 		if pour != "" {
 			beer.SetAttribute(frisco.ServingSizeProperty, pour)
+			beer.AddServing(model.ParseServing(pour, ""))
 		}
 		if abv != "" {
 			beer.SetAbv(parseABV(abv))
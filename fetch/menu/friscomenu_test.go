@@ -35,6 +35,8 @@ func TestFriscoMenu(t *testing.T) {
 		beverages[7].DisplayName(), "must find Flying Dog Dogtoberfest")
 	assert.Equal(t, 5.8, beverages[7].Abv(), "must match Dogtoberfest ABV")
 	assert.Equal(t, "16oz", beverages[7].Attribute(frisco.ServingSizeProperty), "must match serving size")
+	assert.Equal(t, []model.Serving{{Name: "16oz", Size: 16, Unit: "oz"}},
+		beverages[7].Servings(), "must record serving")
 	assert.Equal(t, "Push 72° & Sunny Wheat Ale", beverages[27].DisplayName(),
 		"must decode HTML entities")
 	assert.Equal(t, ts.URL,
@@ -0,0 +1,271 @@
+package menu
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/bevly/bevly/httpagent"
+	"github.com/bevly/bevly/model"
+	"github.com/bevly/bevly/text"
+)
+
+// JSONLDServingProperty holds the offers of a schema.org MenuItem, formatted
+// as "Pint: $6, Snifter: $5".
+const JSONLDServingProperty = "jsonldServingSize"
+
+func init() {
+	menuFetcherRegistry["jsonld"] = jsonldMenu
+}
+
+func jsonldMenu(provider model.MenuProvider) ([]model.Beverage, error) {
+	doc, err := httpagent.New().GetDoc(provider.URL())
+	if err != nil {
+		log.Printf("jsonldMenu: Get(%s) failed: %s\n", provider.URL(), err)
+		return nil, err
+	}
+
+	beverages, err := jsonldBeverages(doc)
+	if err != nil {
+		log.Printf("jsonldMenu: Failed to parse menu: %s\n", err)
+		return nil, err
+	}
+	log.Printf("jsonldMenu: parsed %d beverages from %s\n",
+		len(beverages), provider.URL())
+	return beverages, nil
+}
+
+// jsonldBeverages walks every JSON-LD block in doc, collecting the
+// schema.org MenuItems found under Menus and MenuSections.
+func jsonldBeverages(doc *goquery.Document) ([]model.Beverage, error) {
+	beverages := []model.Beverage{}
+	blocks := 0
+	doc.Find(`script[type="application/ld+json"]`).Each(func(_ int, s *goquery.Selection) {
+		var node interface{}
+		if err := json.Unmarshal([]byte(s.Text()), &node); err != nil {
+			log.Printf("jsonldBeverages: skipping malformed JSON-LD block: %s\n", err)
+			return
+		}
+		blocks++
+		beverages = appendJSONLDItems(beverages, node, false, "")
+	})
+	if blocks == 0 {
+		return nil, fmt.Errorf("no JSON-LD blocks in menu page")
+	}
+	if len(beverages) == 0 {
+		return nil, ErrEmptyMenu
+	}
+	return beverages, nil
+}
+
+// appendJSONLDItems appends the beverages for the MenuItems reachable from
+// node. MenuItems are only accepted inside a Menu or MenuSection, so that
+// unrelated JSON-LD on the page is ignored. Each beverage's menu section is
+// the innermost named MenuSection containing it.
+func appendJSONLDItems(beverages []model.Beverage, node interface{}, inMenu bool, section string) []model.Beverage {
+	for _, child := range jsonldList(node) {
+		obj, ok := child.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if jsonldIsType(obj, "MenuItem") {
+			if inMenu {
+				if bev := jsonldBeverage(obj); bev != nil {
+					bev.SetMenuSection(section)
+					beverages = append(beverages, bev)
+				}
+			}
+			continue
+		}
+
+		childInMenu := inMenu || jsonldIsType(obj, "Menu") ||
+			jsonldIsType(obj, "MenuSection")
+		childSection := section
+		if name := jsonldText(obj["name"]); name != "" && jsonldIsType(obj, "MenuSection") {
+			childSection = name
+		}
+		for _, key := range []string{"@graph", "hasMenu", "menu",
+			"hasMenuSection", "hasMenuItem", "itemListElement"} {
+			if value, ok := obj[key]; ok {
+				beverages = appendJSONLDItems(beverages, value, childInMenu, childSection)
+			}
+		}
+	}
+	return beverages
+}
+
+func jsonldBeverage(item map[string]interface{}) model.Beverage {
+	name := jsonldText(item["name"])
+	if name == "" {
+		return nil
+	}
+	desc := jsonldText(item["description"])
+
+	abv := jsonldPropertyABV(item)
+	if abv == 0 {
+		abv = textABV(desc)
+	}
+	if abv == 0 {
+		abv = textABV(name)
+	}
+
+	bev := model.CreateBeverage(text.Normalize(rABVText.ReplaceAllString(name, "")))
+	bev.SetDescription(desc)
+	if abv > 0 {
+		bev.SetAbv(abv)
+	}
+	servingText, servings := jsonldServings(item["offers"])
+	if servingText != "" {
+		bev.SetAttribute(JSONLDServingProperty, servingText)
+	}
+	bev.SetServings(servings)
+	return bev
+}
+
+// jsonldPropertyABV looks for an ABV in the item's additionalProperty list,
+// which is where sites that care about ABV tend to put it.
+func jsonldPropertyABV(item map[string]interface{}) float64 {
+	for _, prop := range jsonldList(item["additionalProperty"]) {
+		propObj, ok := prop.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		propName := strings.ToLower(jsonldText(propObj["name"]))
+		if propName == "abv" || propName == "alcohol by volume" {
+			return parseABV(jsonldText(propObj["value"]))
+		}
+	}
+	return 0
+}
+
+var rABVText = regexp.MustCompile(`(?i)\s*(?:\bABV\s*:?\s*)?(\d+(?:[.]\d+)?)\s*%(?:\s*ABV\b)?`)
+
+func textABV(desc string) float64 {
+	match := rABVText.FindStringSubmatch(desc)
+	if match == nil {
+		return 0
+	}
+	abv, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0
+	}
+	return abv
+}
+
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"GBP": "£",
+	"EUR": "€",
+}
+
+// jsonldServings returns the offers both formatted for the serving attribute
+// and as servings.
+func jsonldServings(offers interface{}) (string, []model.Serving) {
+	servingText := []string{}
+	servings := []model.Serving{}
+	for _, offer := range jsonldList(offers) {
+		offerObj, ok := offer.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		price := jsonldText(offerObj["price"])
+		if price == "" {
+			continue
+		}
+		currency := jsonldText(offerObj["priceCurrency"])
+		size := jsonldOfferSize(offerObj)
+		serving := model.ParseServing(size, "")
+		serving.Price, _ = strconv.ParseFloat(price, 64)
+		serving.Currency = currency
+		servings = append(servings, serving)
+
+		if symbol, ok := currencySymbols[currency]; ok {
+			price = symbol + price
+		} else if currency != "" {
+			price = price + " " + currency
+		}
+		if size == "" {
+			servingText = append(servingText, price)
+		} else {
+			servingText = append(servingText, size+": "+price)
+		}
+	}
+	return strings.Join(servingText, ", "), servings
+}
+
+// jsonldUnitCodes maps UN/CEFACT unit codes to the units menus print.
+var jsonldUnitCodes = map[string]string{
+	"OZA": "oz",
+	"MLT": "ml",
+	"CLT": "cl",
+	"LTR": "l",
+}
+
+func jsonldOfferSize(offer map[string]interface{}) string {
+	if quantity, ok := offer["eligibleQuantity"].(map[string]interface{}); ok {
+		value := jsonldText(quantity["value"])
+		unit := jsonldText(quantity["unitText"])
+		if unit == "" {
+			unit = jsonldText(quantity["unitCode"])
+			if name, ok := jsonldUnitCodes[unit]; ok {
+				unit = name
+			}
+		}
+		if value != "" {
+			return value + strings.ToLower(unit)
+		}
+	}
+	return jsonldText(offer["name"])
+}
+
+// jsonldList returns v as a list: JSON-LD allows any property to be either
+// a single value or an array of values.
+func jsonldList(v interface{}) []interface{} {
+	switch value := v.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return value
+	default:
+		return []interface{}{value}
+	}
+}
+
+func jsonldText(v interface{}) string {
+	switch value := v.(type) {
+	case string:
+		return text.Normalize(html.UnescapeString(value))
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case map[string]interface{}:
+		return jsonldText(value["@value"])
+	case []interface{}:
+		if len(value) > 0 {
+			return jsonldText(value[0])
+		}
+	}
+	return ""
+}
+
+// jsonldIsType checks the node's @type, accepting both bare and
+// schema.org-qualified type names.
+func jsonldIsType(node map[string]interface{}, typeName string) bool {
+	for _, t := range jsonldList(node["@type"]) {
+		name, ok := t.(string)
+		if !ok {
+			continue
+		}
+		if i := strings.LastIndexAny(name, "/:"); i != -1 {
+			name = name[i+1:]
+		}
+		if name == typeName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package menu
+
+import (
+	"testing"
+
+	"github.com/bevly/bevly/httpfilestub"
+	"github.com/bevly/bevly/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONLDMenu(t *testing.T) {
+	ts := httpfilestub.Server("jsonld_test.html")
+	defer ts.Close()
+
+	provider := model.CreateMenuProvider("taproom", "Tap Room", ts.URL, "jsonld")
+	bevs, err := FetchMenu(provider)
+	assert.Nil(t, err, "fetch from stub server must not fail")
+	if !assert.Equal(t, 4, len(bevs), "must find all menu items") {
+		return
+	}
+
+	assert.Equal(t, "Bear Republic Racer 5", bevs[0].DisplayName())
+	assert.Equal(t, 7.5, bevs[0].Abv(), "ABV from description")
+	assert.Equal(t, "16oz: $6.50, 10oz: $4", bevs[0].Attribute(JSONLDServingProperty))
+	assert.Equal(t, []model.Serving{
+		{Name: "16oz", Size: 16, Unit: "oz", Price: 6.5, Currency: "USD"},
+		{Name: "10oz", Size: 10, Unit: "oz", Price: 4, Currency: "USD"},
+	}, bevs[0].Servings())
+
+	assert.Equal(t, "Allagash White & Friends", bevs[1].DisplayName(),
+		"must decode HTML entities")
+	assert.Equal(t, 5.2, bevs[1].Abv(), "ABV from additionalProperty")
+	assert.Equal(t, "Belgian-style wheat beer spiced with coriander and Curaçao orange peel.",
+		bevs[1].Description())
+	assert.Equal(t, "Pint: $7", bevs[1].Attribute(JSONLDServingProperty))
+	assert.Equal(t, 16.0, bevs[1].Servings()[0].Ounces(), "pint size")
+
+	assert.Equal(t, "Westbrook Gose", bevs[2].DisplayName(), "ABV stripped from name")
+	assert.Equal(t, 4.0, bevs[2].Abv(), "ABV from name")
+	assert.Equal(t, "€5", bevs[2].Attribute(JSONLDServingProperty))
+	assert.Equal(t, "Sours", bevs[2].MenuSection(), "innermost section")
+
+	assert.Equal(t, "Founders Breakfast Stout", bevs[3].DisplayName(),
+		"must walk @graph and qualified types")
+	assert.Equal(t, 8.3, bevs[3].Abv())
+}
+
+func TestTextABV(t *testing.T) {
+	tests := []struct {
+		in  string
+		abv float64
+	}{
+		{"Hazy IPA 6.8%", 6.8},
+		{"ABV: 5%", 5},
+		{"12 oz pour", 0},
+	}
+	for _, test := range tests {
+		if abv := textABV(test.in); abv != test.abv {
+			t.Errorf("textABV(%#v) == %#v, want %#v", test.in, abv, test.abv)
+		}
+	}
+}
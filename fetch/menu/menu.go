@@ -3,6 +3,7 @@ package menu
 import (
 	"errors"
 	"log"
+	"sort"
 
 	"github.com/bevly/bevly/model"
 	"github.com/bevly/bevly/websearch/google"
@@ -14,6 +15,16 @@ type menuFetcher func(model.MenuProvider) ([]model.Beverage, error)
 
 var menuFetcherRegistry = map[string]menuFetcher{}
 
+// Formats lists the menu formats providers may have, by name.
+func Formats() []string {
+	formats := []string{}
+	for format := range menuFetcherRegistry {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
 // Get list of beverages for a menu provider
 func FetchMenu(provider model.MenuProvider) ([]model.Beverage, error) {
 	fetcher := menuFetcherRegistry[provider.MenuFormat()]
@@ -0,0 +1,79 @@
+package menu
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+
+	"github.com/bevly/bevly/fetch/menu/pdfmenu"
+	"github.com/bevly/bevly/httpagent"
+	"github.com/bevly/bevly/model"
+)
+
+// PDFLinkOption is a provider option holding the CSS selector for the link
+// to the PDF menu on the provider's page. If unset, the provider URL is the
+// PDF itself.
+const PDFLinkOption = "pdfLink"
+
+func init() {
+	menuFetcherRegistry["pdf"] = pdfMenu
+}
+
+func pdfMenu(provider model.MenuProvider) ([]model.Beverage, error) {
+	layout, err := pdfmenu.LayoutFromOptions(provider.Options(),
+		provider.ID()+"ServingSize")
+	if err != nil {
+		log.Printf("pdfMenu(%s): bad layout: %s\n", provider.ID(), err)
+		return nil, err
+	}
+
+	agent := httpagent.New()
+	pdfURL, err := pdfMenuURL(agent, provider)
+	if err != nil {
+		log.Printf("pdfMenu(%s): no PDF: %s\n", provider.ID(), err)
+		return nil, err
+	}
+
+	pdfFile, err := fetchPDF(agent, pdfURL)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't fetch PDF for %s: %s", provider.ID(), err)
+	}
+	defer os.Remove(pdfFile)
+
+	beverages, err := layout.Parse(pdfFile)
+	if err != nil {
+		log.Printf("pdfMenu(%s): Failed to parse menu: %s\n", provider.ID(), err)
+		return nil, err
+	}
+	if len(beverages) == 0 {
+		return nil, ErrEmptyMenu
+	}
+	log.Printf("pdfMenu: parsed %d beverages from %s\n", len(beverages), pdfURL)
+	return beverages, nil
+}
+
+func pdfMenuURL(agent *httpagent.Agent, provider model.MenuProvider) (string, error) {
+	linkSelector := provider.Option(PDFLinkOption)
+	if linkSelector == "" {
+		return provider.URL(), nil
+	}
+
+	doc, err := agent.GetDoc(provider.URL())
+	if err != nil {
+		return "", err
+	}
+	href, ok := doc.Find(linkSelector).Attr("href")
+	if !ok {
+		return "", fmt.Errorf("no PDF link matching %#v", linkSelector)
+	}
+	pageURL, err := url.Parse(provider.URL())
+	if err != nil {
+		return "", err
+	}
+	pdfURL, err := pageURL.Parse(href)
+	if err != nil {
+		return "", err
+	}
+	return pdfURL.String(), nil
+}
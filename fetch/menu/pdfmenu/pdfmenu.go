@@ -0,0 +1,395 @@
+// Package pdfmenu parses beverage menus from PDFs, using a Layout that
+// describes which fonts the venue uses for each part of the menu.
+package pdfmenu
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bevly/bevly/model"
+	"github.com/bevly/bevly/pdftext"
+)
+
+// Role is the part a fragment of text plays in a menu.
+type Role int
+
+const (
+	RoleNone Role = iota
+	RoleSection
+	RoleTitle
+	RoleStyle
+	RoleBody
+	RolePrice
+)
+
+var roleNames = map[Role]string{
+	RoleNone:    "none",
+	RoleSection: "section",
+	RoleTitle:   "title",
+	RoleStyle:   "style",
+	RoleBody:    "body",
+	RolePrice:   "price",
+}
+
+func (r Role) String() string {
+	return roleNames[r]
+}
+
+// sizeEpsilon is the slack allowed when comparing font sizes, since PDF
+// generators rarely emit exact sizes.
+const sizeEpsilon = 0.05
+
+// FontRule assigns a role to all text set in a matching font. Name is a
+// regular expression that must match the whole font name, ignoring any
+// subset prefix ("ABCDEF+"). A zero MinSize or MaxSize leaves that end of the
+// size range open.
+type FontRule struct {
+	Role    Role
+	Name    *regexp.Regexp
+	MinSize float64
+	MaxSize float64
+}
+
+var rSubsetPrefix = regexp.MustCompile(`^[A-Z]{6}\+`)
+
+func (f FontRule) Matches(font pdftext.Font) bool {
+	name := rSubsetPrefix.ReplaceAllString(font.Name, "")
+	if f.Name != nil {
+		loc := f.Name.FindStringIndex(name)
+		if loc == nil || loc[0] != 0 || loc[1] != len(name) {
+			return false
+		}
+	}
+	if f.MinSize > 0 && font.Size < f.MinSize-sizeEpsilon {
+		return false
+	}
+	if f.MaxSize > 0 && font.Size > f.MaxSize+sizeEpsilon {
+		return false
+	}
+	return true
+}
+
+// FontRuleFor creates a rule matching exactly the given font, whatever its
+// subset prefix.
+func FontRuleFor(role Role, font pdftext.Font) FontRule {
+	name := rSubsetPrefix.ReplaceAllString(font.Name, "")
+	return FontRule{
+		Role:    role,
+		Name:    regexp.MustCompile(regexp.QuoteMeta(name)),
+		MinSize: font.Size,
+		MaxSize: font.Size,
+	}
+}
+
+// Section describes how beverages under a section heading are treated. Name
+// is the menu section the beverages are listed under. Prefix, if set, is
+// prepended to the beverage names in that section, for menus that list a
+// house brewery's beers without the brewery name.
+type Section struct {
+	Name   string
+	Prefix string
+}
+
+func (s Section) modifyName(name string) string {
+	if s.Prefix != "" {
+		return s.Prefix + " " + name
+	}
+	return name
+}
+
+// Layout describes a PDF menu. If Sections is empty, beverages in every
+// section are included; otherwise only beverages under a listed heading are.
+type Layout struct {
+	Fonts    []FontRule
+	Sections map[string]Section
+
+	// ServingProperty names the beverage attribute that receives the price
+	// lines, formatted as "Pint: $6, Snifter: $5".
+	ServingProperty string
+
+	// LigatureRepair turns on pdftext's guesswork for "!" standing in for a
+	// ligature, for PDFs whose fonts can't be decoded.
+	LigatureRepair bool
+}
+
+func (l *Layout) role(font pdftext.Font) Role {
+	for _, rule := range l.Fonts {
+		if rule.Matches(font) {
+			return rule.Role
+		}
+	}
+	return RoleNone
+}
+
+func (l *Layout) section(heading string) (Section, bool) {
+	if len(l.Sections) == 0 {
+		return Section{Name: heading}, true
+	}
+	section, ok := l.Sections[heading]
+	return section, ok
+}
+
+// Parse reads all beverages from the PDF file pdfname.
+func (l *Layout) Parse(pdfname string) ([]model.Beverage, error) {
+	scanner, err := pdftext.NewFileScanner(pdfname)
+	if err != nil {
+		return nil, err
+	}
+	scanner.LigatureRepair = l.LigatureRepair
+	return l.ParseScanner(scanner)
+}
+
+// ParseScanner reads all beverages from scanner.
+func (l *Layout) ParseScanner(scanner *pdftext.Scanner) ([]model.Beverage, error) {
+	reader := &menuReader{layout: l, Scanner: scanner}
+	if len(l.Sections) == 0 {
+		reader.inSection = true
+	}
+
+	result := []model.Beverage{}
+	for {
+		bev, err := reader.NextBeverage()
+		if err != nil {
+			return nil, err
+		}
+		if bev == nil {
+			break
+		}
+		result = append(result, bev)
+	}
+	return result, nil
+}
+
+type menuReader struct {
+	layout    *Layout
+	section   Section
+	inSection bool
+	bev       model.Beverage
+	*pdftext.Scanner
+}
+
+var rBogusHyphenMatch = regexp.MustCompile(`(?:\s+-\s+|-\s+|\s+-)`)
+
+func fixName(name string) string {
+	return rBogusHyphenMatch.ReplaceAllString(name, " ")
+}
+
+func (r *menuReader) emitBev() model.Beverage {
+	bev := r.bev
+	r.bev = nil
+	return bev
+}
+
+func (r *menuReader) NextBeverage() (model.Beverage, error) {
+	for {
+		textFrag := r.NextText()
+		if textFrag == nil {
+			return r.emitBev(), nil
+		}
+
+		role := r.layout.role(textFrag.Font)
+		if role == RoleSection {
+			r.section, r.inSection = r.layout.section(textFrag.Text())
+
+			if lastBev := r.emitBev(); lastBev != nil {
+				return lastBev, nil
+			}
+			continue
+		}
+
+		if !r.inSection {
+			continue
+		}
+
+		if role == RoleTitle {
+			newBev := model.CreateBeverage(
+				fixName(r.section.modifyName(textFrag.Text())))
+			newBev.SetMenuSection(r.section.Name)
+			if r.bev != nil {
+				result := r.bev
+				r.bev = newBev
+				return result, nil
+			}
+			r.bev = newBev
+			continue
+		}
+
+		if r.bev == nil {
+			continue
+		}
+
+		switch role {
+		case RoleStyle:
+			if !r.addPour(textFrag.Text()) && r.bev.Type() == "" {
+				r.bev.SetType(textFrag.Text())
+			}
+		case RoleBody:
+			r.setDescriptionABV(textFrag.Text())
+		case RolePrice:
+			r.addPour(textFrag.Text())
+		}
+	}
+}
+
+var rABVSuffix = regexp.MustCompile(`\s*(\d+(?:[.]\d+)?)%`)
+
+func (r *menuReader) setDescriptionABV(desc string) {
+	if abvMatch := rABVSuffix.FindStringSubmatch(desc); abvMatch != nil {
+		desc = rABVSuffix.ReplaceAllString(desc, "")
+		if abv, err := strconv.ParseFloat(abvMatch[1], 64); err == nil && abv > 0.0 {
+			r.bev.SetAbv(abv)
+		}
+	}
+	r.bev.SetDescription(desc)
+}
+
+var rPourPrice = regexp.MustCompile(`(\w+)(?: -)? (\$\d+(?:[.]\d+)?)`)
+
+// addPour records a "size - $price" line as a serving, and in the layout's
+// serving attribute, returning false if text is not a price line.
+func (r *menuReader) addPour(text string) bool {
+	pour := rPourPrice.FindStringSubmatch(text)
+	if pour == nil {
+		return false
+	}
+	r.bev.AddServing(model.ParseServing(pour[1], pour[2]))
+	property := r.layout.ServingProperty
+	if property == "" {
+		return true
+	}
+	servings := r.bev.Attribute(property)
+	if servings != "" {
+		servings += ", "
+	}
+	servings += pour[1] + ": " + pour[2]
+	r.bev.SetAttribute(property, servings)
+	return true
+}
+
+// Provider options that configure a Layout. Font options take a font
+// pattern "NAME[@SIZE]", where NAME is a regular expression and SIZE is
+// either a size or a "MIN-MAX" range. OptionSections lists the included
+// section headings separated by ";", each optionally followed by
+// "=> Prefix" to prefix the names of beverages in that section.
+// OptionLigatureRepair set to "true" enables Layout.LigatureRepair.
+const (
+	OptionSectionFont = "pdfSectionFont"
+	OptionTitleFont   = "pdfTitleFont"
+	OptionStyleFont   = "pdfStyleFont"
+	OptionBodyFont    = "pdfBodyFont"
+	OptionPriceFont   = "pdfPriceFont"
+	OptionSections    = "pdfSections"
+
+	OptionLigatureRepair = "pdfLigatureRepair"
+)
+
+var fontOptionRoles = []struct {
+	option string
+	role   Role
+}{
+	{OptionSectionFont, RoleSection},
+	{OptionTitleFont, RoleTitle},
+	{OptionStyleFont, RoleStyle},
+	{OptionBodyFont, RoleBody},
+	{OptionPriceFont, RolePrice},
+}
+
+// LayoutFromOptions builds a layout from provider options. Several
+// patterns may be given for one role by separating them with "|".
+func LayoutFromOptions(options map[string]string, servingProperty string) (*Layout, error) {
+	layout := &Layout{ServingProperty: servingProperty}
+	for _, fontOption := range fontOptionRoles {
+		patterns := options[fontOption.option]
+		if patterns == "" {
+			continue
+		}
+		for _, pattern := range strings.Split(patterns, "|") {
+			rule, err := ParseFontRule(fontOption.role, pattern)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", fontOption.option, err)
+			}
+			layout.Fonts = append(layout.Fonts, rule)
+		}
+	}
+	if len(layout.Fonts) == 0 {
+		return nil, fmt.Errorf("no font options for PDF layout")
+	}
+	layout.Sections = ParseSections(options[OptionSections])
+	layout.LigatureRepair = options[OptionLigatureRepair] == "true"
+	return layout, nil
+}
+
+// ParseFontRule parses a font pattern "NAME[@SIZE]" or "NAME@MIN-MAX".
+func ParseFontRule(role Role, pattern string) (FontRule, error) {
+	rule := FontRule{Role: role}
+	namePattern, sizePattern := strings.TrimSpace(pattern), ""
+	if at := strings.LastIndex(namePattern, "@"); at != -1 {
+		namePattern, sizePattern = namePattern[:at], namePattern[at+1:]
+	}
+
+	if namePattern != "" {
+		rName, err := regexp.Compile(namePattern)
+		if err != nil {
+			return rule, err
+		}
+		rule.Name = rName
+	}
+
+	if sizePattern != "" {
+		bounds := strings.SplitN(sizePattern, "-", 2)
+		var err error
+		if rule.MinSize, err = strconv.ParseFloat(bounds[0], 64); err != nil {
+			return rule, fmt.Errorf("bad font size %#v", sizePattern)
+		}
+		rule.MaxSize = rule.MinSize
+		if len(bounds) == 2 {
+			if rule.MaxSize, err = strconv.ParseFloat(bounds[1], 64); err != nil {
+				return rule, fmt.Errorf("bad font size %#v", sizePattern)
+			}
+		}
+		if rule.MinSize > rule.MaxSize {
+			rule.MinSize, rule.MaxSize = rule.MaxSize, rule.MinSize
+		}
+	}
+	return rule, nil
+}
+
+// ParseSections parses a section list "Heading; Other Heading => Prefix".
+func ParseSections(spec string) map[string]Section {
+	sections := map[string]Section{}
+	for _, entry := range strings.Split(spec, ";") {
+		heading, prefix := entry, ""
+		if arrow := strings.Index(entry, "=>"); arrow != -1 {
+			heading, prefix = entry[:arrow], entry[arrow+2:]
+		}
+		heading = strings.TrimSpace(heading)
+		if heading == "" {
+			continue
+		}
+		sections[heading] = Section{
+			Name:   heading,
+			Prefix: strings.TrimSpace(prefix),
+		}
+	}
+	return sections
+}
+
+// String formats the rule as a font pattern that ParseFontRule accepts.
+func (f FontRule) String() string {
+	pattern := ""
+	if f.Name != nil {
+		pattern = f.Name.String()
+	}
+	switch {
+	case f.MinSize == 0 && f.MaxSize == 0:
+	case math.Abs(f.MinSize-f.MaxSize) < sizeEpsilon:
+		pattern += "@" + strconv.FormatFloat(f.MinSize, 'f', -1, 64)
+	default:
+		pattern += "@" + strconv.FormatFloat(f.MinSize, 'f', -1, 64) +
+			"-" + strconv.FormatFloat(f.MaxSize, 'f', -1, 64)
+	}
+	return pattern
+}
@@ -0,0 +1,115 @@
+package pdfmenu
+
+import (
+	"testing"
+
+	"github.com/bevly/bevly/pdftext"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPDF = "../alepdf/test/menu.pdf"
+
+var aleHouseOptions = map[string]string{
+	OptionSectionFont: "Duke-Fill@22",
+	OptionTitleFont:   "Stag-Semibold@11",
+	OptionStyleFont:   "Stag-Semibold@9",
+	OptionBodyFont:    "Stag-Book@8.5-9.5",
+	OptionSections:    "Guest Drafts; OLIVER BREWING CO. => Oliver",
+}
+
+func TestParseFontRule(t *testing.T) {
+	tests := []struct {
+		pattern string
+		font    pdftext.Font
+		matches bool
+	}{
+		{"Stag-Semibold@11", pdftext.Font{Name: "Stag-Semibold", Size: 11}, true},
+		{"Stag-Semibold@11", pdftext.Font{Name: "Stag-Semibold", Size: 9}, false},
+		{"Stag-Semibold@11", pdftext.Font{Name: "ABCDEF+Stag-Semibold", Size: 11.01}, true},
+		{"Stag-Semibold", pdftext.Font{Name: "Stag-Semibold-Italic", Size: 11}, false},
+		{"Stag-.*@9-10", pdftext.Font{Name: "Stag-Book", Size: 9.5}, true},
+		{"Stag-.*@10-9", pdftext.Font{Name: "Stag-Book", Size: 11}, false},
+		{"@22", pdftext.Font{Name: "Anything", Size: 22}, true},
+	}
+	for _, test := range tests {
+		rule, err := ParseFontRule(RoleTitle, test.pattern)
+		if err != nil {
+			t.Errorf("ParseFontRule(%#v) failed: %s", test.pattern, err)
+			continue
+		}
+		if rule.Matches(test.font) != test.matches {
+			t.Errorf("ParseFontRule(%#v).Matches(%v) == %v, want %v",
+				test.pattern, test.font, !test.matches, test.matches)
+		}
+	}
+
+	if _, err := ParseFontRule(RoleTitle, "Stag@big"); err == nil {
+		t.Errorf("ParseFontRule(%#v) must fail", "Stag@big")
+	}
+}
+
+func TestFontRuleFor(t *testing.T) {
+	font := pdftext.Font{Name: "ABCDEF+Garamond", Size: 11}
+	rule := FontRuleFor(RoleTitle, font)
+	assert.True(t, rule.Matches(font), "matches its own font")
+	assert.True(t, rule.Matches(pdftext.Font{Name: "GHIJKL+Garamond", Size: 11}),
+		"matches other subsets of the font")
+	assert.False(t, rule.Matches(pdftext.Font{Name: "Garamond-Bold", Size: 11}))
+	assert.Equal(t, "Garamond@11", rule.String())
+}
+
+func TestParseSections(t *testing.T) {
+	sections := ParseSections(aleHouseOptions[OptionSections])
+	assert.Equal(t, 2, len(sections))
+	assert.Equal(t, "", sections["Guest Drafts"].Prefix)
+	assert.Equal(t, "Oliver", sections["OLIVER BREWING CO."].Prefix)
+}
+
+func TestLayoutFromOptions(t *testing.T) {
+	layout, err := LayoutFromOptions(aleHouseOptions, "servings")
+	if err != nil {
+		t.Fatalf("LayoutFromOptions failed: %s", err)
+	}
+	bevs, err := layout.Parse(testPDF)
+	if err != nil {
+		t.Fatalf("Parse failed: %s", err)
+	}
+	if !assert.Equal(t, 32, len(bevs), "must find all beers") {
+		return
+	}
+	assert.Equal(t, "Jailbreak Desserted", bevs[0].DisplayName())
+	assert.Equal(t, "Chocolate Coconut Porter", bevs[0].Type())
+	assert.Equal(t, 6.9, bevs[0].Abv())
+	assert.Equal(t, "5oz: $2.75, 10oz: $4.95, 16oz: $6.95, 23oz: $9.25, Gr: $27.95",
+		bevs[0].Attribute("servings"))
+	assert.Equal(t, "Oliver 3 Lions", bevs[29].DisplayName(), "section prefix")
+	assert.Equal(t, "Guest Drafts", bevs[0].MenuSection())
+	assert.Equal(t, "OLIVER BREWING CO.", bevs[29].MenuSection())
+}
+
+func TestLayoutAllSections(t *testing.T) {
+	options := map[string]string{}
+	for k, v := range aleHouseOptions {
+		options[k] = v
+	}
+	delete(options, OptionSections)
+
+	layout, err := LayoutFromOptions(options, "")
+	if err != nil {
+		t.Fatalf("LayoutFromOptions failed: %s", err)
+	}
+	bevs, err := layout.Parse(testPDF)
+	if err != nil {
+		t.Fatalf("Parse failed: %s", err)
+	}
+	assert.True(t, len(bevs) >= 32, "must include beverages from every section")
+	for _, bev := range bevs {
+		assert.NotEqual(t, "Oliver 3 Lions", bev.DisplayName(), "no prefix without section config")
+	}
+}
+
+func TestLayoutFromOptionsNoFonts(t *testing.T) {
+	if _, err := LayoutFromOptions(map[string]string{}, ""); err == nil {
+		t.Errorf("LayoutFromOptions with no fonts must fail")
+	}
+}
@@ -0,0 +1,37 @@
+package menu
+
+import (
+	"testing"
+
+	"github.com/bevly/bevly/fetch/menu/pdfmenu"
+	"github.com/bevly/bevly/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPDFMenu(t *testing.T) {
+	ts := alehouseStub()
+	defer ts.Close()
+
+	prov := model.CreateMenuProvider("pub", "Pub", ts.URL+"/menu/", "pdf")
+	prov.SetOption(PDFLinkOption, "#ales a")
+	prov.SetOption(pdfmenu.OptionSectionFont, "Duke-Fill@22")
+	prov.SetOption(pdfmenu.OptionTitleFont, "Stag-Semibold@11")
+	prov.SetOption(pdfmenu.OptionStyleFont, "Stag-Semibold@9")
+	prov.SetOption(pdfmenu.OptionBodyFont, "Stag-Book@9")
+	prov.SetOption(pdfmenu.OptionSections, "Guest Drafts;OLIVER BREWING CO.=>Oliver")
+
+	bevs, err := pdfMenu(prov)
+	assert.Nil(t, err, "stub fetch must succeed")
+	if !assert.Equal(t, 32, len(bevs), "must find all beers") {
+		return
+	}
+	assert.Equal(t, "Jailbreak Desserted", bevs[0].DisplayName())
+	assert.Equal(t, "Oliver 3 Lions", bevs[29].DisplayName())
+	assert.Equal(t, "5oz: $2.75, 10oz: $4.95, 16oz: $6.95, 23oz: $9.25, Gr: $27.95",
+		bevs[0].Attribute("pubServingSize"))
+	if assert.Equal(t, 5, len(bevs[0].Servings()), "must record each pour") {
+		assert.Equal(t, model.Serving{Name: "5oz", Size: 5, Unit: "oz", Price: 2.75, Currency: "USD"},
+			bevs[0].Servings()[0])
+		assert.Equal(t, 64.0, bevs[0].Servings()[4].Ounces(), "growler size")
+	}
+}
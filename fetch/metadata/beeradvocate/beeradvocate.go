@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/bevly/bevly/fetch/metadata/profile"
 	bevHtml "github.com/bevly/bevly/html"
 	"github.com/bevly/bevly/httpagent"
 	"github.com/bevly/bevly/model"
@@ -23,6 +24,19 @@ var (
 
 const DescriptionProperty = "baDescription"
 
+// Source names BeerAdvocate in field provenance; it is the most trusted
+// metadata source.
+const Source = "BA"
+const AccuracyScore = 10
+
+func init() {
+	model.SetSourceScore(Source, AccuracyScore)
+	model.RegisterSourceData(Source, model.SourceData{
+		Ratings:    []string{"BA", "BAbro"},
+		Attributes: []string{DescriptionProperty},
+	})
+}
+
 func FetchMetadata(bev model.Beverage, search websearch.Search) error {
 	log.Printf("Searching for BA profile for %s", bev)
 	baURL, err := FindProfile(bev, search)
@@ -33,9 +47,48 @@ func FetchMetadata(bev model.Beverage, search websearch.Search) error {
 	return fetchBAMetadata(bev, baURL)
 }
 
+// FetchProfileMetadata fetches metadata from a known BA profile, such as one
+// staff have pinned.
+func FetchProfileMetadata(bev model.Beverage, profileURL string) error {
+	return fetchBAMetadata(bev, profileURL)
+}
+
+// ProfileMatchConfidence is the confidence a candidate profile must reach
+// to be chosen; see profile.Site.Rank.
+const ProfileMatchConfidence = 0.5
+
+var profileSite = &profile.Site{
+	Source:    Source,
+	IsProfile: IsBeerAdvocateProfile,
+	Title:     stripBAName,
+}
+
+// FindProfile finds the beverage's BA profile, searching BeerAdvocate
+// itself first, and the web if that fails or finds no good candidate. The
+// best candidate is chosen, and the candidates considered are kept on the
+// beverage. Returns profile.ErrPendingReview if the best candidate awaits
+// review.
 func FindProfile(bev model.Beverage, s websearch.Search) (string, error) {
-	baURL, err := baSearch(bev.SearchName(), s)
+	name := bev.SearchName()
+	candidates := []model.ProfileCandidate{}
+	if Site != nil {
+		results, err := Site.Search(name)
+		if err != nil {
+			log.Printf("FindProfile(%s): site search failed, searching the web: %s\n", bev, err)
+		}
+		candidates = profileSite.Rank(bev, candidates, results)
+	}
+	if _, ok := profile.Best(candidates, ProfileMatchConfidence); !ok {
+		results, err := s.Search("beeradvocate " + name)
+		if err != nil {
+			return "", err
+		}
+		log.Printf("FindProfile(%s): %d web results\n", bev, len(results))
+		candidates = profileSite.Rank(bev, candidates, results)
+	}
+	baURL, err := profileSite.Choose(bev, candidates, ProfileMatchConfidence)
 	if err != nil {
+		bev.SetNeedSync(true)
 		return "", err
 	}
 	if baURL == "" {
@@ -47,36 +100,7 @@ func FindProfile(bev model.Beverage, s websearch.Search) (string, error) {
 }
 
 func stripBAName(text string) string {
-	return strings.Replace(text, "Beer Advocate", "", 1)
-}
-
-func baSearch(name string, search websearch.Search) (string, error) {
-	terms := "beeradvocate " + name
-	results, err := search.Search(terms)
-	if err != nil {
-		return "", err
-	}
-	log.Printf("baSearch(%s): %d results\n", terms, len(results))
-	if len(results) > 0 {
-		for _, result := range results {
-			urlString := result.URL
-			log.Printf("baSearch(%s): considering %s (%s)\n",
-				terms, result.Text, result.URL)
-			if IsBeerAdvocateProfile(urlString) {
-				cleansedText := stripBAName(result.Text)
-				confidence := text.NameMatchConfidence(cleansedText, name)
-				if confidence < 0.13 {
-					log.Printf("baSearch(%s): rejecting %s (confidence: %.2f%%)\n",
-						terms, cleansedText, confidence*100)
-					continue
-				}
-				log.Printf("baSearch(%s): accepting %s (confidence: %.2f%%)\n",
-					terms, cleansedText, confidence*100)
-				return urlString, nil
-			}
-		}
-	}
-	return "", nil
+	return strings.Replace(strings.Replace(text, "Beer Advocate", "", 1), "BeerAdvocate", "", 1)
 }
 
 var rBeerAdvocateProfileURL = regexp.MustCompile(`beeradvocate.*?/beer/profile/\d+/\d+`)
@@ -98,19 +122,19 @@ func fetchBAMetadata(bev model.Beverage, metaURL string) error {
 		return ErrNotBABeer
 	}
 
+	prov := model.CreateProvenance(Source, model.SourceScore(Source))
 	bev.SetNeedSync(true)
-	bev.SetAccuracyScore(10)
-	bev.SetLink(metaURL)
-	setBATitleBrewer(bev, doc)
-	setBATypeAbv(bev, doc)
+	model.SetField(bev, model.FieldLink, metaURL, prov)
+	setBATitleBrewer(bev, doc, prov)
+	setBATypeAbv(bev, doc, prov)
 	setBARatings(bev, doc)
-	setBADescription(bev, doc)
+	setBADescription(bev, doc, prov)
 	return nil
 }
 
 var rDescRegexp = regexp.MustCompile(`(?s)Notes/Commercial Description:</b>(.*)`)
 
-func setBADescription(bev model.Beverage, doc *goquery.Document) {
+func setBADescription(bev model.Beverage, doc *goquery.Document, prov model.Provenance) {
 	parentHtml, err := doc.Find("a[href^=\"/beer/style\"]").First().Parent().Html()
 	if err != nil {
 		log.Printf("setBADescription(%s): error getting description: %s\n",
@@ -127,7 +151,7 @@ func setBADescription(bev model.Beverage, doc *goquery.Document) {
 	desc := cleanseBADescription(match[1])
 	if desc != "" && desc != "No notes at this time." {
 		log.Printf("setBADescription(%s): desc=%s\n", bev, desc)
-		bev.SetDescription(desc)
+		model.SetField(bev, model.FieldDescription, desc, prov)
 		bev.SetAttribute(DescriptionProperty, desc)
 	} else {
 		log.Printf("setBADescription(%s): no desc\n", bev)
@@ -160,25 +184,25 @@ func addRating(bev model.Beverage, name string, text string) {
 	}
 }
 
-func setBATitleBrewer(bev model.Beverage, doc *goquery.Document) {
+func setBATitleBrewer(bev model.Beverage, doc *goquery.Document, prov model.Provenance) {
 	header := doc.Find(".titleBar h1")
 	combinedTitle := header.Text()
 	breweryTitle := header.Find("span").Text()
 	beerName := strings.Replace(combinedTitle, breweryTitle, "", 1)
 
-	bev.SetName(text.Normalize(beerName))
-	bev.SetBrewer(normalizeBrewer(breweryTitle))
+	model.SetField(bev, model.FieldName, text.Normalize(beerName), prov)
+	model.SetField(bev, model.FieldBrewer, normalizeBrewer(breweryTitle), prov)
 	log.Printf("setBATitleBrewer: %s name=%s brewer=%s\n", bev, bev.Name(), bev.Brewer())
 }
 
-func setBATypeAbv(bev model.Beverage, doc *goquery.Document) {
+func setBATypeAbv(bev model.Beverage, doc *goquery.Document, prov model.Provenance) {
 	styleTag := doc.Find("a[href^=\"/beer/style\"]").First()
 	style := styleTag.Text()
-	bev.SetType(style)
+	model.SetField(bev, model.FieldType, style, prov)
 
 	rawInfoText := styleTag.Parent().Text()
 	abv := extractAbv(rawInfoText)
-	bev.SetAbv(abv)
+	model.SetAbvField(bev, abv, prov)
 }
 
 var abvPattern = regexp.MustCompile(`(?s)ABV.*?([\d.]+)%`)
@@ -1,8 +1,10 @@
 package beeradvocate
 
 import (
+	"github.com/bevly/bevly/fetch/metadata/profile"
 	"github.com/bevly/bevly/httpfilestub"
 	"github.com/bevly/bevly/model"
+	"github.com/bevly/bevly/websearch"
 	"github.com/bevly/bevly/websearch/duckduckgo"
 	"github.com/stretchr/testify/assert"
 	"net/http"
@@ -55,10 +57,49 @@ func webSearchStub() *httptest.Server {
 func TestFindProfile(t *testing.T) {
 	ts := webSearchStub()
 	defer ts.Close()
+	// The site search finds nothing, so the web is searched:
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html><body>No results</body></html>"))
+	}))
+	defer site.Close()
+	defer func(s websearch.Search) { Site = s }(Site)
+	Site = SiteSearchWithURL(site.URL)
 
 	search := duckduckgo.SearchWithURL(ts.URL)
-	profile, err := FindProfile(model.CreateBeverage("boulder cold hop english ipa"), search)
+	bev := model.CreateBeverage("boulder cold hop english ipa")
+	_, err := FindProfile(bev, search)
+	assert.Equal(t, profile.ErrPendingReview, err, "a loose match awaits review")
+	pending, _ := model.PendingCandidate(bev, Source)
+	assert.Equal(t, "http://www.beeradvocate.com/beer/profile/130/36468/", pending.URL)
+
+	defer func(confidence float64) { profile.ReviewConfidence = confidence }(profile.ReviewConfidence)
+	profile.ReviewConfidence = 0
+	profileURL, err := FindProfile(model.CreateBeverage("boulder cold hop english ipa"), search)
 	assert.Nil(t, err, "FindProfile error")
 	assert.Equal(t, "http://www.beeradvocate.com/beer/profile/130/36468/",
-		profile, "find cold-hop british")
+		profileURL, "find cold-hop british")
+}
+
+func TestFindProfileSiteSearch(t *testing.T) {
+	ts := httpfilestub.Server("ba_search_test.html")
+	defer ts.Close()
+	defer func(s websearch.Search) { Site = s }(Site)
+	Site = SiteSearchWithURL(ts.URL)
+
+	results, err := Site.Search("racer 5")
+	if assert.Nil(t, err) && assert.Equal(t, 3, len(results)) {
+		assert.Equal(t, "https://www.beeradvocate.com/beer/profile/610/4473/", results[0].URL)
+		assert.Equal(t, "Racer 5 India Pale Ale - Bear Republic Brewing Co.", results[0].Text)
+	}
+
+	// Web search is not needed:
+	bev := model.CreateBeverage("Bear Republic Racer 5")
+	bev.SetAbv(7.5)
+	profile, err := FindProfile(bev, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://www.beeradvocate.com/beer/profile/610/4473/", profile)
+	if assert.Equal(t, 3, len(bev.ProfileCandidates()), "candidates are kept") {
+		assert.True(t, bev.ProfileCandidates()[0].Chosen)
+		assert.Equal(t, 1.0, bev.ProfileCandidates()[0].AbvScore)
+	}
 }
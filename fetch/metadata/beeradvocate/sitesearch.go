@@ -0,0 +1,99 @@
+package beeradvocate
+
+import (
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/bevly/bevly/httpagent"
+	"github.com/bevly/bevly/text"
+	"github.com/bevly/bevly/throttle"
+	"github.com/bevly/bevly/websearch"
+)
+
+const SiteSearchBaseURL = "https://www.beeradvocate.com/search/"
+
+// ProfileBaseURL resolves the relative profile links of search results.
+const ProfileBaseURL = "https://www.beeradvocate.com/"
+
+var Throttle = throttle.Default("BeerAdvocate")
+
+// SiteSearch searches BeerAdvocate's own beer search, returning the
+// profiles its results page links to.
+type SiteSearch struct {
+	BaseURL  string
+	Throttle *throttle.Throttle
+}
+
+func DefaultSiteSearch() websearch.Search {
+	return &SiteSearch{BaseURL: SiteSearchBaseURL, Throttle: Throttle}
+}
+
+func SiteSearchWithURL(baseURL string) websearch.Search {
+	return &SiteSearch{BaseURL: baseURL}
+}
+
+// Site is searched for profiles before falling back to web search. It may
+// be set to nil to only use web search.
+var Site = DefaultSiteSearch()
+
+func (s *SiteSearch) Search(terms string) ([]websearch.Result, error) {
+	if s.Throttle != nil {
+		s.Throttle.DelayInvocation()
+	}
+	searchURL := s.SearchURL(terms)
+	log.Printf("BA search: %s / %s\n", terms, searchURL)
+	doc, err := httpagent.New().GetDoc(searchURL)
+	if err != nil {
+		return nil, err
+	}
+	// A search with one result redirects straight to its profile:
+	if doc.Url != nil && IsBeerAdvocateProfile(doc.Url.String()) {
+		title := text.Normalize(doc.Find(".titleBar h1").Text())
+		return []websearch.Result{{URL: doc.Url.String(), Text: title}}, nil
+	}
+	return siteSearchResults(doc), nil
+}
+
+func (s *SiteSearch) SearchURL(terms string) string {
+	return s.BaseURL + "?" + url.Values{"q": {terms}, "qt": {"beer"}}.Encode()
+}
+
+var rBreweryProfilePath = regexp.MustCompile(`/beer/profile/\d+/?$`)
+
+// siteSearchResults lists the distinct beer profiles a results page links
+// to, in page order. Each result's text is the beer's name followed by the
+// name of the brewery linked alongside it, as web search titles have them,
+// and its snippet is the rest of the text listed with it, such as the
+// style and ABV.
+func siteSearchResults(doc *goquery.Document) []websearch.Result {
+	base, _ := url.Parse(ProfileBaseURL)
+	results := []websearch.Result{}
+	seen := map[string]bool{}
+	doc.Find("a[href]").Each(func(_ int, a *goquery.Selection) {
+		href, _ := a.Attr("href")
+		link, err := base.Parse(strings.TrimSpace(href))
+		if err != nil {
+			return
+		}
+		link.RawQuery, link.Fragment = "", ""
+		profileURL := link.String()
+		title := text.Normalize(a.Text())
+		if title == "" || seen[profileURL] || !IsBeerAdvocateProfile(profileURL) {
+			return
+		}
+		seen[profileURL] = true
+		brewery := a.Parent().Find("a[href]").FilterFunction(func(_ int, b *goquery.Selection) bool {
+			breweryHref, _ := b.Attr("href")
+			return rBreweryProfilePath.MatchString(breweryHref)
+		}).First()
+		if name := text.Normalize(brewery.Text()); name != "" {
+			title += " - " + name
+		}
+		snippet := text.Normalize(strings.Replace(a.Parent().Text(), a.Text(), "", 1))
+		results = append(results, websearch.Result{URL: profileURL, Text: title, Snippet: snippet})
+	})
+	return results
+}
@@ -0,0 +1,368 @@
+// Package catalog looks beverage metadata up in a local catalog file of
+// beers, so that syncs needn't search the web for beers it knows.
+//
+// Catalogs are CSV files with a header row, or JSONL files with one beer
+// per line, told apart by their extension. CSV columns are name, brewery,
+// style, abv, description, link and image; any other column ending in
+// "_rating" holds the percentage rating of the source it is named after,
+// such as "ratebeer_rating". JSONL records have the same fields, with the
+// ratings in a "ratings" object. Ratings are stored under the names the
+// metadata sources give them; see RatingSource.
+package catalog
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bevly/bevly/fetch/metadata/profile"
+	"github.com/bevly/bevly/model"
+	"github.com/bevly/bevly/text"
+)
+
+var ErrNoMatch = errors.New("no catalog match for beverage")
+
+// Source names the catalog in field provenance. Catalogs are usually
+// exports of the sites bevly scrapes, so they're trusted a little below
+// them.
+const Source = "Catalog"
+const AccuracyScore = 7
+
+func init() {
+	model.SetSourceScore(Source, AccuracyScore)
+}
+
+// FileEnv names the catalog file.
+const FileEnv = "BEVLY_CATALOG"
+
+// A beer matches if its text.NameIdentityConfidence with the beverage
+// reaches MatchConfidence and profile.ReviewConfidence; the match is
+// conclusive, and the web needn't be searched, if it reaches
+// ConclusiveConfidence and the beer's brewery, style and ABV are all known.
+const (
+	MatchConfidence      = 0.6
+	ConclusiveConfidence = 0.9
+)
+
+// Entry is a beer in the catalog.
+type Entry struct {
+	Name        string         `json:"name"`
+	Brewery     string         `json:"brewery"`
+	Style       string         `json:"style"`
+	Abv         float64        `json:"abv"`
+	Description string         `json:"description"`
+	Link        string         `json:"link"`
+	Image       string         `json:"image"`
+	Ratings     map[string]int `json:"ratings"`
+}
+
+// FullName is the beer's name prefixed with its brewery's, as menus
+// usually list it.
+func (e *Entry) FullName() string {
+	return text.Normalize(e.Brewery + " " + e.Name)
+}
+
+// Complete reports whether the entry has the metadata that makes a match
+// conclusive.
+func (e *Entry) Complete() bool {
+	return e.Brewery != "" && e.Style != "" && e.Abv > 0
+}
+
+// Catalog is a catalog file loaded into memory, indexed by the words of its
+// beers' names. Refresh reloads it when the file changes.
+type Catalog struct {
+	File string
+
+	mutex   sync.RWMutex
+	modTime time.Time
+	entries []Entry
+	byWord  map[string][]int
+}
+
+var defaultCatalog *Catalog
+var defaultCatalogOnce sync.Once
+
+// Default returns the catalog named by FileEnv, or nil if there is none or
+// it can't be loaded.
+func Default() *Catalog {
+	defaultCatalogOnce.Do(func() {
+		file := os.Getenv(FileEnv)
+		if file == "" {
+			return
+		}
+		catalog, err := Load(file)
+		if err != nil {
+			log.Printf("catalog: can't load %s: %s\n", file, err)
+			return
+		}
+		defaultCatalog = catalog
+	})
+	return defaultCatalog
+}
+
+// Load reads a catalog file.
+func Load(file string) (*Catalog, error) {
+	catalog := &Catalog{File: file}
+	if err := catalog.Refresh(); err != nil {
+		return nil, err
+	}
+	return catalog, nil
+}
+
+// Refresh reloads the catalog if its file has changed since it was loaded.
+// The loaded catalog is kept if the file can't be read.
+func (c *Catalog) Refresh() error {
+	info, err := os.Stat(c.File)
+	if err != nil {
+		return err
+	}
+	c.mutex.RLock()
+	current := info.ModTime().Equal(c.modTime)
+	c.mutex.RUnlock()
+	if current {
+		return nil
+	}
+
+	entries, err := ReadFile(c.File)
+	if err != nil {
+		return err
+	}
+	byWord := map[string][]int{}
+	for i := range entries {
+		for _, word := range nameWords(entries[i].FullName()) {
+			byWord[word] = append(byWord[word], i)
+		}
+	}
+	c.mutex.Lock()
+	c.entries, c.byWord, c.modTime = entries, byWord, info.ModTime()
+	c.mutex.Unlock()
+	log.Printf("catalog: loaded %d beers from %s\n", len(entries), c.File)
+	return nil
+}
+
+// Len is the number of beers in the catalog.
+func (c *Catalog) Len() int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return len(c.entries)
+}
+
+// nameWords splits a name into the words NameIdentityConfidence compares.
+func nameWords(name string) []string {
+	return strings.Fields(text.BreweryKey(name))
+}
+
+// Lookup finds the beer whose name best matches name, and the
+// text.NameIdentityConfidence of the match. Only beers sharing a word with
+// name are compared. Returns nil if no beer shares a word.
+func (c *Catalog) Lookup(name string) (*Entry, float64) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	var best *Entry
+	bestConfidence := 0.0
+	compared := map[int]bool{}
+	for _, word := range nameWords(name) {
+		for _, i := range c.byWord[word] {
+			if compared[i] {
+				continue
+			}
+			compared[i] = true
+			entry := &c.entries[i]
+			confidence := text.NameIdentityConfidence(name, entry.FullName())
+			if best == nil || confidence > bestConfidence {
+				best, bestConfidence = entry, confidence
+			}
+		}
+	}
+	if best == nil {
+		return nil, 0
+	}
+	found := *best
+	return &found, bestConfidence
+}
+
+// FetchMetadata sets a beverage's metadata from its best catalog match,
+// and reports whether the match is conclusive.
+func (c *Catalog) FetchMetadata(bev model.Beverage) (bool, error) {
+	if err := c.Refresh(); err != nil {
+		log.Printf("catalog: can't refresh %s: %s\n", c.File, err)
+	}
+
+	entry, confidence := c.Lookup(bev.SearchName())
+	if bev.Brewer() != "" {
+		// Menus that list the brewer apart may not repeat it in the name:
+		byBrewer, brewerConfidence := c.Lookup(bev.Brewer() + " " + bev.SearchName())
+		if brewerConfidence > confidence {
+			entry, confidence = byBrewer, brewerConfidence
+		}
+	}
+	if entry == nil || confidence < MatchConfidence {
+		log.Printf("catalog(%s): no match\n", bev)
+		return false, ErrNoMatch
+	}
+	if confidence < profile.ReviewConfidence {
+		// The catalog has no profiles for staff to review, so its weak
+		// matches are left to the sources that do:
+		log.Printf("catalog(%s): passing over %s (confidence: %.2f%%), below review confidence\n",
+			bev, entry.FullName(), confidence*100)
+		return false, ErrNoMatch
+	}
+	conclusive := confidence >= ConclusiveConfidence && entry.Complete()
+	log.Printf("catalog(%s): accepting %s (confidence: %.2f%%, conclusive: %v)\n",
+		bev, entry.FullName(), confidence*100, conclusive)
+	setEntryMetadata(bev, entry)
+	return conclusive, nil
+}
+
+func setEntryMetadata(bev model.Beverage, entry *Entry) {
+	prov := model.CreateProvenance(Source, model.SourceScore(Source))
+	bev.SetNeedSync(true)
+
+	model.SetField(bev, model.FieldName, text.Normalize(entry.Name), prov)
+	model.SetField(bev, model.FieldBrewer, text.Normalize(entry.Brewery), prov)
+	model.SetField(bev, model.FieldType, text.Normalize(entry.Style), prov)
+	model.SetField(bev, model.FieldDescription, text.NormalizeMultiline(entry.Description), prov)
+	model.SetField(bev, model.FieldLink, entry.Link, prov)
+	model.SetField(bev, model.FieldImage, entry.Image, prov)
+	model.SetAbvField(bev, entry.Abv, prov)
+	for source, rating := range entry.Ratings {
+		bev.AddRating(model.CreateRating(RatingSource(source), rating))
+	}
+}
+
+// ratingSources maps the names catalogs give ratings to the names the
+// metadata sources rate under.
+var ratingSources = map[string]string{
+	"ratebeer":       "rb",
+	"rb":             "rb",
+	"ratebeer_style": "rb:style",
+	"rb_style":       "rb:style",
+	"beeradvocate":   "BA",
+	"ba":             "BA",
+	"ba_bros":        "BAbro",
+	"babro":          "BAbro",
+	"untappd":        "untappd",
+}
+
+// RatingSource names a catalog rating as the metadata source it comes
+// from does, so that clients see the same ratings whichever fetched them.
+// Unknown names are kept as they are.
+func RatingSource(name string) string {
+	if source, ok := ratingSources[strings.ToLower(name)]; ok {
+		return source
+	}
+	return name
+}
+
+// ReadFile reads the beers in a CSV or JSONL catalog file.
+func ReadFile(file string) ([]Entry, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".csv":
+		return ReadCSV(f)
+	case ".jsonl", ".json":
+		return ReadJSONL(f)
+	}
+	return nil, fmt.Errorf("unknown catalog format: %s", file)
+}
+
+// ReadJSONL reads one beer per line, skipping blank lines.
+func ReadJSONL(r io.Reader) ([]Entry, error) {
+	entries := []Entry{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("catalog line %d: %s", line, err)
+		}
+		if entry.Name != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+const ratingColumnSuffix = "_rating"
+
+// ReadCSV reads beers from CSV with a header row naming its columns.
+func ReadCSV(r io.Reader) ([]Entry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+
+	entries := []Entry{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		entry := Entry{Ratings: map[string]int{}}
+		for i, value := range record {
+			if i >= len(header) {
+				break
+			}
+			value = strings.TrimSpace(value)
+			switch column := header[i]; column {
+			case "name":
+				entry.Name = value
+			case "brewery":
+				entry.Brewery = value
+			case "style":
+				entry.Style = value
+			case "abv":
+				if value != "" {
+					if entry.Abv, err = strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64); err != nil {
+						return nil, fmt.Errorf("catalog line %d: bad abv %q", line, value)
+					}
+				}
+			case "description":
+				entry.Description = value
+			case "link":
+				entry.Link = value
+			case "image":
+				entry.Image = value
+			default:
+				if strings.HasSuffix(column, ratingColumnSuffix) && value != "" {
+					rating, err := strconv.Atoi(value)
+					if err != nil {
+						return nil, fmt.Errorf("catalog line %d: bad %s %q", line, column, value)
+					}
+					entry.Ratings[strings.TrimSuffix(column, ratingColumnSuffix)] = rating
+				}
+			}
+		}
+		if entry.Name != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,111 @@
+package catalog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bevly/bevly/fetch/metadata/profile"
+	"github.com/bevly/bevly/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadFile(t *testing.T) {
+	entries, err := ReadFile("catalog_test.csv")
+	if assert.Nil(t, err) && assert.Equal(t, 4, len(entries)) {
+		racer := entries[0]
+		assert.Equal(t, "Racer 5 IPA", racer.Name)
+		assert.Equal(t, 7.5, racer.Abv)
+		assert.Equal(t, "Hoppy, bitter, and highly aromatic.", racer.Description)
+		assert.Equal(t, map[string]int{"ratebeer": 97, "ba": 93}, racer.Ratings)
+		assert.False(t, entries[3].Complete())
+	}
+
+	entries, err = ReadFile("catalog_test.jsonl")
+	if assert.Nil(t, err) && assert.Equal(t, 2, len(entries)) {
+		assert.Equal(t, "North Coast Brewing Co.", entries[1].Brewery)
+		assert.Equal(t, 9.0, entries[1].Abv)
+	}
+}
+
+func TestFetchMetadata(t *testing.T) {
+	catalog, err := Load("catalog_test.csv")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	bev := model.CreateBeverage("Dogfish Head 90 Minute IPA")
+	conclusive, err := catalog.FetchMetadata(bev)
+	assert.Nil(t, err)
+	assert.True(t, conclusive)
+	assert.Equal(t, "90 Minute IPA", bev.Name())
+	assert.Equal(t, "Imperial IPA", bev.Type())
+	assert.Equal(t, 9.0, bev.Abv())
+	assert.Equal(t, Source, bev.Provenance(model.FieldType).Source)
+
+	// The brewer may be listed apart from the name:
+	bev = model.CreateBeverage("Racer 5")
+	bev.SetBrewer("Bear Republic")
+	conclusive, err = catalog.FetchMetadata(bev)
+	assert.Nil(t, err)
+	assert.False(t, conclusive, "IPA is missing from the name")
+	assert.Equal(t, "Racer 5 IPA", bev.Name())
+	ratings := map[string]int{}
+	for _, rating := range bev.Ratings() {
+		ratings[rating.Source()] = rating.PercentageRating()
+	}
+	assert.Equal(t, map[string]int{"rb": 97, "BA": 93}, ratings, "ratings named as their sources name them")
+
+	bev = model.CreateBeverage("Oliver Draft Punk")
+	conclusive, err = catalog.FetchMetadata(bev)
+	assert.Nil(t, err)
+	assert.False(t, conclusive, "incomplete entry")
+
+	_, err = catalog.FetchMetadata(model.CreateBeverage("Dogfish Head 120 Minute IPA"))
+	assert.Equal(t, ErrNoMatch, err)
+
+	defer func(confidence float64) { profile.ReviewConfidence = confidence }(profile.ReviewConfidence)
+	profile.ReviewConfidence = 0.99
+	bev = model.CreateBeverage("Racer 5")
+	bev.SetBrewer("Bear Republic")
+	_, err = catalog.FetchMetadata(bev)
+	assert.Equal(t, ErrNoMatch, err, "matches below review confidence are passed over")
+	assert.Equal(t, "", bev.Name())
+}
+
+func TestRefresh(t *testing.T) {
+	dir, err := ioutil.TempDir("", "catalog")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "beers.jsonl")
+	assert.Nil(t, ioutil.WriteFile(file,
+		[]byte(`{"name": "Old Rasputin", "brewery": "North Coast"}`+"\n"), 0644))
+	catalog, err := Load(file)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 1, catalog.Len())
+
+	assert.Nil(t, ioutil.WriteFile(file, []byte(`{"name": "Old Rasputin"}`+"\n"+
+		`{"name": "Scrimshaw", "brewery": "North Coast"}`+"\n"), 0644))
+	later := time.Now().Add(time.Minute)
+	assert.Nil(t, os.Chtimes(file, later, later))
+	assert.Nil(t, catalog.Refresh())
+	assert.Equal(t, 2, catalog.Len())
+	entry, _ := catalog.Lookup("North Coast Scrimshaw")
+	if assert.NotNil(t, entry) {
+		assert.Equal(t, "Scrimshaw", entry.Name)
+	}
+}
+
+func TestRatingSource(t *testing.T) {
+	assert.Equal(t, "rb", RatingSource("RateBeer"))
+	assert.Equal(t, "BA", RatingSource("ba"))
+	assert.Equal(t, "BAbro", RatingSource("ba_bros"))
+	assert.Equal(t, "untappd", RatingSource("untappd"))
+	assert.Equal(t, "brewdog", RatingSource("brewdog"), "unknown names are kept")
+}
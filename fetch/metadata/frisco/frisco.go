@@ -16,6 +16,19 @@ const ProfileURLProperty = "friscoProfileUrl"
 const ServingSizeProperty = "friscoServingSize"
 const FriscoDescription = "friscoDescription"
 
+// Source names Frisco in field provenance; its profiles are trusted least.
+const Source = "Frisco"
+const AccuracyScore = 2
+
+// The profile URL and serving size come from Frisco's menu, not its
+// profiles, so they are kept when Frisco is blocked.
+func init() {
+	model.SetSourceScore(Source, AccuracyScore)
+	model.RegisterSourceData(Source, model.SourceData{
+		Attributes: []string{IBUProperty, FriscoDescription},
+	})
+}
+
 var ErrNoFriscoProfile = errors.New("no frisco profile")
 
 func Agent() *httpagent.Agent {
@@ -31,7 +44,12 @@ func FetchMetadata(bev model.Beverage) error {
 	if friscoProfile == "" {
 		return ErrNoFriscoProfile
 	}
+	return FetchProfileMetadata(bev, friscoProfile)
+}
 
+// FetchProfileMetadata fetches metadata from the given Frisco profile rather
+// than the one the menu linked.
+func FetchProfileMetadata(bev model.Beverage, friscoProfile string) error {
 	profileDoc, err := Agent().GetDoc(friscoProfile)
 	if err != nil {
 		return err
@@ -54,47 +72,32 @@ var rDescIBUPrefix = regexp.MustCompile(`^\s*IBU: \d+\s*-`)
 func setFriscoMetadata(bev model.Beverage, doc *goquery.Document) {
 	desc := doc.Find("[data-role='page'] [data-role='content']").Text()
 
-	overwrite := bev.AccuracyScore() <= 2
-	if bev.AccuracyScore() < 2 {
-		bev.SetAccuracyScore(2)
-	}
+	prov := model.CreateProvenance(Source, model.SourceScore(Source))
 	bev.SetNeedSync(true)
 
-	setExtractedText := func(reg *regexp.Regexp, current string, action func(string)) {
-		if overwrite || current == "" {
-			match := reg.FindStringSubmatch(desc)
-			if match != nil && match[1] != "" {
-				action(text.Normalize(match[1]))
-			}
+	extract := func(reg *regexp.Regexp) string {
+		match := reg.FindStringSubmatch(desc)
+		if match == nil {
+			return ""
 		}
+		return text.Normalize(match[1])
 	}
 
-	setExtractedFloat64 := func(reg *regexp.Regexp, current float64, action func(float64)) {
-		fakeVal := ""
-		if current > 0.0 {
-			fakeVal = "_"
+	model.SetField(bev, model.FieldBrewer, extract(rBrewery), prov)
+	model.SetField(bev, model.FieldName, extract(rName), prov)
+	model.SetField(bev, model.FieldType, extract(rType), prov)
+	if abv, err := strconv.ParseFloat(extract(rAbv), 64); err == nil {
+		model.SetAbvField(bev, abv, prov)
+	}
+	if serving := extract(rServing); serving != "" {
+		bev.SetAttribute(ServingSizeProperty, serving)
+		if len(bev.Servings()) == 0 {
+			bev.AddServing(model.ParseServing(serving, ""))
 		}
-		setExtractedText(reg, fakeVal, func(abv string) {
-			fabv, err := strconv.ParseFloat(abv, 64)
-			if err == nil && fabv > 0.0 {
-				action(fabv)
-			}
-		})
 	}
-
-	setExtractedText(rBrewery, bev.Brewer(), bev.SetBrewer)
-	setExtractedText(rName, bev.Name(), bev.SetName)
-	setExtractedText(rType, bev.Type(), bev.SetType)
-	setExtractedFloat64(rAbv, bev.Abv(), bev.SetAbv)
-	setExtractedText(rServing,
-		bev.Attribute(ServingSizeProperty), func(serving string) {
-			bev.SetAttribute(ServingSizeProperty, serving)
-		})
-
-	setExtractedText(rIBU,
-		bev.Attribute(IBUProperty), func(ibu string) {
-			bev.SetAttribute(IBUProperty, ibu)
-		})
+	if ibu := extract(rIBU); ibu != "" {
+		bev.SetAttribute(IBUProperty, ibu)
+	}
 
 	descMatch := rDesc.FindStringSubmatch(desc)
 	if descMatch != nil {
@@ -104,8 +107,6 @@ func setFriscoMetadata(bev model.Beverage, doc *goquery.Document) {
 		exDesc = text.NormalizeMultiline(exDesc)
 		bev.SetAttribute(FriscoDescription, exDesc)
 
-		if exDesc != "" && (overwrite || bev.Description() == "") {
-			bev.SetDescription(exDesc)
-		}
+		model.SetField(bev, model.FieldDescription, exDesc, prov)
 	}
 }
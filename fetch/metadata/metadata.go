@@ -1,42 +1,327 @@
 package metadata
 
 import (
+	"fmt"
 	"log"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/bevly/bevly/fetch/metadata/beeradvocate"
-	"github.com/bevly/bevly/fetch/metadata/frisco"
-	"github.com/bevly/bevly/fetch/metadata/ratebeer"
+	"github.com/bevly/bevly/fetch/metadata/profile"
+	"github.com/bevly/bevly/fetch/metadata/untappd"
 	"github.com/bevly/bevly/model"
-	"github.com/bevly/bevly/websearch/bing"
+	"github.com/bevly/bevly/style"
+	"github.com/bevly/bevly/websearch"
 )
 
-// FetchMetadata fetches metadata for a beverage, from whatever
-// sources we deem suitable. Minimal metadata: type of beverage,
-// rating.
+// Environment variables configuring metadata fetches per deployment:
 //
-// The beverage object will be modified in-place. If the fetch fails,
-// a suitable error object will be returned.
-func FetchMetadata(beverage model.Beverage) (err error) {
-	log.Printf("FetchMetadata: %s", beverage)
+// BEVLY_METADATA_SOURCES names the enabled sources in fetch order, such as
+// "RateBeer,Frisco,BA". All registered sources are enabled, in registration
+// order, if it is unset.
+//
+// BEVLY_METADATA_SEARCH names the search backend sources use ("bing",
+// "google" or "duckduckgo"), optionally followed by per-source choices, such
+// as "bing,BA=duckduckgo".
+//
+// BEVLY_METADATA_SCORES sets the accuracy scores of sources whose values
+// should win over others' more or less often than their defaults, such as
+// "Untappd=11,BA=8". See model.SetField.
+const (
+	SourcesEnv = "BEVLY_METADATA_SOURCES"
+	SearchEnv  = "BEVLY_METADATA_SEARCH"
+	ScoresEnv  = "BEVLY_METADATA_SCORES"
+)
 
-	beverage.SetSyncTime(time.Now())
+// Config says which sources to fetch metadata from, in what order, and with
+// which search backends.
+type Config struct {
+	Sources []string
+	// Search names the search backend of each source that doesn't use
+	// DefaultSearch.
+	Search        map[string]string
+	DefaultSearch string
+	// Scores sets the accuracy score of each source that doesn't keep its
+	// default.
+	Scores map[string]int
+}
 
-	search := bing.DefaultSearch()
-	err = ratebeer.FetchMetadata(beverage, search)
-	if err != nil {
-		log.Printf("FetchMetadata(%s): ratebeer fetch error: %s\n",
-			beverage, err)
+// DefaultConfig fetches from every registered source with Bing.
+func DefaultConfig() Config {
+	return Config{
+		Sources:       append([]string{}, DefaultSourceOrder...),
+		Search:        map[string]string{},
+		DefaultSearch: DefaultSearchName,
+		Scores:        map[string]int{},
 	}
+}
+
+// ConfigFromEnv reads the configuration from SourcesEnv, SearchEnv and
+// ScoresEnv.
+func ConfigFromEnv() Config {
+	return ParseConfig(os.Getenv(SourcesEnv), os.Getenv(SearchEnv), os.Getenv(ScoresEnv))
+}
 
-	if frisco.IsFrisco(beverage) {
-		err = frisco.FetchMetadata(beverage)
+// ParseConfig parses configuration in the form of SourcesEnv, SearchEnv and
+// ScoresEnv; empty strings keep the defaults. Scores that aren't integers
+// are logged and ignored.
+func ParseConfig(sources, search, scores string) Config {
+	config := DefaultConfig()
+	if sources = strings.TrimSpace(sources); sources != "" {
+		config.Sources = []string{}
+		for _, name := range strings.Split(sources, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				config.Sources = append(config.Sources, name)
+			}
+		}
+	}
+	for _, choice := range strings.Split(search, ",") {
+		choice = strings.TrimSpace(choice)
+		if choice == "" {
+			continue
+		}
+		if eq := strings.Index(choice, "="); eq >= 0 {
+			config.Search[strings.TrimSpace(choice[:eq])] = strings.TrimSpace(choice[eq+1:])
+		} else {
+			config.DefaultSearch = choice
+		}
+	}
+	for _, setting := range strings.Split(scores, ",") {
+		setting = strings.TrimSpace(setting)
+		if setting == "" {
+			continue
+		}
+		eq := strings.Index(setting, "=")
+		if eq < 0 {
+			log.Printf("ParseConfig: ignoring score %q without a source\n", setting)
+			continue
+		}
+		score, err := strconv.Atoi(strings.TrimSpace(setting[eq+1:]))
 		if err != nil {
-			log.Printf("FetchMetadata(%s): frisco fetch error: %s\n",
-				beverage, err)
+			log.Printf("ParseConfig: ignoring score %q: %s\n", setting, err)
+			continue
+		}
+		config.Scores[strings.TrimSpace(setting[:eq])] = score
+	}
+	return config
+}
+
+// SearchName names the search backend a source uses.
+func (c Config) SearchName(source string) string {
+	if name := c.Search[source]; name != "" {
+		return name
+	}
+	return c.DefaultSearch
+}
+
+// SourceResult reports how fetching from one source went. A source that
+// was not fetched from says why in Skipped.
+type SourceResult struct {
+	Source   string
+	Search   string
+	Skipped  string
+	Err      error
+	Duration time.Duration
+}
+
+func (r SourceResult) String() string {
+	switch {
+	case r.Skipped != "":
+		return fmt.Sprintf("%s: skipped (%s)", r.Source, r.Skipped)
+	case r.Err != nil:
+		return fmt.Sprintf("%s: failed in %s: %s", r.Source, r.Duration, r.Err)
+	}
+	return fmt.Sprintf("%s: fetched in %s", r.Source, r.Duration)
+}
+
+// Report lists the result of each configured source, in fetch order.
+type Report struct {
+	Results []SourceResult
+}
+
+// Errors lists the errors of the sources that failed.
+func (r *Report) Errors() []error {
+	errors := []error{}
+	for _, result := range r.Results {
+		if result.Err != nil {
+			errors = append(errors, &SourceError{Source: result.Source, Err: result.Err})
 		}
 	}
+	return errors
+}
+
+// Err combines the errors of the sources that failed, or is nil if none
+// did.
+func (r *Report) Err() error {
+	errors := r.Errors()
+	switch len(errors) {
+	case 0:
+		return nil
+	case 1:
+		return errors[0]
+	}
+	messages := make([]string, len(errors))
+	for i, err := range errors {
+		messages[i] = err.Error()
+	}
+	return fmt.Errorf("%d sources failed: %s", len(errors), strings.Join(messages, "; "))
+}
+
+// SourceError is an error fetching from a named source.
+type SourceError struct {
+	Source string
+	Err    error
+}
+
+func (e *SourceError) Error() string {
+	return e.Source + ": " + e.Err.Error()
+}
+
+// Fetcher fetches metadata from the sources its Config enables.
+type Fetcher struct {
+	Config   Config
+	searches map[string]websearch.Search
+}
+
+// NewFetcher creates a fetcher, setting the accuracy scores config
+// configures. Sources record the scores in field provenance wherever they
+// fetch from, so the scores apply to the whole process.
+func NewFetcher(config Config) *Fetcher {
+	for name, score := range config.Scores {
+		if Source(name) == nil {
+			log.Printf("NewFetcher: score set for unknown source %s\n", name)
+		}
+		model.SetSourceScore(name, score)
+	}
+	return &Fetcher{Config: config, searches: map[string]websearch.Search{}}
+}
+
+// DefaultFetcher is configured from the environment; see ConfigFromEnv.
+func DefaultFetcher() *Fetcher {
+	return NewFetcher(ConfigFromEnv())
+}
+
+// lazySearch creates its search backend on first use, since backends such as
+// Bing need configuration that sources that never search shouldn't demand.
+// Searches with a backend that isn't configured fail with a
+// websearch.UnavailableError.
+type lazySearch struct {
+	fetcher *Fetcher
+	name    string
+}
+
+func (s *lazySearch) Search(terms string) ([]websearch.Result, error) {
+	search, err := s.fetcher.backend(s.name)
+	if err != nil {
+		return nil, err
+	}
+	return search.Search(terms)
+}
+
+func (s *lazySearch) SearchURL(terms string) string {
+	search, err := s.fetcher.backend(s.name)
+	if err != nil {
+		return ""
+	}
+	return search.SearchURL(terms)
+}
+
+func (f *Fetcher) search(name string) websearch.Search {
+	return &lazySearch{fetcher: f, name: name}
+}
+
+func (f *Fetcher) backend(name string) (websearch.Search, error) {
+	if search := f.searches[name]; search != nil {
+		return search, nil
+	}
+	newSearch := searchRegistry[name]
+	if newSearch == nil {
+		log.Printf("Fetcher: unknown search backend %s, using %s\n",
+			name, DefaultSearchName)
+		newSearch = searchRegistry[DefaultSearchName]
+	}
+	search, err := newSearch()
+	if err != nil {
+		log.Printf("Fetcher: %s\n", err)
+		return nil, err
+	}
+	f.searches[name] = search
+	return search, nil
+}
+
+// Fetch fetches metadata for a beverage from each enabled source that
+// applies to it, in order, modifying the beverage in place. Once a
+// ConclusiveSource has fetched conclusive metadata, the remaining sources
+// are skipped unless profiles are pinned for them.
+//
+// override, which may be nil, is staff curation: sources it blocks are
+// skipped, profiles it pins are fetched instead of searched for, and
+// profiles it rejects are never chosen. Sources
+// whose match awaits review, that need a web search backend that isn't
+// configured, or whose API rate limit is reached are reported as skipped. Its fields are not applied here; see
+// model.ApplyOverride.
+func (f *Fetcher) Fetch(beverage model.Beverage, override *model.Override) *Report {
+	log.Printf("FetchMetadata: %s", beverage)
+	beverage.SetSyncTime(time.Now())
+
+	category := beverage.Category()
+	if category == "" {
+		category = style.DetectCategory(beverage)
+	}
+
+	report := &Report{}
+	concludedBy := ""
+	for _, name := range f.Config.Sources {
+		result := SourceResult{Source: name, Search: f.Config.SearchName(name)}
+		source := Source(name)
+		profileURL := override.ProfileURL(name)
+		switch {
+		case source == nil:
+			result.Skipped = "unknown source"
+		case override.Blocks(name):
+			result.Skipped = "blocked by override"
+		case profileURL == "" && concludedBy != "":
+			result.Skipped = "concluded by " + concludedBy
+		case profileURL == "" && !source.Applies(beverage, category):
+			result.Skipped = "not applicable to " + categoryName(category)
+		default:
+			model.RejectCandidates(beverage, name, override.RejectedURLs(name))
+			start := time.Now()
+			search := f.search(result.Search)
+			if conclusive, ok := source.(ConclusiveSource); ok {
+				var concluded bool
+				concluded, result.Err = conclusive.FetchConclusive(beverage, search, profileURL)
+				if concluded && result.Err == nil {
+					concludedBy = name
+				}
+			} else {
+				result.Err = source.Fetch(beverage, search, profileURL)
+			}
+			result.Duration = time.Since(start)
+			if result.Err == profile.ErrPendingReview {
+				result.Skipped, result.Err = "match pending review", nil
+			} else if unavailable, ok := result.Err.(*websearch.UnavailableError); ok {
+				result.Skipped, result.Err = unavailable.Error(), nil
+			} else if result.Err == untappd.ErrRateLimited {
+				result.Skipped, result.Err = result.Err.Error(), nil
+			}
+		}
+		log.Printf("FetchMetadata(%s): %s\n", beverage, result)
+		report.Results = append(report.Results, result)
+	}
+	return report
+}
+
+func categoryName(category string) string {
+	if category == "" {
+		return "unknown category"
+	}
+	return category
+}
 
-	err = beeradvocate.FetchMetadata(beverage, search)
-	return err
+// FetchMetadata fetches metadata for a beverage with the DefaultFetcher,
+// returning the errors of any sources that failed.
+func FetchMetadata(beverage model.Beverage, override *model.Override) error {
+	return DefaultFetcher().Fetch(beverage, override).Err()
 }
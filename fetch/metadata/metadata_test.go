@@ -0,0 +1,159 @@
+package metadata
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/bevly/bevly/fetch/metadata/ratebeer"
+	"github.com/bevly/bevly/fetch/metadata/untappd"
+	"github.com/bevly/bevly/model"
+	"github.com/bevly/bevly/websearch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseConfig(t *testing.T) {
+	config := ParseConfig("", "", "")
+	assert.Equal(t, DefaultSourceOrder, config.Sources)
+	assert.Equal(t, "bing", config.SearchName("BA"))
+
+	config = ParseConfig(" BA, RateBeer ", "google, BA=duckduckgo", "Untappd=11, BA=x")
+	assert.Equal(t, []string{"BA", "RateBeer"}, config.Sources)
+	assert.Equal(t, "duckduckgo", config.SearchName("BA"))
+	assert.Equal(t, "google", config.SearchName("RateBeer"))
+	assert.Equal(t, map[string]int{"Untappd": 11}, config.Scores,
+		"unparseable scores ignored")
+}
+
+type testSource struct {
+	name    string
+	fetched []string
+	err     error
+}
+
+func (s *testSource) Name() string       { return s.name }
+func (s *testSource) AccuracyScore() int { return model.SourceScore(s.name) }
+
+func (s *testSource) Applies(bev model.Beverage, category string) bool {
+	return category != model.CategoryWine
+}
+
+func (s *testSource) Fetch(bev model.Beverage, search websearch.Search, profileURL string) error {
+	s.fetched = append(s.fetched, profileURL)
+	return s.err
+}
+
+func TestFetcher(t *testing.T) {
+	good := &testSource{name: "test-good"}
+	bad := &testSource{name: "test-bad", err: errors.New("no results")}
+	RegisterSource(good)
+	RegisterSource(bad)
+	fetcher := NewFetcher(Config{Sources: []string{"test-bad", "nope", "test-good"}})
+
+	bev := model.CreateBeverage("Oliver Draft Punk")
+	report := fetcher.Fetch(bev, &model.Override{
+		ProfileURLs: map[string]string{"test-good": "http://example.com/punk"},
+	})
+	assert.False(t, bev.SyncTime().IsZero(), "sync time")
+	if assert.Equal(t, 3, len(report.Results)) {
+		assert.Equal(t, "test-bad", report.Results[0].Source, "configured order")
+		assert.Equal(t, "unknown source", report.Results[1].Skipped)
+		assert.Nil(t, report.Results[2].Err)
+	}
+	assert.Equal(t, []string{"http://example.com/punk"}, good.fetched, "pinned profile")
+	assert.Equal(t, "test-bad: no results", report.Err().Error())
+
+	wine := model.CreateBeverage("Catena")
+	wine.SetCategory(model.CategoryWine)
+	report = fetcher.Fetch(wine, &model.Override{Blocked: []string{"test-bad"}})
+	assert.Equal(t, "blocked by override", report.Results[0].Skipped)
+	assert.Equal(t, "not applicable to wine", report.Results[2].Skipped)
+	assert.Nil(t, report.Err())
+}
+
+func TestFetcherRateLimited(t *testing.T) {
+	RegisterSource(&testSource{name: "test-limited", err: untappd.ErrRateLimited})
+	report := NewFetcher(Config{Sources: []string{"test-limited"}}).
+		Fetch(model.CreateBeverage("Bear Republic Racer 5"), nil)
+	assert.Equal(t, "untappd rate limit reached", report.Results[0].Skipped)
+	assert.Nil(t, report.Err())
+}
+
+// typeTestSource sets the beverage type at the source's score.
+type typeTestSource struct {
+	testSource
+	beverageType string
+}
+
+func (s *typeTestSource) Fetch(bev model.Beverage, search websearch.Search, profileURL string) error {
+	model.SetField(bev, model.FieldType, s.beverageType,
+		model.CreateProvenance(s.name, model.SourceScore(s.name)))
+	return nil
+}
+
+func TestFetcherScores(t *testing.T) {
+	RegisterSource(&typeTestSource{testSource{name: "test-first"}, "Porter"})
+	RegisterSource(&typeTestSource{testSource{name: "test-second"}, "Baltic Porter"})
+	model.SetSourceScore("test-first", 5)
+	model.SetSourceScore("test-second", 3)
+
+	bev := model.CreateBeverage("Smuttynose Baltic Porter")
+	NewFetcher(Config{Sources: []string{"test-first", "test-second"}}).Fetch(bev, nil)
+	assert.Equal(t, "Porter", bev.Type(), "default scores")
+
+	bev = model.CreateBeverage("Smuttynose Baltic Porter")
+	config := ParseConfig("test-first,test-second", "", "test-second=6")
+	NewFetcher(config).Fetch(bev, nil)
+	assert.Equal(t, 6, Source("test-second").AccuracyScore())
+	assert.Equal(t, "Baltic Porter", bev.Type(), "configured scores")
+	assert.Equal(t, 6, bev.Provenance(model.FieldType).Score)
+}
+
+type conclusiveTestSource struct {
+	testSource
+}
+
+func (s *conclusiveTestSource) FetchConclusive(bev model.Beverage, search websearch.Search, profileURL string) (bool, error) {
+	return true, s.Fetch(bev, search, profileURL)
+}
+
+func TestFetcherConclusive(t *testing.T) {
+	catalog := &conclusiveTestSource{testSource{name: "test-catalog"}}
+	web := &testSource{name: "test-web"}
+	pinned := &testSource{name: "test-pinned"}
+	RegisterSource(catalog)
+	RegisterSource(web)
+	RegisterSource(pinned)
+	fetcher := NewFetcher(Config{Sources: []string{"test-catalog", "test-web", "test-pinned"}})
+
+	report := fetcher.Fetch(model.CreateBeverage("Racer 5"), &model.Override{
+		ProfileURLs: map[string]string{"test-pinned": "http://example.com/racer-5"},
+	})
+	assert.Equal(t, 1, len(catalog.fetched))
+	assert.Equal(t, "concluded by test-catalog", report.Results[1].Skipped)
+	assert.Equal(t, 0, len(web.fetched))
+	assert.Equal(t, []string{"http://example.com/racer-5"}, pinned.fetched, "pinned profiles are still fetched")
+}
+
+type noResultsSearch struct{}
+
+func (noResultsSearch) Search(terms string) ([]websearch.Result, error) {
+	return []websearch.Result{}, nil
+}
+
+func (noResultsSearch) SearchURL(terms string) string { return "" }
+
+func TestFetcherSearchUnavailable(t *testing.T) {
+	defer func(site websearch.Search) { ratebeer.Site = site }(ratebeer.Site)
+	ratebeer.Site = noResultsSearch{}
+	defer os.Setenv("BING_API_KEY", os.Getenv("BING_API_KEY"))
+	os.Unsetenv("BING_API_KEY")
+
+	fetcher := NewFetcher(ParseConfig(ratebeer.Source, "bing", ""))
+	report := fetcher.Fetch(model.CreateBeverage("Racer 5"), nil)
+	if assert.Equal(t, 1, len(report.Results)) {
+		assert.Equal(t, "web search unavailable: bing: BING_API_KEY is not set",
+			report.Results[0].Skipped)
+	}
+	assert.Nil(t, report.Err())
+}
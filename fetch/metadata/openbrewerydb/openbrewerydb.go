@@ -0,0 +1,188 @@
+// Package openbrewerydb enriches breweries with their address, website and
+// kind from an Open Brewery DB-compatible JSON API, or from a dump of its
+// dataset for offline use.
+package openbrewerydb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/bevly/bevly/httpagent"
+	"github.com/bevly/bevly/model"
+	"github.com/bevly/bevly/text"
+)
+
+// Source names Open Brewery DB in brewery details.
+const Source = "OpenBreweryDB"
+
+const DefaultBaseURL = "https://api.openbrewerydb.org/v1"
+
+// DatasetEnv names a JSON dump of the dataset to use instead of the API;
+// BaseURLEnv points at a compatible API.
+const (
+	DatasetEnv = "OPENBREWERYDB_DATASET"
+	BaseURLEnv = "OPENBREWERYDB_API_URL"
+)
+
+// Brewery is a brewery record, as the API and the dataset dumps have it.
+// Coordinates are strings, and null where unknown.
+type Brewery struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	BreweryType string `json:"brewery_type"`
+	City        string `json:"city"`
+	State       string `json:"state"`
+	Province    string `json:"state_province"`
+	Country     string `json:"country"`
+	Website     string `json:"website_url"`
+	Latitude    string `json:"latitude"`
+	Longitude   string `json:"longitude"`
+}
+
+// Details converts the record to brewery details fetched now.
+func (b *Brewery) Details() model.BreweryDetails {
+	state := b.State
+	if state == "" {
+		state = b.Province
+	}
+	latitude, _ := strconv.ParseFloat(b.Latitude, 64)
+	longitude, _ := strconv.ParseFloat(b.Longitude, 64)
+	return model.BreweryDetails{
+		City:      b.City,
+		State:     state,
+		Country:   b.Country,
+		Website:   b.Website,
+		Type:      b.BreweryType,
+		Latitude:  latitude,
+		Longitude: longitude,
+		Source:    Source,
+		FetchTime: time.Now(),
+	}
+}
+
+// Lookup finds the breweries whose names match a query.
+type Lookup interface {
+	Search(name string) ([]Brewery, error)
+}
+
+// Default uses the dataset dump named by DatasetEnv if set, and the API
+// otherwise.
+func Default() (Lookup, error) {
+	if file := os.Getenv(DatasetEnv); file != "" {
+		dataset, err := LoadDataset(file)
+		if err != nil {
+			return nil, err
+		}
+		return dataset, nil
+	}
+	baseURL := os.Getenv(BaseURLEnv)
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return NewClient(baseURL), nil
+}
+
+// Client searches an Open Brewery DB-compatible API.
+type Client struct {
+	BaseURL string
+	agent   *httpagent.Agent
+}
+
+func NewClient(baseURL string) *Client {
+	return &Client{BaseURL: baseURL, agent: httpagent.New()}
+}
+
+func (c *Client) Search(name string) ([]Brewery, error) {
+	reqURL := c.BaseURL + "/breweries/search?" +
+		url.Values{"query": {name}, "per_page": {"10"}}.Encode()
+	log.Printf("openbrewerydb: GET %s\n", reqURL)
+	res, err := c.agent.Get(reqURL)
+	if res != nil && res.Body != nil {
+		defer res.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode >= 300 || res.StatusCode < 200 {
+		return nil, fmt.Errorf("openbrewerydb http err:%d", res.StatusCode)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	var breweries []Brewery
+	if err = json.Unmarshal(body, &breweries); err != nil {
+		return nil, fmt.Errorf("malformed openbrewerydb response: %s", err)
+	}
+	return breweries, nil
+}
+
+// Dataset searches a dump of the dataset in memory.
+type Dataset struct {
+	byKey map[string][]Brewery
+}
+
+// LoadDataset reads a JSON dump: an array of brewery records.
+func LoadDataset(file string) (*Dataset, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var breweries []Brewery
+	if err = json.Unmarshal(content, &breweries); err != nil {
+		return nil, fmt.Errorf("malformed openbrewerydb dataset %s: %s", file, err)
+	}
+	dataset := &Dataset{byKey: map[string][]Brewery{}}
+	for _, brewery := range breweries {
+		key := text.BreweryKey(brewery.Name)
+		dataset.byKey[key] = append(dataset.byKey[key], brewery)
+	}
+	log.Printf("openbrewerydb: loaded %d breweries from %s\n", len(breweries), file)
+	return dataset, nil
+}
+
+// Search finds the breweries whose names share name's text.BreweryKey.
+func (d *Dataset) Search(name string) ([]Brewery, error) {
+	return d.byKey[text.BreweryKey(name)], nil
+}
+
+// Enrich looks a brewery up by its name and then its aliases, and sets its
+// details from the first record whose name matches it. The lookup is
+// recorded in the details' FetchTime even if nothing matches, so breweries
+// aren't looked up on every sync. Returns true if the brewery was found.
+func Enrich(brewery model.Brewery, lookup Lookup) (bool, error) {
+	names := append([]string{brewery.Name()}, brewery.Aliases()...)
+	for _, name := range names {
+		records, err := lookup.Search(name)
+		if err != nil {
+			return false, err
+		}
+		for _, record := range records {
+			if brewery.Matches(record.Name) {
+				log.Printf("openbrewerydb(%s): found %s in %s, %s\n",
+					brewery, record.Name, record.City, record.State)
+				setDetails(brewery, record.Details())
+				return true, nil
+			}
+		}
+	}
+	log.Printf("openbrewerydb(%s): not found\n", brewery)
+	setDetails(brewery, model.BreweryDetails{FetchTime: time.Now()})
+	return false, nil
+}
+
+func setDetails(brewery model.Brewery, details model.BreweryDetails) {
+	brewery.SetDetails(details)
+	if brewery.Location() == "" {
+		brewery.SetLocation(details.Place())
+	}
+	if details.Website != "" {
+		brewery.SetLink("website", details.Website)
+	}
+}
@@ -0,0 +1,78 @@
+package openbrewerydb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bevly/bevly/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// openBreweryDBStub stands in for the API, finding Oliver Brewing and
+// nothing else.
+func openBreweryDBStub() *httptest.Server {
+	return httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/breweries/search" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			if r.URL.Query().Get("query") == "Oliver Brewing" {
+				w.Write([]byte(`[{"id": "oliver", "name": "Oliver Brewing Co",
+					"brewery_type": "micro", "city": "Baltimore",
+					"state_province": "Maryland", "country": "United States",
+					"website_url": "http://www.oliverbrewingco.com",
+					"latitude": "39.3082", "longitude": "-76.5629"}]`))
+				return
+			}
+			w.Write([]byte(`[]`))
+		}))
+}
+
+func TestEnrichFromAPI(t *testing.T) {
+	ts := openBreweryDBStub()
+	defer ts.Close()
+
+	brewery := model.CreateBrewery("Oliver Brewing")
+	found, err := Enrich(brewery, NewClient(ts.URL))
+	assert.Nil(t, err)
+	assert.True(t, found)
+	details := brewery.Details()
+	assert.Equal(t, "Baltimore", details.City)
+	assert.Equal(t, "Maryland", details.State)
+	assert.Equal(t, "micro", details.Type)
+	assert.Equal(t, 39.3082, details.Latitude)
+	assert.Equal(t, Source, details.Source)
+	assert.Equal(t, "Baltimore, Maryland", brewery.Location())
+	assert.Equal(t, "http://www.oliverbrewingco.com", brewery.Links()["website"])
+
+	brewery = model.CreateBrewery("Nonesuch Ales")
+	found, err = Enrich(brewery, NewClient(ts.URL))
+	assert.Nil(t, err)
+	assert.False(t, found)
+	assert.True(t, brewery.Details().Fetched())
+	assert.Equal(t, "", brewery.Details().Source)
+}
+
+func TestEnrichFromDataset(t *testing.T) {
+	dataset, err := LoadDataset("openbrewerydb_test.json")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	brewery := model.CreateBrewery("Dogfish Head")
+	found, err := Enrich(brewery, dataset)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "Milton", brewery.Details().City)
+	assert.Equal(t, "regional", brewery.Details().Type)
+
+	brewery = model.CreateBrewery("Brew Dog")
+	brewery.AddAlias("BrewDog")
+	found, err = Enrich(brewery, dataset)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "Aberdeenshire", brewery.Details().State)
+	assert.False(t, brewery.Details().HasCoordinates())
+}
@@ -0,0 +1,196 @@
+// Package profile chooses a beverage's profile on a metadata source from
+// search results: every result that links to a profile is scored, and the
+// best is chosen, rather than the first that will do.
+package profile
+
+import (
+	"errors"
+	"log"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bevly/bevly/model"
+	"github.com/bevly/bevly/text"
+	"github.com/bevly/bevly/websearch"
+)
+
+// MaxCandidates is how many candidates of each source are kept on a
+// beverage for review.
+const MaxCandidates = 10
+
+// AbvTolerance is the difference in ABV at which a search result's ABV
+// stops agreeing with the beverage's at all. A result whose ABV disagrees
+// loses up to AbvWeight of its confidence.
+const (
+	AbvTolerance = 1.5
+	AbvWeight    = 0.3
+)
+
+// ReviewConfidenceEnv names the environment variable setting
+// ReviewConfidence, such as "0.8"; "0" disables review.
+const ReviewConfidenceEnv = "BEVLY_REVIEW_CONFIDENCE"
+
+const DefaultReviewConfidence = 0.7
+
+// ReviewConfidence is the confidence below which the best candidate is
+// parked for staff review instead of chosen, though good enough to choose.
+var ReviewConfidence = reviewConfidenceFromEnv()
+
+// ErrPendingReview is returned by Choose when the best candidate awaits
+// review.
+var ErrPendingReview = errors.New("match pending review")
+
+func reviewConfidenceFromEnv() float64 {
+	value := os.Getenv(ReviewConfidenceEnv)
+	if value == "" {
+		return DefaultReviewConfidence
+	}
+	confidence, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Bad %s %q, using %v: %s\n", ReviewConfidenceEnv, value,
+			DefaultReviewConfidence, err)
+		return DefaultReviewConfidence
+	}
+	return confidence
+}
+
+// Site describes how to read one source's search results.
+type Site struct {
+	Source string
+	// IsProfile reports whether a result URL is a beverage profile.
+	IsProfile func(url string) bool
+	// Title cleans a result's title, such as by removing the site's name.
+	Title func(title string) string
+}
+
+// Rank scores the results that link to profiles as candidates for bev's
+// profile, best first. Results linking to a profile already among
+// candidates are skipped, so results of several searches can be pooled, as
+// are those linking to profiles staff rejected for bev.
+func (s *Site) Rank(bev model.Beverage, candidates []model.ProfileCandidate, results []websearch.Result) []model.ProfileCandidate {
+	seen := map[string]bool{}
+	for _, candidate := range candidates {
+		seen[profileKey(candidate.URL)] = true
+	}
+	for _, rejected := range model.RejectedCandidates(bev, s.Source) {
+		seen[profileKey(rejected.URL)] = true
+	}
+	for _, result := range results {
+		if !s.IsProfile(result.URL) || seen[profileKey(result.URL)] {
+			continue
+		}
+		seen[profileKey(result.URL)] = true
+		title := result.Text
+		if s.Title != nil {
+			title = s.Title(title)
+		}
+		candidates = append(candidates, score(bev, s.Source, result, text.Normalize(title)))
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Confidence > candidates[j].Confidence
+	})
+	return candidates
+}
+
+// profileKey identifies a profile whether it's linked by http or https.
+func profileKey(url string) string {
+	url = strings.TrimPrefix(strings.TrimPrefix(url, "https://"), "http://")
+	return strings.TrimSuffix(url, "/")
+}
+
+func score(bev model.Beverage, source string, result websearch.Result, title string) model.ProfileCandidate {
+	match := text.MatchName(bev.SearchName(), bev.Brewer(), title)
+	candidate := model.ProfileCandidate{
+		Source:      source,
+		URL:         result.URL,
+		Title:       title,
+		Confidence:  match.Confidence,
+		NameScore:   match.NameScore,
+		BrewerScore: match.BrewerScore,
+		AbvScore:    -1,
+		Abv:         ResultAbv(result),
+		Explanation: match.String(),
+	}
+	if candidate.Abv > 0 && bev.Abv() > 0 {
+		difference := math.Abs(candidate.Abv - bev.Abv())
+		candidate.AbvScore = math.Max(0, 1-difference/AbvTolerance)
+		candidate.Confidence *= 1 - AbvWeight + AbvWeight*candidate.AbvScore
+		candidate.Explanation += "; abv " + strconv.FormatFloat(candidate.Abv, 'f', -1, 64) +
+			"% vs " + strconv.FormatFloat(bev.Abv(), 'f', -1, 64) + "%"
+	}
+	return candidate
+}
+
+var (
+	rLabeledAbv = regexp.MustCompile(`(?i)\babv\W{0,3}(\d{1,2}(?:\.\d+)?)\s*%|(\d{1,2}(?:\.\d+)?)\s*%\s*abv\b`)
+	rResultAbv  = regexp.MustCompile(`(\d{1,2}(?:\.\d+)?)\s*%`)
+)
+
+// MaxResultAbv is the highest ABV read from a percentage a search result
+// doesn't label as ABV. Higher percentages are usually something else, such
+// as the share of raters who liked the beer.
+const MaxResultAbv = 20.0
+
+// ResultAbv finds the ABV a search result lists, or 0 if it lists none: the
+// percentage labeled ABV, or failing that, the first plausible one.
+func ResultAbv(result websearch.Result) float64 {
+	resultText := result.Text + " " + result.Snippet
+	if match := rLabeledAbv.FindStringSubmatch(resultText); match != nil {
+		abv, _ := strconv.ParseFloat(match[1]+match[2], 64)
+		return abv
+	}
+	for _, match := range rResultAbv.FindAllStringSubmatch(resultText, -1) {
+		if abv, _ := strconv.ParseFloat(match[1], 64); abv > 0 && abv <= MaxResultAbv {
+			return abv
+		}
+	}
+	return 0
+}
+
+// Best returns the best candidate if its confidence reaches threshold.
+// candidates must be ranked.
+func Best(candidates []model.ProfileCandidate, threshold float64) (model.ProfileCandidate, bool) {
+	if len(candidates) == 0 || candidates[0].Confidence < threshold {
+		return model.ProfileCandidate{}, false
+	}
+	return candidates[0], true
+}
+
+// Choose picks the best candidate, if its confidence reaches threshold, and
+// keeps the top candidates on the beverage, marking the chosen one. Returns
+// the chosen profile's URL, or "" if none is good enough. A best candidate
+// below ReviewConfidence is marked Pending instead of chosen, and Choose
+// returns ErrPendingReview. Candidates staff rejected are kept too.
+func (s *Site) Choose(bev model.Beverage, candidates []model.ProfileCandidate, threshold float64) (string, error) {
+	if len(candidates) > MaxCandidates {
+		candidates = candidates[:MaxCandidates]
+	}
+	chosen := ""
+	var err error
+	if best, ok := Best(candidates, threshold); ok {
+		if best.Confidence < ReviewConfidence {
+			candidates[0].Pending = true
+			err = ErrPendingReview
+		} else {
+			candidates[0].Chosen = true
+			chosen = best.URL
+		}
+	}
+	for i, candidate := range candidates {
+		verdict := "passing over"
+		if candidate.Chosen {
+			verdict = "choosing"
+		} else if candidate.Pending {
+			verdict = "parking for review"
+		}
+		log.Printf("%s(%s): %d) %s %s: %s\n", s.Source, bev, i+1, verdict,
+			candidate.URL, candidate.Explanation)
+	}
+	kept := append([]model.ProfileCandidate{}, candidates...)
+	bev.SetProfileCandidates(s.Source, append(kept, model.RejectedCandidates(bev, s.Source)...))
+	return chosen, err
+}
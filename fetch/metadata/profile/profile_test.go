@@ -0,0 +1,107 @@
+package profile
+
+import (
+	"testing"
+
+	"github.com/bevly/bevly/model"
+	"github.com/bevly/bevly/websearch"
+	"github.com/stretchr/testify/assert"
+)
+
+var testSite = &Site{
+	Source: "test",
+	IsProfile: func(url string) bool {
+		return url != "http://example.com/news"
+	},
+}
+
+func TestRankPrefersBestMatch(t *testing.T) {
+	bev := model.CreateBeverage("Bear Republic Racer 5")
+	results := []websearch.Result{
+		{URL: "http://example.com/news", Text: "Bear Republic Racer 5 news"},
+		{URL: "http://example.com/racer-x", Text: "Bear Republic Racer X"},
+		{URL: "http://example.com/racer-5", Text: "Bear Republic Racer 5 IPA"},
+		{URL: "https://example.com/racer-5/", Text: "Racer 5 IPA"},
+	}
+	candidates := testSite.Rank(bev, nil, results)
+	if assert.Equal(t, 2, len(candidates), "profiles only, without duplicates") {
+		assert.Equal(t, "http://example.com/racer-5", candidates[0].URL)
+		assert.Equal(t, "test", candidates[0].Source)
+		assert.Equal(t, -1.0, candidates[0].AbvScore)
+	}
+
+	chosen, err := testSite.Choose(bev, candidates, 0.5)
+	assert.Nil(t, err)
+	assert.Equal(t, "http://example.com/racer-5", chosen)
+	kept, ok := model.ChosenCandidate(bev, "test")
+	assert.True(t, ok)
+	assert.Equal(t, chosen, kept.URL)
+	assert.Equal(t, 2, len(bev.ProfileCandidates()), "losing candidates are kept")
+}
+
+func TestRankAbvAgreement(t *testing.T) {
+	bev := model.CreateBeverage("Bear Republic Racer 5")
+	bev.SetAbv(7.5)
+	results := []websearch.Result{
+		{URL: "http://example.com/clone", Text: "Racer 5", Snippet: "Homebrew | 5.2%"},
+		{URL: "http://example.com/racer-5", Text: "Racer 5", Snippet: "American IPA | 7.50%"},
+	}
+	candidates := testSite.Rank(bev, nil, results)
+	if assert.Equal(t, 2, len(candidates)) {
+		assert.Equal(t, "http://example.com/racer-5", candidates[0].URL)
+		assert.Equal(t, 1.0, candidates[0].AbvScore)
+		assert.Equal(t, 0.0, candidates[1].AbvScore)
+		assert.Equal(t, 5.2, candidates[1].Abv)
+	}
+
+	chosen, err := testSite.Choose(bev, candidates, 0.99)
+	assert.Nil(t, err)
+	assert.Equal(t, "", chosen, "no candidate is good enough")
+	_, ok := model.ChosenCandidate(bev, "test")
+	assert.False(t, ok)
+	assert.Equal(t, 2, len(bev.ProfileCandidates()))
+}
+
+func TestChooseParksForReview(t *testing.T) {
+	defer func(confidence float64) { ReviewConfidence = confidence }(ReviewConfidence)
+	ReviewConfidence = 0.99
+	bev := model.CreateBeverage("Bear Republic Racer 5")
+	candidates := testSite.Rank(bev, nil, []websearch.Result{
+		{URL: "http://example.com/racer-5", Text: "Racer 5 IPA"},
+	})
+	chosen, err := testSite.Choose(bev, candidates, 0.5)
+	assert.Equal(t, ErrPendingReview, err)
+	assert.Equal(t, "", chosen)
+	_, ok := model.ChosenCandidate(bev, "test")
+	assert.False(t, ok, "nothing is chosen before review")
+	pending, ok := model.PendingCandidate(bev, "test")
+	assert.True(t, ok)
+	assert.Equal(t, "http://example.com/racer-5", pending.URL)
+}
+
+func TestRankSkipsRejected(t *testing.T) {
+	bev := model.CreateBeverage("Bear Republic Racer 5")
+	model.RejectCandidates(bev, "test", []string{"http://example.com/racer-5"})
+	candidates := testSite.Rank(bev, nil, []websearch.Result{
+		{URL: "https://example.com/racer-5/", Text: "Bear Republic Racer 5 IPA"},
+		{URL: "http://example.com/racer-x", Text: "Bear Republic Racer X"},
+	})
+	if assert.Equal(t, 1, len(candidates), "rejected profile skipped") {
+		assert.Equal(t, "http://example.com/racer-x", candidates[0].URL)
+	}
+
+	testSite.Choose(bev, candidates, 0.5)
+	rejected := model.RejectedCandidates(bev, "test")
+	if assert.Equal(t, 1, len(rejected), "rejected candidate kept") {
+		assert.Equal(t, "http://example.com/racer-5", rejected[0].URL)
+		assert.False(t, rejected[0].Chosen)
+	}
+}
+
+func TestResultAbv(t *testing.T) {
+	assert.Equal(t, 7.5, ResultAbv(websearch.Result{Snippet: "American IPA | 7.50%"}))
+	assert.Equal(t, 7.5, ResultAbv(websearch.Result{Snippet: "97% of raters liked it. ABV: 7.5%"}), "labeled ABV")
+	assert.Equal(t, 6.2, ResultAbv(websearch.Result{Snippet: "Rated 4.1 by 96% of raters; 6.2% ABV"}))
+	assert.Equal(t, 5.0, ResultAbv(websearch.Result{Snippet: "97% of raters, 5% alcohol"}), "implausible ABV skipped")
+	assert.Equal(t, 0.0, ResultAbv(websearch.Result{Snippet: "97% of raters liked it"}))
+}
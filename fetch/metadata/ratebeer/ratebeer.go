@@ -2,6 +2,7 @@ package ratebeer
 
 import (
 	"github.com/PuerkitoBio/goquery"
+	"github.com/bevly/bevly/fetch/metadata/profile"
 	"github.com/bevly/bevly/httpagent"
 	"github.com/bevly/bevly/model"
 	"github.com/bevly/bevly/text"
@@ -22,10 +23,27 @@ var Throttle = throttle.Default("RateBeer")
 
 const RateBeerAccuracyScore = 9
 
+// Source names RateBeer in field provenance.
+const Source = "RateBeer"
+
+// ProfileURLProperty is the attribute holding the beverage's RateBeer
+// profile, whichever source its link came from.
+const ProfileURLProperty = "rbLink"
+
+func init() {
+	model.SetSourceScore(Source, RateBeerAccuracyScore)
+	model.RegisterSourceData(Source, model.SourceData{
+		Ratings: []string{"rb", "rb:style"},
+		// "img" is the image attribute RateBeer set before images had a
+		// field of their own.
+		Attributes: []string{ProfileURLProperty, "rbDescription", "rbImg", "img"},
+	})
+}
+
 func FetchMetadata(bev model.Beverage, search websearch.Search) (err error) {
 	log.Printf("FetchMetadata(%s): Searching for Ratebeer profile", bev)
 
-	profileURL, err := FindProfile(bev.SearchName(), search)
+	profileURL, err := FindProfile(bev, search)
 	if err != nil {
 		log.Printf("FetchMetadata(%s): Ratebeer profile error: %s",
 			bev, err)
@@ -86,52 +104,40 @@ func FetchRatebeerMetadata(bev model.Beverage, profileURL string) error {
 func fetchRatebeerProfile(bev model.Beverage, profileURL string, doc *goquery.Document) (err error) {
 	bev.SetNeedSync(true)
 
-	overwrite := bev.AccuracyScore() < RateBeerAccuracyScore
-	if overwrite {
-		bev.SetAccuracyScore(RateBeerAccuracyScore)
-	}
-
-	set := func(old, new string, setter func(string)) {
-		if new != "" && (overwrite || old == "") {
-			setter(new)
-		}
-	}
+	prov := model.CreateProvenance(Source, model.SourceScore(Source))
 
 	selFirstText := func(selector string) string {
 		return doc.Find(selector).First().Text()
 	}
 
-	bev.SetAttribute("rbLink", profileURL)
+	bev.SetAttribute(ProfileURLProperty, profileURL)
 	log.Printf("rb(%s): link=%s\n", bev, profileURL)
-	set(bev.Link(), profileURL, bev.SetLink)
+	model.SetField(bev, model.FieldLink, profileURL, prov)
 
 	name := selFirstText(".user-header h1")
 	brewer := selFirstText("big a")
-	set(bev.Name(), name, bev.SetName)
-	set(bev.Brewer(), brewer, bev.SetBrewer)
+	model.SetField(bev, model.FieldName, name, prov)
+	model.SetField(bev, model.FieldBrewer, brewer, prov)
 
 	log.Printf("rb(%s): name=%s brewer=%s\n", bev, name, brewer)
 
 	addRatings(bev, doc)
 
 	abv := findAbv(doc)
-	if abv > 0.0 && (overwrite || bev.Abv() == 0.0) {
+	if model.SetAbvField(bev, abv, prov) {
 		log.Printf("rb(%s): abv=%.1f%%\n", bev, abv)
-		bev.SetAbv(abv)
 	}
 
 	desc := findDescription(doc)
 	log.Printf("rb(%s): description: %s\n", bev, desc)
-	set(bev.Description(), desc, bev.SetDescription)
+	model.SetField(bev, model.FieldDescription, desc, prov)
 	bev.SetAttribute("rbDescription", desc)
 
 	image := findImageURL(doc)
 	if image != "" {
 		log.Printf("rb(%s): image: %s\n", bev, image)
 		bev.SetAttribute("rbImg", image)
-		set(bev.Attribute("img"), image, func(img string) {
-			bev.SetAttribute("img", img)
-		})
+		model.SetField(bev, model.FieldImage, image, prov)
 	}
 
 	return nil
@@ -232,32 +238,40 @@ func addV1Ratings(bev model.Beverage, ratings *goquery.Selection) {
 	}
 }
 
-func FindProfile(name string, search websearch.Search) (string, error) {
-	terms := "ratebeer " + name
-	results, err := search.Search(terms)
-	if err != nil {
-		return "", err
+// ProfileMatchConfidence is the confidence a candidate profile must reach
+// to be chosen; see profile.Site.Rank.
+const ProfileMatchConfidence = 0.5
+
+var profileSite = &profile.Site{
+	Source:    Source,
+	IsProfile: IsRatebeerProfile,
+	Title:     stripRatebeerName,
+}
+
+// FindProfile finds the beverage's RateBeer profile, searching RateBeer
+// itself first, and the web if that fails or finds no good candidate. The
+// best candidate is chosen, and the candidates considered are kept on the
+// beverage. Returns "" if no candidate is good enough, and
+// profile.ErrPendingReview if the best awaits review.
+func FindProfile(bev model.Beverage, search websearch.Search) (string, error) {
+	name := bev.SearchName()
+	candidates := []model.ProfileCandidate{}
+	if Site != nil {
+		results, err := Site.Search(name)
+		if err != nil {
+			log.Printf("rb(%s): site search failed, searching the web: %s\n", bev, err)
+		}
+		candidates = profileSite.Rank(bev, candidates, results)
 	}
-	log.Printf("ratebeer(%s): %d results\n", terms, len(results))
-	if len(results) > 0 {
-		for _, result := range results {
-			log.Printf("rb(%s): considering %s (%s)\n",
-				search, result.Text, result.URL)
-			if IsRatebeerProfile(result.URL) {
-				title := stripRatebeerName(result.Text)
-				confidence := text.NameMatchConfidence(title, name)
-				if confidence < 0.13 {
-					log.Printf("rb(%s): rejecting %s (confidence: %.2f%%)\n",
-						terms, title, confidence*100)
-					continue
-				}
-				log.Printf("rb(%s): accepting %s (confidence: %.2f%%)\n",
-					terms, title, confidence*100)
-				return result.URL, nil
-			}
+	if _, ok := profile.Best(candidates, ProfileMatchConfidence); !ok {
+		results, err := search.Search("ratebeer " + name)
+		if err != nil {
+			return "", err
 		}
+		log.Printf("rb(%s): %d web results\n", bev, len(results))
+		candidates = profileSite.Rank(bev, candidates, results)
 	}
-	return "", nil
+	return profileSite.Choose(bev, candidates, ProfileMatchConfidence)
 }
 
 var rRatebeerURL = regexp.MustCompile(`ratebeer.com/beer/.*?/\d+/?$`)
@@ -7,6 +7,7 @@ import (
 
 	"github.com/bevly/bevly/httpfilestub"
 	"github.com/bevly/bevly/model"
+	"github.com/bevly/bevly/websearch"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -56,6 +57,8 @@ func TestRatebeerFetch(t *testing.T) {
 	assert.Equal(t, 9.5, bev.Abv(), "abv")
 	assert.Equal(t, "Enchanting and enlightening, this golden, frothy ale boasts an intriguing herbal aroma, warming alcohol esters on the tongue and light, but firm body to finish. Exotic spices add subtle notes to both the aroma and flavor. Strong, sensual and satisfying.", bev.Description(), "desc")
 	assert.Equal(t, "http://res.cloudinary.com/ratebeer/image/upload/w_120,c_limit,q_85,d_no%20image.jpg/beer_630.jpg", bev.Attribute("rbImg"), "image")
+	assert.Equal(t, bev.Attribute("rbImg"), bev.Image(), "image field")
+	assert.Equal(t, Source, bev.Provenance(model.FieldImage).Source, "image provenance")
 }
 
 func beverage(beerName, file string) (model.Beverage, error) {
@@ -94,3 +97,49 @@ func TestRatebeerEncoding(t *testing.T) {
 	assert.Nil(t, err, "no error from stub")
 	assert.Equal(t, "Pumking is an ode to Púca, a creature of Celtic folklore, who is both feared and respected by those who believe in it. Púca is said to waylay travelers throughout the night, tossing them on its back, and providing them the ride of their lives, from which they return forever changed. Brewed in the spirit of All Hallows Eve, a time of the year when spirits can make contact with the physical world and when magic is most potent. Pour Pumking into a goblet and allow it’s alluring spirit to overflow. As spicy aromas present themselves, let it’s deep copper color entrance you as your journey into this mystical brew has just begun. As the first drops touch your tongue a magical spell will bewitch your taste buds making it difficult to escape the Pumking. 2007 - Brown Label 7.9% ABV w/text & logo on bottlecap 2008 - Brown Label 9.0% ABV w/text & logo on bottlecap 2009 - Orange Label 9.0% ABV w/text & logo on bottlecap 2010 - Orange Label 9.0% ABV w/logo only on bottlecap 2011 - Orange wood grain background label 8.6% ABV and Southern Tier logotype in two lines. 2012 - Same as 2011 label 8.6% ABV, silver/black/white bottlecap. 2013 - Same as 2012 label and bottle cap 8.6% ABV, date stamp in green text. 2014 - New label design w/orange/white/green, 8.6% ABV", bev.Description(), "description")
 }
+
+type stubSearch struct {
+	results []websearch.Result
+	terms   []string
+}
+
+func (s *stubSearch) Search(terms string) ([]websearch.Result, error) {
+	s.terms = append(s.terms, terms)
+	return s.results, nil
+}
+
+func (s *stubSearch) SearchURL(terms string) string {
+	return "http://search.example.com/?q=" + terms
+}
+
+func TestFindProfileSiteSearch(t *testing.T) {
+	ts := httpfilestub.Server("ratebeer_search_test.html")
+	defer ts.Close()
+	defer func(site websearch.Search) { Site = site }(Site)
+	Site = SiteSearchWithURL(ts.URL)
+
+	web := &stubSearch{}
+	bev := model.CreateBeverage("Victory Golden Monkey")
+	profile, err := FindProfile(bev, web)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://www.ratebeer.com/beer/victory-golden-monkey/8947/", profile)
+	assert.Equal(t, 0, len(web.terms), "no web search")
+}
+
+func TestFindProfileWebFallback(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+	defer func(site websearch.Search) { Site = site }(Site)
+	Site = SiteSearchWithURL(ts.URL)
+
+	web := &stubSearch{results: []websearch.Result{{
+		URL:  "http://www.ratebeer.com/beer/abita-maple-pecan/137411/",
+		Text: "Abita Pecan Harvest Ale - RateBeer",
+	}}}
+	profile, err := FindProfile(model.CreateBeverageBrewer("Abita Pecan Harvest", "Abita"), web)
+	assert.Nil(t, err)
+	assert.Equal(t, "http://www.ratebeer.com/beer/abita-maple-pecan/137411/", profile)
+	assert.Equal(t, []string{"ratebeer Abita Pecan Harvest"}, web.terms)
+}
@@ -0,0 +1,80 @@
+package ratebeer
+
+import (
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/bevly/bevly/httpagent"
+	"github.com/bevly/bevly/text"
+	"github.com/bevly/bevly/throttle"
+	"github.com/bevly/bevly/websearch"
+)
+
+const SiteSearchBaseURL = "https://www.ratebeer.com/findbeer.asp"
+
+// ProfileBaseURL resolves the relative profile links of search results.
+const ProfileBaseURL = "https://www.ratebeer.com/"
+
+// SiteSearch searches RateBeer's own beer search, returning the profiles
+// its results page links to. It shares Throttle with profile fetches.
+type SiteSearch struct {
+	BaseURL  string
+	Throttle *throttle.Throttle
+}
+
+func DefaultSiteSearch() websearch.Search {
+	return &SiteSearch{BaseURL: SiteSearchBaseURL, Throttle: Throttle}
+}
+
+func SiteSearchWithURL(baseURL string) websearch.Search {
+	return &SiteSearch{BaseURL: baseURL}
+}
+
+// Site is searched for profiles before falling back to web search. It may
+// be set to nil to only use web search.
+var Site = DefaultSiteSearch()
+
+func (s *SiteSearch) Search(terms string) ([]websearch.Result, error) {
+	if s.Throttle != nil {
+		s.Throttle.DelayInvocation()
+	}
+	searchURL := s.SearchURL(terms)
+	log.Printf("RateBeer search: %s / %s\n", terms, searchURL)
+	doc, err := httpagent.Win1252Agent().GetDoc(searchURL)
+	if err != nil {
+		return nil, err
+	}
+	return siteSearchResults(doc), nil
+}
+
+func (s *SiteSearch) SearchURL(terms string) string {
+	return s.BaseURL + "?" + url.Values{"beername": {terms}}.Encode()
+}
+
+// siteSearchResults lists the distinct beer profiles a results page links
+// to, in page order, with the link text as the result text and the rest of
+// its table row as the snippet.
+func siteSearchResults(doc *goquery.Document) []websearch.Result {
+	base, _ := url.Parse(ProfileBaseURL)
+	results := []websearch.Result{}
+	seen := map[string]bool{}
+	doc.Find("a[href]").Each(func(_ int, a *goquery.Selection) {
+		href, _ := a.Attr("href")
+		link, err := base.Parse(strings.TrimSpace(href))
+		if err != nil {
+			return
+		}
+		link.RawQuery, link.Fragment = "", ""
+		profileURL := link.String()
+		title := text.Normalize(a.Text())
+		if title == "" || seen[profileURL] || !IsRatebeerProfile(profileURL) {
+			return
+		}
+		seen[profileURL] = true
+		snippet := text.Normalize(strings.Replace(a.Closest("tr").Text(), a.Text(), "", 1))
+		results = append(results, websearch.Result{URL: profileURL, Text: title, Snippet: snippet})
+	})
+	return results
+}
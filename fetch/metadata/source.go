@@ -0,0 +1,189 @@
+package metadata
+
+import (
+	"github.com/bevly/bevly/fetch/metadata/beeradvocate"
+	"github.com/bevly/bevly/fetch/metadata/catalog"
+	"github.com/bevly/bevly/fetch/metadata/frisco"
+	"github.com/bevly/bevly/fetch/metadata/ratebeer"
+	"github.com/bevly/bevly/fetch/metadata/untappd"
+	"github.com/bevly/bevly/model"
+	"github.com/bevly/bevly/websearch"
+	"github.com/bevly/bevly/websearch/bing"
+	"github.com/bevly/bevly/websearch/duckduckgo"
+	"github.com/bevly/bevly/websearch/google"
+)
+
+// MetadataSource is somewhere beverage metadata can be fetched from.
+type MetadataSource interface {
+	// Name identifies the source in configuration, overrides and field
+	// provenance.
+	Name() string
+	// AccuracyScore is how far the source's metadata is trusted; see
+	// model.SetField. Sources register default scores, which ScoresEnv
+	// overrides.
+	AccuracyScore() int
+	// Applies reports whether the source is worth searching for a beverage
+	// of the given category ("" if unknown).
+	Applies(bev model.Beverage, category string) bool
+	// Fetch fetches metadata into bev from the profile at profileURL, or
+	// from the profile found with search if profileURL is empty.
+	Fetch(bev model.Beverage, search websearch.Search, profileURL string) error
+}
+
+// ConclusiveSource is a source that can tell when it has fetched all a
+// beverage's metadata with confidence, so that sources after it needn't
+// search for the beverage. Sources fetching pinned profiles still do.
+type ConclusiveSource interface {
+	MetadataSource
+	// FetchConclusive fetches as Fetch does, reporting whether the
+	// metadata fetched is conclusive.
+	FetchConclusive(bev model.Beverage, search websearch.Search, profileURL string) (bool, error)
+}
+
+var metadataSourceRegistry = map[string]MetadataSource{}
+
+// DefaultSourceOrder lists the registered sources in the order they are
+// fetched unless configured otherwise.
+var DefaultSourceOrder = []string{}
+
+// RegisterSource makes a source available to fetch from, after those
+// already registered.
+func RegisterSource(source MetadataSource) {
+	if _, exists := metadataSourceRegistry[source.Name()]; !exists {
+		DefaultSourceOrder = append(DefaultSourceOrder, source.Name())
+	}
+	metadataSourceRegistry[source.Name()] = source
+}
+
+// Source returns the registered source with the given name, or nil.
+func Source(name string) MetadataSource {
+	return metadataSourceRegistry[name]
+}
+
+// searchRegistry creates search backends, failing with a
+// websearch.UnavailableError for those that aren't configured.
+var searchRegistry = map[string]func() (websearch.Search, error){
+	"bing":       bing.ConfiguredSearch,
+	"google":     alwaysAvailable(google.DefaultSearch),
+	"duckduckgo": alwaysAvailable(duckduckgo.DefaultSearch),
+}
+
+func alwaysAvailable(newSearch func() websearch.Search) func() (websearch.Search, error) {
+	return func() (websearch.Search, error) { return newSearch(), nil }
+}
+
+const DefaultSearchName = "bing"
+
+func init() {
+	RegisterSource(&catalogSource{})
+	RegisterSource(&ratebeerSource{})
+	RegisterSource(&friscoSource{})
+	RegisterSource(&beerAdvocateSource{})
+	RegisterSource(&untappdSource{untappd.DefaultClient()})
+}
+
+// catalogSource looks beverages up in the local catalog, if one is
+// configured, and is conclusive for those it knows well, sparing the
+// throttled web sources.
+type catalogSource struct{}
+
+func (*catalogSource) Name() string       { return catalog.Source }
+func (*catalogSource) AccuracyScore() int { return model.SourceScore(catalog.Source) }
+
+func (*catalogSource) Applies(bev model.Beverage, category string) bool {
+	return catalog.Default() != nil && listsBeerCiderMead(category)
+}
+
+// The catalog has no profiles to pin.
+func (s *catalogSource) Fetch(bev model.Beverage, search websearch.Search, profileURL string) error {
+	_, err := s.FetchConclusive(bev, search, profileURL)
+	return err
+}
+
+func (*catalogSource) FetchConclusive(bev model.Beverage, search websearch.Search, profileURL string) (bool, error) {
+	if catalog.Default() == nil {
+		return false, catalog.ErrNoMatch
+	}
+	return catalog.Default().FetchMetadata(bev)
+}
+
+type ratebeerSource struct{}
+
+func (*ratebeerSource) Name() string       { return ratebeer.Source }
+func (*ratebeerSource) AccuracyScore() int { return model.SourceScore(ratebeer.Source) }
+
+func (*ratebeerSource) Applies(bev model.Beverage, category string) bool {
+	return listsBeerCiderMead(category)
+}
+
+// listsBeerCiderMead is true for the categories sources such as RateBeer list:
+// ciders and meads as well as beer, but nothing else. Beverages of unknown
+// category are searched as beer.
+func listsBeerCiderMead(category string) bool {
+	switch category {
+	case "", model.CategoryBeer, model.CategoryCider, model.CategoryMead:
+		return true
+	}
+	return false
+}
+
+func (*ratebeerSource) Fetch(bev model.Beverage, search websearch.Search, profileURL string) error {
+	if profileURL != "" {
+		return ratebeer.FetchRatebeerMetadata(bev, profileURL)
+	}
+	return ratebeer.FetchMetadata(bev, search)
+}
+
+// friscoSource fetches the profiles Frisco's menu links to; it never
+// searches.
+type friscoSource struct{}
+
+func (*friscoSource) Name() string       { return frisco.Source }
+func (*friscoSource) AccuracyScore() int { return model.SourceScore(frisco.Source) }
+
+func (*friscoSource) Applies(bev model.Beverage, category string) bool {
+	return frisco.IsFrisco(bev)
+}
+
+func (*friscoSource) Fetch(bev model.Beverage, search websearch.Search, profileURL string) error {
+	if profileURL != "" {
+		return frisco.FetchProfileMetadata(bev, profileURL)
+	}
+	return frisco.FetchMetadata(bev)
+}
+
+type beerAdvocateSource struct{}
+
+func (*beerAdvocateSource) Name() string       { return beeradvocate.Source }
+func (*beerAdvocateSource) AccuracyScore() int { return model.SourceScore(beeradvocate.Source) }
+
+// BeerAdvocate only accepts beer profiles.
+func (*beerAdvocateSource) Applies(bev model.Beverage, category string) bool {
+	return category == "" || category == model.CategoryBeer
+}
+
+func (*beerAdvocateSource) Fetch(bev model.Beverage, search websearch.Search, profileURL string) error {
+	if profileURL != "" {
+		return beeradvocate.FetchProfileMetadata(bev, profileURL)
+	}
+	return beeradvocate.FetchMetadata(bev, search)
+}
+
+// untappdSource applies only where credentials are configured.
+type untappdSource struct {
+	client *untappd.Client
+}
+
+func (*untappdSource) Name() string       { return untappd.Source }
+func (*untappdSource) AccuracyScore() int { return model.SourceScore(untappd.Source) }
+
+func (s *untappdSource) Applies(bev model.Beverage, category string) bool {
+	return s.client.Configured() && listsBeerCiderMead(category)
+}
+
+func (s *untappdSource) Fetch(bev model.Beverage, search websearch.Search, profileURL string) error {
+	if profileURL != "" {
+		return s.client.FetchProfileMetadata(bev, profileURL)
+	}
+	return s.client.FetchMetadata(bev)
+}
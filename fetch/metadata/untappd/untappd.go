@@ -0,0 +1,348 @@
+// Package untappd fetches beverage metadata from an Untappd-compatible JSON
+// API: a beer search, then the details of the best match.
+package untappd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/bevly/bevly/fetch/metadata/profile"
+	"github.com/bevly/bevly/httpagent"
+	"github.com/bevly/bevly/model"
+	"github.com/bevly/bevly/text"
+	"github.com/bevly/bevly/throttle"
+	"github.com/bevly/bevly/websearch"
+)
+
+var (
+	ErrNoCredentials = errors.New("no untappd credentials")
+	ErrNoResults     = errors.New("no results for beverage")
+	ErrBadProfileURL = errors.New("not an untappd beer URL")
+	// ErrRateLimited is returned without calling the API once it has
+	// answered 429 Too Many Requests, until it allows calls again.
+	ErrRateLimited = errors.New("untappd rate limit reached")
+)
+
+// Source names Untappd in field provenance. Its structured data is trusted
+// above Frisco's, but below the sites bevly has always relied on.
+const Source = "Untappd"
+const AccuracyScore = 8
+
+// Attributes recording metadata without a Beverage field of its own.
+const (
+	IBUProperty         = "untappdIBU"
+	RatingCountProperty = "untappdRatingCount"
+	ProfileURLProperty  = "untappdLink"
+)
+
+// RatingSource names Untappd ratings, which are scaled from 0-5 to a
+// percentage.
+const RatingSource = "untappd"
+
+func init() {
+	model.SetSourceScore(Source, AccuracyScore)
+	model.RegisterSourceData(Source, model.SourceData{
+		Ratings:    []string{RatingSource},
+		Attributes: []string{IBUProperty, RatingCountProperty, ProfileURLProperty},
+	})
+}
+
+const DefaultBaseURL = "https://api.untappd.com/v4"
+const ProfileBaseURL = "https://untappd.com"
+
+// Credentials and, for compatible APIs, the base URL are read from the
+// environment.
+const (
+	ClientIDEnv     = "UNTAPPD_CLIENT_ID"
+	ClientSecretEnv = "UNTAPPD_CLIENT_SECRET"
+	BaseURLEnv      = "UNTAPPD_API_URL"
+	// RateLimitEnv is the number of calls an hour the API allows the
+	// client, if not DefaultRateLimit.
+	RateLimitEnv = "UNTAPPD_RATE_LIMIT"
+)
+
+// MatchConfidence is the confidence a search result must reach to be
+// chosen; see profile.Site.Rank.
+const MatchConfidence = 0.5
+
+// The public API allows 100 calls an hour. Each beverage fetched costs two:
+// a search, then the beer's details.
+const DefaultRateLimit = 100
+
+// RateLimitBackoff is how long calls are held off after a 429 response
+// that doesn't say when to retry: the length of the API's quota window.
+const RateLimitBackoff = time.Hour
+
+// Throttle spaces calls evenly to stay within the rate limit.
+var Throttle = rateLimitThrottle(rateLimit())
+
+func rateLimit() int {
+	limit := DefaultRateLimit
+	if setting := os.Getenv(RateLimitEnv); setting != "" {
+		parsed, err := strconv.Atoi(setting)
+		if err != nil || parsed <= 0 {
+			log.Printf("untappd: ignoring %s=%s, using %d calls an hour\n",
+				RateLimitEnv, setting, limit)
+		} else {
+			limit = parsed
+		}
+	}
+	return limit
+}
+
+// rateLimitThrottle spaces calls at least an hour / callsPerHour apart.
+func rateLimitThrottle(callsPerHour int) *throttle.Throttle {
+	minMillis := int64(time.Hour/time.Millisecond) / int64(callsPerHour)
+	return throttle.New("Untappd", minMillis, minMillis+minMillis/10)
+}
+
+type Client struct {
+	BaseURL      string
+	ClientID     string
+	ClientSecret string
+	Throttle     *throttle.Throttle
+	agent        *httpagent.Agent
+	// limitedUntil is when the API allows calls again after a 429.
+	limitedUntil time.Time
+}
+
+// DefaultClient is configured from the environment.
+func DefaultClient() *Client {
+	baseURL := os.Getenv(BaseURLEnv)
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	client := NewClient(baseURL, os.Getenv(ClientIDEnv), os.Getenv(ClientSecretEnv))
+	client.Throttle = Throttle
+	return client
+}
+
+func NewClient(baseURL, clientID, clientSecret string) *Client {
+	return &Client{
+		BaseURL:      baseURL,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		agent:        httpagent.New(),
+	}
+}
+
+// Configured reports whether the client has credentials.
+func (c *Client) Configured() bool {
+	return c.ClientID != "" && c.ClientSecret != ""
+}
+
+// Beer is the metadata the API has for a beer.
+type Beer struct {
+	ID          int     `json:"bid"`
+	Name        string  `json:"beer_name"`
+	Label       string  `json:"beer_label"`
+	Abv         float64 `json:"beer_abv"`
+	IBU         float64 `json:"beer_ibu"`
+	Description string  `json:"beer_description"`
+	Style       string  `json:"beer_style"`
+	Slug        string  `json:"beer_slug"`
+	Rating      float64 `json:"rating_score"`
+	RatingCount int     `json:"rating_count"`
+	Brewery     Brewery `json:"brewery"`
+}
+
+type Brewery struct {
+	Name string `json:"brewery_name"`
+}
+
+// ProfileURL is the beer's page on Untappd.
+func (b *Beer) ProfileURL() string {
+	return fmt.Sprintf("%s/b/%s/%d", ProfileBaseURL, b.Slug, b.ID)
+}
+
+// PercentageRating scales the 0-5 rating to a percentage.
+func (b *Beer) PercentageRating() int {
+	return int(math.Floor(b.Rating*20 + 0.5))
+}
+
+type searchResponse struct {
+	Response struct {
+		Beers struct {
+			Items []struct {
+				Beer    Beer    `json:"beer"`
+				Brewery Brewery `json:"brewery"`
+			} `json:"items"`
+		} `json:"beers"`
+	} `json:"response"`
+}
+
+type infoResponse struct {
+	Response struct {
+		Beer Beer `json:"beer"`
+	} `json:"response"`
+}
+
+// Search lists the beers matching name, best match first. Search results
+// carry the basics only; see Beer for details.
+func (c *Client) Search(name string) ([]Beer, error) {
+	var res searchResponse
+	err := c.get("/search/beer", url.Values{"q": {name}}, &res)
+	if err != nil {
+		return nil, err
+	}
+	beers := make([]Beer, 0, len(res.Response.Beers.Items))
+	for _, item := range res.Response.Beers.Items {
+		beer := item.Beer
+		beer.Brewery = item.Brewery
+		beers = append(beers, beer)
+	}
+	return beers, nil
+}
+
+// Beer fetches the details of a beer.
+func (c *Client) Beer(id int) (*Beer, error) {
+	var res infoResponse
+	err := c.get("/beer/info/"+strconv.Itoa(id), url.Values{"compact": {"true"}}, &res)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Response.Beer, nil
+}
+
+func (c *Client) get(path string, query url.Values, result interface{}) error {
+	if !c.Configured() {
+		return ErrNoCredentials
+	}
+	if time.Now().Before(c.limitedUntil) {
+		return ErrRateLimited
+	}
+	if c.Throttle != nil {
+		c.Throttle.DelayInvocation()
+	}
+	query.Set("client_id", c.ClientID)
+	query.Set("client_secret", c.ClientSecret)
+	reqURL := c.BaseURL + path + "?" + query.Encode()
+
+	log.Printf("untappd: GET %s%s\n", c.BaseURL, path)
+	res, err := c.agent.Get(reqURL)
+	if res != nil && res.Body != nil {
+		defer res.Body.Close()
+	}
+	if res != nil && res.StatusCode == http.StatusTooManyRequests {
+		c.limitedUntil = time.Now().Add(retryAfter(res))
+		log.Printf("untappd: rate limited until %s\n", c.limitedUntil.Format(time.Kitchen))
+		return ErrRateLimited
+	}
+	if err != nil {
+		return err
+	}
+	if res.StatusCode >= 300 || res.StatusCode < 200 {
+		return fmt.Errorf("untappd http err:%d", res.StatusCode)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("malformed untappd response: %s", err)
+	}
+	return nil
+}
+
+// retryAfter reads how long to wait from a 429 response's Retry-After
+// header, in seconds, defaulting to RateLimitBackoff.
+func retryAfter(res *http.Response) time.Duration {
+	if seconds, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return RateLimitBackoff
+}
+
+// profileSite ranks search results as RateBeer and BA rank web results, so
+// that matches below profile.ReviewConfidence await staff review.
+var profileSite = &profile.Site{
+	Source:    Source,
+	IsProfile: rProfileID.MatchString,
+}
+
+// FetchMetadata searches for a beverage and fetches the details of the
+// closest match. The candidates considered are kept on the beverage.
+// Returns profile.ErrPendingReview if the best candidate awaits review.
+func (c *Client) FetchMetadata(bev model.Beverage) error {
+	beers, err := c.Search(bev.SearchName())
+	if err != nil {
+		log.Printf("untappd(%s): search error: %s\n", bev, err)
+		return err
+	}
+	results := make([]websearch.Result, 0, len(beers))
+	for _, beer := range beers {
+		result := websearch.Result{URL: beer.ProfileURL(), Text: beer.Brewery.Name + " " + beer.Name}
+		if beer.Abv > 0 {
+			result.Snippet = strconv.FormatFloat(beer.Abv, 'f', -1, 64) + "% ABV"
+		}
+		results = append(results, result)
+	}
+	candidates := profileSite.Rank(bev, []model.ProfileCandidate{}, results)
+	profileURL, err := profileSite.Choose(bev, candidates, MatchConfidence)
+	if err != nil {
+		bev.SetNeedSync(true)
+		return err
+	}
+	if profileURL == "" {
+		// No results, but we don't want to resync repeatedly anyway:
+		bev.SetNeedSync(true)
+		return ErrNoResults
+	}
+	return c.FetchProfileMetadata(bev, profileURL)
+}
+
+var rProfileID = regexp.MustCompile(`untappd\.com/(?:b/[^/]+|beer)/(\d+)`)
+
+// FetchProfileMetadata fetches the beer whose Untappd page is profileURL.
+func (c *Client) FetchProfileMetadata(bev model.Beverage, profileURL string) error {
+	match := rProfileID.FindStringSubmatch(profileURL)
+	if match == nil {
+		return ErrBadProfileURL
+	}
+	id, _ := strconv.Atoi(match[1])
+	return c.fetchBeer(bev, id)
+}
+
+func (c *Client) fetchBeer(bev model.Beverage, id int) error {
+	beer, err := c.Beer(id)
+	if err != nil {
+		log.Printf("untappd(%s): beer %d error: %s\n", bev, id, err)
+		return err
+	}
+	setBeerMetadata(bev, beer)
+	return nil
+}
+
+func setBeerMetadata(bev model.Beverage, beer *Beer) {
+	prov := model.CreateProvenance(Source, model.SourceScore(Source))
+	bev.SetNeedSync(true)
+
+	profileURL := beer.ProfileURL()
+	bev.SetAttribute(ProfileURLProperty, profileURL)
+	model.SetField(bev, model.FieldLink, profileURL, prov)
+	model.SetField(bev, model.FieldName, text.Normalize(beer.Name), prov)
+	model.SetField(bev, model.FieldBrewer, text.Normalize(beer.Brewery.Name), prov)
+	model.SetField(bev, model.FieldType, text.Normalize(beer.Style), prov)
+	model.SetField(bev, model.FieldDescription, text.NormalizeMultiline(beer.Description), prov)
+	model.SetField(bev, model.FieldImage, beer.Label, prov)
+	model.SetAbvField(bev, beer.Abv, prov)
+	if beer.IBU > 0 {
+		bev.SetAttribute(IBUProperty, strconv.FormatFloat(beer.IBU, 'f', -1, 64))
+	}
+	if beer.RatingCount > 0 {
+		bev.AddRating(model.CreateRating(RatingSource, beer.PercentageRating()))
+		bev.SetAttribute(RatingCountProperty, strconv.Itoa(beer.RatingCount))
+	}
+	log.Printf("untappd(%s): name=%s brewer=%s abv=%.1f%%\n",
+		bev, beer.Name, beer.Brewery.Name, beer.Abv)
+}
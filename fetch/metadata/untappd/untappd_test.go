@@ -0,0 +1,116 @@
+package untappd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bevly/bevly/fetch/metadata/profile"
+	"github.com/bevly/bevly/httpfilestub"
+	"github.com/bevly/bevly/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// untappdStub stands in for the API, answering a search for Racer 5 and
+// the details of beer 4473, and rejecting requests without credentials.
+func untappdStub() *httptest.Server {
+	return httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			if q.Get("client_id") != "id" || q.Get("client_secret") != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			switch {
+			case r.URL.Path == "/search/beer" && q.Get("q") == "Bear Republic Racer 5":
+				httpfilestub.WriteFile(w, "untappd_search_test.json")
+			case r.URL.Path == "/search/beer":
+				w.Write([]byte(`{"response": {"beers": {"count": 0, "items": []}}}`))
+			case r.URL.Path == "/beer/info/4473":
+				httpfilestub.WriteFile(w, "untappd_info_test.json")
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+}
+
+func TestFetchMetadata(t *testing.T) {
+	ts := untappdStub()
+	defer ts.Close()
+	client := NewClient(ts.URL, "id", "secret")
+
+	bev := model.CreateBeverage("Bear Republic Racer 5")
+	assert.Nil(t, client.FetchMetadata(bev))
+	assert.Equal(t, "Racer 5 IPA", bev.Name())
+	assert.Equal(t, "Bear Republic Brewing Co.", bev.Brewer())
+	assert.Equal(t, "IPA - American", bev.Type())
+	assert.Equal(t, 7.5, bev.Abv())
+	assert.Equal(t, "75", bev.Attribute(IBUProperty))
+	assert.Equal(t, "61822", bev.Attribute(RatingCountProperty))
+	assert.Equal(t, "https://labels.untappd.com/site/beer_logos/beer-RacerFive.jpg", bev.Image())
+	assert.Equal(t, "https://untappd.com/b/bear-republic-brewing-co-racer-5-ipa/4473", bev.Link())
+	assert.Contains(t, bev.Description(), "American pale and crystal malts, and heavily hopped")
+	if assert.Equal(t, 1, len(bev.Ratings())) {
+		assert.Equal(t, RatingSource, bev.Ratings()[0].Source())
+		assert.Equal(t, 78, bev.Ratings()[0].PercentageRating())
+	}
+	assert.Equal(t, Source, bev.Provenance(model.FieldAbv).Source)
+	assert.Equal(t, AccuracyScore, bev.AccuracyScore())
+}
+
+func TestFetchMetadataPendingReview(t *testing.T) {
+	ts := untappdStub()
+	defer ts.Close()
+	client := NewClient(ts.URL, "id", "secret")
+	defer func(confidence float64) { profile.ReviewConfidence = confidence }(profile.ReviewConfidence)
+	profile.ReviewConfidence = 0.99
+
+	bev := model.CreateBeverage("Bear Republic Racer 5")
+	assert.Equal(t, profile.ErrPendingReview, client.FetchMetadata(bev))
+	assert.Equal(t, "", bev.Name(), "nothing fetched")
+	pending, ok := model.PendingCandidate(bev, Source)
+	if assert.True(t, ok) {
+		assert.Equal(t, "https://untappd.com/b/bear-republic-brewing-co-racer-5-ipa/4473", pending.URL)
+		assert.Equal(t, 7.5, pending.Abv)
+	}
+}
+
+func TestFetchProfileMetadata(t *testing.T) {
+	ts := untappdStub()
+	defer ts.Close()
+	client := NewClient(ts.URL, "id", "secret")
+
+	bev := model.CreateBeverage("Racer Five")
+	assert.Nil(t, client.FetchProfileMetadata(bev, "https://untappd.com/b/bear-republic-brewing-co-racer-5-ipa/4473"))
+	assert.Equal(t, "Racer 5 IPA", bev.Name())
+	assert.Equal(t, ErrBadProfileURL, client.FetchProfileMetadata(bev, "https://untappd.com/brewery/1185"))
+}
+
+func TestFetchErrors(t *testing.T) {
+	ts := untappdStub()
+	defer ts.Close()
+
+	bev := model.CreateBeverage("Weasel Ale")
+	assert.Equal(t, ErrNoResults, NewClient(ts.URL, "id", "secret").FetchMetadata(bev))
+	assert.Equal(t, ErrNoCredentials, NewClient(ts.URL, "", "").FetchMetadata(bev))
+	assert.NotNil(t, NewClient(ts.URL, "id", "wrong").FetchMetadata(bev), "unauthorized")
+}
+
+func TestRateLimit(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer ts.Close()
+	client := NewClient(ts.URL, "id", "secret")
+
+	bev := model.CreateBeverage("Bear Republic Racer 5")
+	assert.Equal(t, ErrRateLimited, client.FetchMetadata(bev))
+	assert.Equal(t, ErrRateLimited, client.FetchMetadata(bev))
+	assert.Equal(t, 1, calls, "no calls until the limit resets")
+
+	assert.Equal(t, int64(36000), rateLimitThrottle(DefaultRateLimit).MinMillis,
+		"100 calls an hour")
+}
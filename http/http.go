@@ -1,10 +1,18 @@
 package http
 
 import (
+	"encoding/json"
+	"math"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/bevly/bevly/model"
 	"github.com/bevly/bevly/repository"
+	"github.com/bevly/bevly/style"
+	bevsync "github.com/bevly/bevly/sync"
 	"github.com/go-martini/martini"
 	"github.com/martini-contrib/gzip"
 	"github.com/martini-contrib/render"
@@ -15,13 +23,314 @@ func BeverageServerBlocking(repo repository.Repository) {
 	m.Use(gzip.All())
 	m.Use(render.Renderer())
 
-	m.Get("/:source/drink/", func(par martini.Params, r render.Render, res http.ResponseWriter) {
+	m.Get("/:source/drink/", func(par martini.Params, r render.Render, req *http.Request, res http.ResponseWriter) {
 		NoCache(res)
-		r.JSON(http.StatusOK, bevListJsonModel(repo.ProviderIDBeverages(par["source"])))
+		locality := newBreweryLocality(repo, repo.ProviderByID(par["source"]))
+		beverages := filterBeverages(repo.ProviderIDBeverages(par["source"]), req, locality)
+		r.JSON(http.StatusOK, bevListJsonModel(beverages, locality))
 	})
+	m.Get("/:source/menu/", func(par martini.Params, r render.Render, req *http.Request, res http.ResponseWriter) {
+		NoCache(res)
+		provider := repo.ProviderByID(par["source"])
+		if provider == nil {
+			r.JSON(http.StatusNotFound, map[string]interface{}{"error": "no such menu"})
+			return
+		}
+		locality := newBreweryLocality(repo, provider)
+		beverages := filterBeverages(repo.ProviderBeverages(provider), req, locality)
+		r.JSON(http.StatusOK, menuJsonModel(provider, beverages, locality))
+	})
+	m.Get("/brewery/:id", func(par martini.Params, r render.Render, res http.ResponseWriter) {
+		NoCache(res)
+		brewery := repo.BreweryByID(par["id"])
+		if brewery == nil {
+			r.JSON(http.StatusNotFound, map[string]interface{}{"error": "no such brewery"})
+			return
+		}
+		r.JSON(http.StatusOK, breweryJsonModel(repo, brewery))
+	})
+	m.Group("/admin", func(admin martini.Router) {
+		adminRoutes(admin, repo)
+	}, requireAdmin)
 	m.Run()
 }
 
+// AdminTokenEnv names the environment variable holding the token admin
+// requests must present as "Authorization: Bearer <token>". The admin API is
+// disabled when it is unset.
+const AdminTokenEnv = "BEVLY_ADMIN_TOKEN"
+
+func requireAdmin(r render.Render, req *http.Request, res http.ResponseWriter) {
+	NoCache(res)
+	token := os.Getenv(AdminTokenEnv)
+	if token == "" {
+		r.JSON(http.StatusForbidden, map[string]interface{}{"error": "admin API disabled"})
+		return
+	}
+	if req.Header.Get("Authorization") != "Bearer "+token {
+		r.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "bad admin token"})
+	}
+}
+
+func adminRoutes(admin martini.Router, repo repository.Repository) {
+	admin.Get("/overrides", func(r render.Render) {
+		overrides := []interface{}{}
+		for _, override := range repo.Overrides() {
+			overrides = append(overrides, overrideJsonModel(override))
+		}
+		r.JSON(http.StatusOK, map[string]interface{}{"overrides": overrides})
+	})
+	admin.Get("/drink/:id/override", func(par martini.Params, r render.Render) {
+		override := repo.Override(par["id"])
+		if override == nil {
+			r.JSON(http.StatusNotFound, map[string]interface{}{"error": "no override"})
+			return
+		}
+		r.JSON(http.StatusOK, overrideJsonModel(override))
+	})
+	admin.Put("/drink/:id/override", func(par martini.Params, r render.Render, req *http.Request) {
+		override, err := decodeOverride(par["id"], req)
+		if err == nil {
+			err = override.Validate()
+		}
+		if err != nil {
+			r.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+			return
+		}
+		if renderRepoError(r, repo.SaveOverride(override)) {
+			return
+		}
+		r.JSON(http.StatusOK, map[string]interface{}{
+			"override": overrideJsonModel(override),
+			"drink":    bevJsonModel(repo.BeverageByID(override.BeverageID)),
+		})
+	})
+	admin.Delete("/drink/:id/override", func(par martini.Params, r render.Render) {
+		repo.DeleteOverride(par["id"])
+		r.JSON(http.StatusOK, map[string]interface{}{})
+	})
+	admin.Get("/drink/:id/duplicates", func(par martini.Params, r render.Render) {
+		r.JSON(http.StatusOK, bevListJsonModel(repo.BeverageDuplicates(par["id"]), nil))
+	})
+	// Lists pairs of drinks that may be the same drink, but were not merged
+	// automatically since their breweries are not both known.
+	admin.Get("/duplicates", func(r render.Render) {
+		suggestions := []interface{}{}
+		for _, suggestion := range bevsync.SuggestDuplicates(repo) {
+			suggestions = append(suggestions, map[string]interface{}{
+				"drink":      bevJsonModel(suggestion.Beverage),
+				"duplicate":  bevJsonModel(suggestion.Duplicate),
+				"confidence": suggestion.Confidence,
+			})
+		}
+		r.JSON(http.StatusOK, suggestions)
+	})
+	// Merges the drink named by "duplicateId" in the request body into :id.
+	admin.Post("/drink/:id/merge", func(par martini.Params, r render.Render, req *http.Request) {
+		var body struct {
+			DuplicateID string `json:"duplicateId"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			r.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+			return
+		}
+		err := repo.MergeBeverages(par["id"], body.DuplicateID)
+		if !renderRepoError(r, err) {
+			r.JSON(http.StatusOK, bevJsonModel(repo.BeverageByID(par["id"])))
+		}
+	})
+	// Splits the duplicate :id from the drink it was merged into.
+	admin.Delete("/drink/:id/merge", func(par martini.Params, r render.Render) {
+		err := bevsync.SplitBeverage(repo, par["id"])
+		if !renderRepoError(r, err) {
+			r.JSON(http.StatusOK, map[string]interface{}{})
+		}
+	})
+	// Fetches the provider's menu, from the http(s) "url" if given, and
+	// compares it with the stored menu, saving nothing. Local files are only
+	// previewed from the command line.
+	admin.Get("/provider/:id/menu/preview", func(par martini.Params, r render.Render, req *http.Request) {
+		provider := repo.ProviderByID(par["id"])
+		if provider == nil {
+			r.JSON(http.StatusNotFound, map[string]interface{}{"error": "no such menu"})
+			return
+		}
+		if menuURL := req.URL.Query().Get("url"); menuURL != "" {
+			if !isWebURL(menuURL) {
+				r.JSON(http.StatusBadRequest, map[string]interface{}{"error": "url must be http or https"})
+				return
+			}
+			provider = model.CreateMenuProviderAt(provider, menuURL)
+		}
+		fetched, diff := bevsync.PreviewMenu(repo, provider)
+		if fetched.Err != nil {
+			r.JSON(http.StatusBadGateway, map[string]interface{}{"error": fetched.Err.Error()})
+			return
+		}
+		r.JSON(http.StatusOK, map[string]interface{}{
+			"provider": provider.ID(),
+			"url":      provider.URL(),
+			"drinks":   bevJsonList(fetched.Beverages, nil),
+			"diff":     menuDiffJsonModel(diff),
+		})
+	})
+	admin.Get("/reviews", func(r render.Render) {
+		reviews := []interface{}{}
+		for _, beverage := range repo.BeveragesPendingReview() {
+			for _, source := range model.PendingSources(beverage) {
+				reviews = append(reviews, reviewJsonModel(beverage, source))
+			}
+		}
+		r.JSON(http.StatusOK, map[string]interface{}{"reviews": reviews})
+	})
+	// Settles the review of the match from :source by the "action" in the
+	// request body: "accept" pins the pending candidate, "choose" pins the
+	// candidate whose profile is "url", and "reject" rejects the pending
+	// candidate. Sources are blocked by editing the override.
+	admin.Post("/drink/:id/review/:source", func(par martini.Params, r render.Render, req *http.Request) {
+		var body struct {
+			Action string `json:"action"`
+			URL    string `json:"url"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			r.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+			return
+		}
+		var err error
+		switch {
+		case body.Action == "accept":
+			err = bevsync.AcceptMatch(repo, par["id"], par["source"], "")
+		case body.Action == "choose" && body.URL != "":
+			err = bevsync.AcceptMatch(repo, par["id"], par["source"], body.URL)
+		case body.Action == "reject":
+			err = bevsync.RejectMatch(repo, par["id"], par["source"])
+		default:
+			r.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error": `action must be "accept", "reject" or "choose" with a url`})
+			return
+		}
+		if !renderRepoError(r, err) {
+			r.JSON(http.StatusOK, map[string]interface{}{
+				"override": overrideJsonModel(repo.Override(par["id"])),
+				"drink":    bevJsonModel(repo.BeverageByID(par["id"])),
+			})
+		}
+	})
+}
+
+// renderRepoError renders the response for a repository error, returning
+// false if there was none.
+func renderRepoError(r render.Render, err error) bool {
+	switch err {
+	case nil:
+		return false
+	case repository.ErrNoSuchBeverage, bevsync.ErrNoPendingReview:
+		r.JSON(http.StatusNotFound, map[string]interface{}{"error": err.Error()})
+	case repository.ErrSelfMerge, repository.ErrNotMerged, bevsync.ErrNotCandidate:
+		r.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	default:
+		r.JSON(http.StatusInternalServerError, map[string]interface{}{"error": err.Error()})
+	}
+	return true
+}
+
+// isWebURL reports whether rawURL is an http or https URL with a host.
+func isWebURL(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") &&
+		parsed.Host != ""
+}
+
+// decodeOverride reads an override in the form overrideJsonModel produces.
+func decodeOverride(beverageID string, req *http.Request) (*model.Override, error) {
+	var body struct {
+		Fields           map[string]string   `json:"fields"`
+		ProfileURLs      map[string]string   `json:"profileUrls"`
+		Blocked          []string            `json:"blocked"`
+		RejectedProfiles map[string][]string `json:"rejectedProfiles"`
+		NotDuplicates    []string            `json:"notDuplicates"`
+		Note             string              `json:"note"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	return &model.Override{
+		BeverageID:       beverageID,
+		Fields:           body.Fields,
+		ProfileURLs:      body.ProfileURLs,
+		Blocked:          body.Blocked,
+		RejectedProfiles: body.RejectedProfiles,
+		NotDuplicates:    body.NotDuplicates,
+		Note:             body.Note,
+	}, nil
+}
+
+func menuDiffJsonModel(diff bevsync.MenuDiff) interface{} {
+	changed := []interface{}{}
+	for _, change := range diff.Changed {
+		fields := []interface{}{}
+		for _, field := range change.Changes {
+			fields = append(fields, map[string]interface{}{
+				"field":   field.Field,
+				"stored":  field.Stored,
+				"fetched": field.Fetched,
+			})
+		}
+		changed = append(changed, map[string]interface{}{"name": change.Name, "changes": fields})
+	}
+	return map[string]interface{}{
+		"added":     bevJsonList(diff.Added, nil),
+		"removed":   bevJsonList(diff.Removed, nil),
+		"changed":   changed,
+		"unchanged": diff.Unchanged,
+	}
+}
+
+// reviewJsonModel describes a beverage's match from source pending review,
+// with the candidates it was chosen from, best first.
+func reviewJsonModel(beverage model.Beverage, source string) interface{} {
+	pending, _ := model.PendingCandidate(beverage, source)
+	candidates := []interface{}{}
+	for _, candidate := range model.SourceCandidates(beverage, source) {
+		candidates = append(candidates, candidateJsonModel(candidate))
+	}
+	return map[string]interface{}{
+		"drink":      bevJsonModel(beverage),
+		"source":     source,
+		"pending":    candidateJsonModel(pending),
+		"candidates": candidates,
+	}
+}
+
+func candidateJsonModel(candidate model.ProfileCandidate) interface{} {
+	return map[string]interface{}{
+		"url":         candidate.URL,
+		"title":       candidate.Title,
+		"confidence":  candidate.Confidence,
+		"nameScore":   candidate.NameScore,
+		"brewerScore": candidate.BrewerScore,
+		"abvScore":    candidate.AbvScore,
+		"abv":         candidate.Abv,
+		"explanation": candidate.Explanation,
+		"chosen":      candidate.Chosen,
+		"pending":     candidate.Pending,
+		"rejected":    candidate.Rejected,
+	}
+}
+
+func overrideJsonModel(override *model.Override) interface{} {
+	return map[string]interface{}{
+		"drinkId":          override.BeverageID,
+		"fields":           override.Fields,
+		"profileUrls":      override.ProfileURLs,
+		"blocked":          override.Blocked,
+		"rejectedProfiles": override.RejectedProfiles,
+		"notDuplicates":    override.NotDuplicates,
+		"note":             override.Note,
+		"updatedAt":        override.UpdatedAt,
+	}
+}
+
 func NoCache(res http.ResponseWriter) {
 	headers := res.Header()
 	headers.Set("Cache-Control", "no-cache, no-store, must-revalidate")
@@ -29,16 +338,192 @@ func NoCache(res http.ResponseWriter) {
 	headers.Set("Expires", "0")
 }
 
-func bevListJsonModel(beverages []model.Beverage) interface{} {
+// breweryLocality tells how far beverages' breweries are from the venue
+// serving them. A nil locality knows nothing.
+type breweryLocality struct {
+	provider  model.MenuProvider
+	breweries map[string]model.Brewery
+}
+
+func newBreweryLocality(repo repository.Repository, provider model.MenuProvider) *breweryLocality {
+	if provider == nil {
+		return nil
+	}
+	breweries := map[string]model.Brewery{}
+	for _, brewery := range repo.Breweries() {
+		breweries[brewery.ID()] = brewery
+	}
+	return &breweryLocality{provider: provider, breweries: breweries}
+}
+
+func (l *breweryLocality) brewery(beverage model.Beverage) model.Brewery {
+	if l == nil {
+		return nil
+	}
+	return l.breweries[beverage.BreweryID()]
+}
+
+// distanceKm returns how far the beverage's brewery is from the venue, if
+// both are located.
+func (l *breweryLocality) distanceKm(beverage model.Beverage) (float64, bool) {
+	brewery := l.brewery(beverage)
+	if brewery == nil {
+		return 0, false
+	}
+	return model.BreweryDistanceKm(l.provider, brewery)
+}
+
+func (l *breweryLocality) local(beverage model.Beverage) bool {
+	brewery := l.brewery(beverage)
+	return brewery != nil && model.IsLocal(l.provider, brewery)
+}
+
+// annotate adds the beverage's "local" badge and "breweryDistanceKm", where
+// known, to its JSON.
+func (l *breweryLocality) annotate(bevJson map[string]interface{}, beverage model.Beverage) {
+	if l == nil {
+		return
+	}
+	bevJson["local"] = l.local(beverage)
+	if distance, ok := l.distanceKm(beverage); ok {
+		bevJson["breweryDistanceKm"] = math.Floor(distance*10+0.5) / 10
+	}
+}
+
+// filterBeverages applies the request's filters: "style" keeps beverages
+// whose canonical style or style family matches, "category" keeps
+// beverages of that category, "local=true" keeps beverages from breweries
+// local to the venue, and "maxDistance" keeps beverages from breweries
+// within that many kilometres of it.
+func filterBeverages(beverages []model.Beverage, req *http.Request, locality *breweryLocality) []model.Beverage {
+	styleQuery := req.FormValue("style")
+	category := strings.ToLower(req.FormValue("category"))
+	localOnly := req.FormValue("local") == "true"
+	maxDistance, err := strconv.ParseFloat(req.FormValue("maxDistance"), 64)
+	if err != nil {
+		maxDistance = -1
+	}
+	if styleQuery == "" && category == "" && !localOnly && maxDistance < 0 {
+		return beverages
+	}
+	result := []model.Beverage{}
+	for _, beverage := range beverages {
+		if styleQuery != "" && !style.Canonical(beverage.Type()).Matches(styleQuery) {
+			continue
+		}
+		if category != "" && beverage.Category() != category {
+			continue
+		}
+		if localOnly && !locality.local(beverage) {
+			continue
+		}
+		if maxDistance >= 0 {
+			if distance, ok := locality.distanceKm(beverage); !ok || distance > maxDistance {
+				continue
+			}
+		}
+		result = append(result, beverage)
+	}
+	return result
+}
+
+func bevListJsonModel(beverages []model.Beverage, locality *breweryLocality) interface{} {
+	return map[string]interface{}{
+		"drinks": bevJsonList(beverages, locality),
+	}
+}
+
+func bevJsonList(beverages []model.Beverage, locality *breweryLocality) []interface{} {
 	bevList := make([]interface{}, len(beverages))
 	for i, beverage := range beverages {
-		bevList[i] = bevJsonModel(beverage)
+		bevJson := bevJsonModel(beverage).(map[string]interface{})
+		locality.annotate(bevJson, beverage)
+		bevList[i] = bevJson
+	}
+	return bevList
+}
+
+// menuJsonModel groups beverages into the menu's sections, in the order the
+// venue lists them.
+func menuJsonModel(provider model.MenuProvider, beverages []model.Beverage, locality *breweryLocality) interface{} {
+	sections := []map[string]interface{}{}
+	sectionIndex := map[string]int{}
+	for _, beverage := range beverages {
+		i, ok := sectionIndex[beverage.MenuSection()]
+		if !ok {
+			i = len(sections)
+			sectionIndex[beverage.MenuSection()] = i
+			sections = append(sections, map[string]interface{}{
+				"name":   beverage.MenuSection(),
+				"drinks": []interface{}{},
+			})
+		}
+		bevJson := bevJsonModel(beverage).(map[string]interface{})
+		locality.annotate(bevJson, beverage)
+		sections[i]["drinks"] = append(sections[i]["drinks"].([]interface{}), bevJson)
+	}
+	return map[string]interface{}{
+		"id":       provider.ID(),
+		"name":     provider.Name(),
+		"sections": sections,
+	}
+}
+
+// breweryJsonModel describes a brewery and lists its beverages on tap at any
+// provider, with the IDs of the providers pouring each.
+func breweryJsonModel(repo repository.Repository, brewery model.Brewery) interface{} {
+	drinks := []interface{}{}
+	drinkIndex := map[string]int{}
+	for _, provider := range repo.MenuProviders() {
+		for _, beverage := range repo.ProviderBeverages(provider) {
+			if beverage.BreweryID() != brewery.ID() {
+				continue
+			}
+			i, ok := drinkIndex[beverage.ID()]
+			if !ok {
+				i = len(drinks)
+				drinkIndex[beverage.ID()] = i
+				bevJson := bevJsonModel(beverage).(map[string]interface{})
+				bevJson["menus"] = []string{}
+				drinks = append(drinks, bevJson)
+			}
+			bevJson := drinks[i].(map[string]interface{})
+			bevJson["menus"] = append(bevJson["menus"].([]string), provider.ID())
+		}
 	}
 	return map[string]interface{}{
-		"drinks": bevList,
+		"id":       brewery.ID(),
+		"name":     brewery.Name(),
+		"aliases":  brewery.Aliases(),
+		"location": brewery.Location(),
+		"links":    brewery.Links(),
+		"details":  breweryDetailsJsonModel(brewery.Details()),
+		"drinks":   drinks,
 	}
 }
 
+// breweryDetailsJsonModel describes where a brewery is, or is nil if it
+// hasn't been looked up.
+func breweryDetailsJsonModel(details model.BreweryDetails) interface{} {
+	if !details.Fetched() {
+		return nil
+	}
+	detailsJson := map[string]interface{}{
+		"city":      details.City,
+		"state":     details.State,
+		"country":   details.Country,
+		"website":   details.Website,
+		"type":      details.Type,
+		"source":    details.Source,
+		"fetchedAt": details.FetchTime,
+	}
+	if details.HasCoordinates() {
+		detailsJson["latitude"] = details.Latitude
+		detailsJson["longitude"] = details.Longitude
+	}
+	return detailsJson
+}
+
 func ratingScores(beverage model.Beverage) map[string]interface{} {
 	ratingScores := map[string]interface{}{}
 	for _, rating := range beverage.Ratings() {
@@ -47,22 +532,75 @@ func ratingScores(beverage model.Beverage) map[string]interface{} {
 	return ratingScores
 }
 
+// servingsJsonModel lists the beverage's servings, and returns the lowest
+// price per ounce among them (0 if none is known) for clients to sort on.
+func servingsJsonModel(beverage model.Beverage) ([]interface{}, float64) {
+	servings := []interface{}{}
+	lowestPricePerOunce := 0.0
+	for _, serving := range beverage.Servings() {
+		pricePerOunce := serving.PricePerOunce()
+		if pricePerOunce > 0 && (lowestPricePerOunce == 0 || pricePerOunce < lowestPricePerOunce) {
+			lowestPricePerOunce = pricePerOunce
+		}
+		servings = append(servings, map[string]interface{}{
+			"name":          serving.Name,
+			"size":          serving.Size,
+			"unit":          serving.Unit,
+			"ounces":        serving.Ounces(),
+			"price":         serving.Price,
+			"currency":      serving.Currency,
+			"pricePerOunce": pricePerOunce,
+		})
+	}
+	return servings, lowestPricePerOunce
+}
+
+// provenanceJsonModel describes the metadata source of each field that has
+// one.
+func provenanceJsonModel(beverage model.Beverage) map[string]interface{} {
+	provenance := map[string]interface{}{}
+	for field, prov := range beverage.Provenances() {
+		provenance[field] = map[string]interface{}{
+			"source":    prov.Source,
+			"score":     prov.Score,
+			"fetchedAt": prov.FetchTime,
+		}
+	}
+	return provenance
+}
+
 func bevJsonModel(beverage model.Beverage) interface{} {
+	servings, pricePerOunce := servingsJsonModel(beverage)
+	canonicalStyle := style.Canonical(beverage.Type())
 	bevJson := map[string]interface{}{
-		"id":           beverage.ID(),
-		"name":         beverage.DisplayName(),
-		"brewer":       beverage.Brewer(),
-		"type":         beverage.Type(),
-		"abv":          beverage.Abv(),
-		"description":  beverage.Description(),
-		"externalLink": beverage.Link(),
-		"ratings":      ratingScores(beverage),
+		"id":            beverage.ID(),
+		"name":          beverage.DisplayName(),
+		"brewer":        beverage.Brewer(),
+		"breweryId":     beverage.BreweryID(),
+		"type":          beverage.Type(),
+		"style":         canonicalStyle.Name,
+		"styleFamily":   canonicalStyle.Family,
+		"category":      beverage.Category(),
+		"abv":           beverage.Abv(),
+		"description":   beverage.Description(),
+		"externalLink":  beverage.Link(),
+		"ratings":       ratingScores(beverage),
+		"servings":      servings,
+		"pricePerOunce": pricePerOunce,
+		"section":       beverage.MenuSection(),
+		"order":         beverage.MenuOrder(),
+		"tap":           beverage.Tap(),
+		"provenance":    provenanceJsonModel(beverage),
+		"overridden":    model.OverriddenFields(beverage),
 	}
 
 	// Attributes should be named to not collide:
 	for name, value := range beverage.Attributes() {
 		bevJson[name] = value
 	}
+	if beverage.Image() != "" {
+		bevJson["img"] = beverage.Image()
+	}
 
 	return bevJson
 }
@@ -0,0 +1,231 @@
+package model
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bevly/bevly/text"
+)
+
+// Brewery is a brewery (or cidery, meadery, winery) that beverages
+// reference by ID. Aliases are the other names sources use for it; Links maps
+// a source name such as "BA" to the brewery's page on that source.
+type Brewery interface {
+	ID() string
+	SetID(id string)
+
+	Name() string
+	SetName(name string)
+
+	Aliases() []string
+	SetAliases(aliases []string)
+	AddAlias(alias string) bool
+
+	// Matches reports whether name is the brewery's name or one of its
+	// aliases, as compared by text.BreweryKey.
+	Matches(name string) bool
+
+	Location() string
+	SetLocation(location string)
+
+	// Details are the brewery's address, website and kind, as an
+	// enrichment source such as Open Brewery DB knows them.
+	Details() BreweryDetails
+	SetDetails(details BreweryDetails)
+
+	Links() map[string]string
+	SetLinks(links map[string]string)
+	SetLink(source, url string)
+}
+
+// BreweryDetails describes where a brewery is and what kind it is. Type is
+// Open Brewery DB's classification, such as "micro" or "brewpub". FetchTime
+// records when the details were looked up, even if Source had none.
+type BreweryDetails struct {
+	City      string
+	State     string
+	Country   string
+	Website   string
+	Type      string
+	Latitude  float64
+	Longitude float64
+	Source    string
+	FetchTime time.Time
+}
+
+// Fetched reports whether the brewery's details have been looked up.
+func (d BreweryDetails) Fetched() bool {
+	return !d.FetchTime.IsZero()
+}
+
+func (d BreweryDetails) HasCoordinates() bool {
+	return d.Latitude != 0 || d.Longitude != 0
+}
+
+// Place names the brewery's city and state, or country if it has no state.
+func (d BreweryDetails) Place() string {
+	region := d.State
+	if region == "" {
+		region = d.Country
+	}
+	switch {
+	case d.City == "":
+		return region
+	case region == "":
+		return d.City
+	}
+	return d.City + ", " + region
+}
+
+// DistanceKm is the great-circle distance from the brewery to a point.
+// It is only meaningful if HasCoordinates.
+func (d BreweryDetails) DistanceKm(latitude, longitude float64) float64 {
+	const earthRadiusKm = 6371.0
+	toRadians := func(deg float64) float64 { return deg * math.Pi / 180 }
+	lat1, lat2 := toRadians(d.Latitude), toRadians(latitude)
+	dLat := lat2 - lat1
+	dLon := toRadians(longitude - d.Longitude)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+type breweryData struct {
+	id       string
+	name     string
+	aliases  []string
+	location string
+	details  BreweryDetails
+	links    map[string]string
+}
+
+func CreateBrewery(name string) Brewery {
+	return &breweryData{name: name}
+}
+
+func (b *breweryData) ID() string {
+	return b.id
+}
+
+func (b *breweryData) SetID(id string) {
+	b.id = id
+}
+
+func (b *breweryData) Name() string {
+	return b.name
+}
+
+func (b *breweryData) SetName(name string) {
+	b.name = name
+}
+
+func (b *breweryData) Aliases() []string {
+	return b.aliases
+}
+
+func (b *breweryData) SetAliases(aliases []string) {
+	b.aliases = aliases
+}
+
+// AddAlias records another name for the brewery, returning false if the
+// brewery already answers to it exactly.
+func (b *breweryData) AddAlias(alias string) bool {
+	alias = text.Normalize(alias)
+	if alias == "" || alias == b.name {
+		return false
+	}
+	for _, existing := range b.aliases {
+		if existing == alias {
+			return false
+		}
+	}
+	b.aliases = append(b.aliases, alias)
+	return true
+}
+
+func (b *breweryData) Matches(name string) bool {
+	key := text.BreweryKey(name)
+	if key == "" {
+		return false
+	}
+	if text.BreweryKey(b.name) == key {
+		return true
+	}
+	for _, alias := range b.aliases {
+		if text.BreweryKey(alias) == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *breweryData) Location() string {
+	return b.location
+}
+
+func (b *breweryData) SetLocation(location string) {
+	b.location = location
+}
+
+func (b *breweryData) Details() BreweryDetails {
+	return b.details
+}
+
+func (b *breweryData) SetDetails(details BreweryDetails) {
+	b.details = details
+}
+
+func (b *breweryData) Links() map[string]string {
+	return b.links
+}
+
+func (b *breweryData) SetLinks(links map[string]string) {
+	b.links = links
+}
+
+func (b *breweryData) SetLink(source, url string) {
+	if b.links == nil {
+		b.links = map[string]string{}
+	}
+	b.links[source] = url
+}
+
+func (b *breweryData) String() string {
+	return b.name
+}
+
+// ProviderCoordinates returns the venue's latitude and longitude, if its
+// options give them.
+func ProviderCoordinates(provider MenuProvider) (float64, float64, bool) {
+	latitude, err := strconv.ParseFloat(provider.Option(OptionLatitude), 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	longitude, err := strconv.ParseFloat(provider.Option(OptionLongitude), 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return latitude, longitude, true
+}
+
+// BreweryDistanceKm returns how far a brewery is from a venue, if both are
+// located.
+func BreweryDistanceKm(provider MenuProvider, brewery Brewery) (float64, bool) {
+	latitude, longitude, ok := ProviderCoordinates(provider)
+	if !ok || !brewery.Details().HasCoordinates() {
+		return 0, false
+	}
+	return brewery.Details().DistanceKm(latitude, longitude), true
+}
+
+// IsLocal reports whether a brewery is local to a venue: within
+// LocalDistanceKm of it, or in the same state if either is not located.
+func IsLocal(provider MenuProvider, brewery Brewery) bool {
+	if distance, ok := BreweryDistanceKm(provider, brewery); ok {
+		return distance <= LocalDistanceKm
+	}
+	state := provider.Option(OptionState)
+	return state != "" && strings.EqualFold(state, brewery.Details().State)
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsLocal(t *testing.T) {
+	provider := CreateMenuProvider("frisco", "Frisco", "", "")
+	provider.SetOption(OptionLatitude, "39.1836")
+	provider.SetOption(OptionLongitude, "-76.8079")
+	provider.SetOption(OptionState, "Maryland")
+
+	oliver := CreateBrewery("Oliver Brewing")
+	oliver.SetDetails(BreweryDetails{State: "Maryland",
+		Latitude: 39.3082, Longitude: -76.5629, FetchTime: time.Now()})
+	distance, ok := BreweryDistanceKm(provider, oliver)
+	assert.True(t, ok)
+	assert.InDelta(t, 25, distance, 1)
+	assert.True(t, IsLocal(provider, oliver))
+
+	dogfish := CreateBrewery("Dogfish Head")
+	dogfish.SetDetails(BreweryDetails{State: "Delaware",
+		Latitude: 38.7776, Longitude: -75.3099, FetchTime: time.Now()})
+	assert.False(t, IsLocal(provider, dogfish), "Milton is 137km away")
+
+	// Without coordinates, breweries in the venue's state are local:
+	flyingDog := CreateBrewery("Flying Dog")
+	flyingDog.SetDetails(BreweryDetails{State: "maryland", FetchTime: time.Now()})
+	_, ok = BreweryDistanceKm(provider, flyingDog)
+	assert.False(t, ok)
+	assert.True(t, IsLocal(provider, flyingDog))
+	assert.False(t, IsLocal(provider, CreateBrewery("Unknown")))
+}
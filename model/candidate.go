@@ -0,0 +1,121 @@
+package model
+
+// ProfileCandidate is a search result a metadata source considered as a
+// beverage's profile, with the scores it was ranked by. The candidates a
+// source passed over are kept for review.
+type ProfileCandidate struct {
+	Source string
+	URL    string
+	Title  string
+	// Confidence, from 0 to 1, combines the scores below.
+	Confidence float64
+	NameScore  float64
+	// BrewerScore and AbvScore are -1 where the beverage's brewer or ABV,
+	// or the result's ABV, are unknown.
+	BrewerScore float64
+	AbvScore    float64
+	// Abv is the ABV the search result listed, or 0.
+	Abv         float64
+	Explanation string
+	// Chosen marks the candidate the source fetched.
+	Chosen bool
+	// Pending marks the best candidate when it was good enough to choose
+	// but not to choose without staff review; the source fetched nothing.
+	Pending bool
+	// Rejected marks a candidate staff rejected as the beverage's profile;
+	// it is never chosen again. See Override.RejectedProfiles.
+	Rejected bool
+}
+
+// ChosenCandidate returns the candidate source chose for bev, if any.
+func ChosenCandidate(bev Beverage, source string) (ProfileCandidate, bool) {
+	for _, candidate := range bev.ProfileCandidates() {
+		if candidate.Source == source && candidate.Chosen {
+			return candidate, true
+		}
+	}
+	return ProfileCandidate{}, false
+}
+
+// PendingCandidate returns the candidate of source awaiting review for bev,
+// if any.
+func PendingCandidate(bev Beverage, source string) (ProfileCandidate, bool) {
+	for _, candidate := range bev.ProfileCandidates() {
+		if candidate.Source == source && candidate.Pending {
+			return candidate, true
+		}
+	}
+	return ProfileCandidate{}, false
+}
+
+// PendingSources lists the sources with a candidate awaiting review for
+// bev.
+func PendingSources(bev Beverage) []string {
+	sources := []string{}
+	for _, candidate := range bev.ProfileCandidates() {
+		if candidate.Pending {
+			sources = append(sources, candidate.Source)
+		}
+	}
+	return sources
+}
+
+// SourceCandidates lists the candidates of source for bev, best first.
+func SourceCandidates(bev Beverage, source string) []ProfileCandidate {
+	candidates := []ProfileCandidate{}
+	for _, candidate := range bev.ProfileCandidates() {
+		if candidate.Source == source {
+			candidates = append(candidates, candidate)
+		}
+	}
+	return candidates
+}
+
+// RejectedCandidates lists the candidates of source staff rejected for bev.
+func RejectedCandidates(bev Beverage, source string) []ProfileCandidate {
+	rejected := []ProfileCandidate{}
+	for _, candidate := range SourceCandidates(bev, source) {
+		if candidate.Rejected {
+			rejected = append(rejected, candidate)
+		}
+	}
+	return rejected
+}
+
+// RejectCandidates marks the candidates of source for bev linking to urls
+// as rejected, adding those bev lacks.
+func RejectCandidates(bev Beverage, source string, urls []string) {
+	if len(urls) == 0 {
+		return
+	}
+	candidates := SourceCandidates(bev, source)
+	for _, url := range urls {
+		found := false
+		for i := range candidates {
+			if candidates[i].URL == url {
+				candidates[i].Rejected, candidates[i].Chosen, candidates[i].Pending = true, false, false
+				found = true
+			}
+		}
+		if !found {
+			candidates = append(candidates, ProfileCandidate{Source: source, URL: url, Rejected: true})
+		}
+	}
+	bev.SetProfileCandidates(source, candidates)
+}
+
+// ReviewCandidates ends the review of candidates, marking the one linking
+// to chosenURL as chosen, and no longer rejected, or none if chosenURL is
+// "". Returns false if no candidate links to chosenURL.
+func ReviewCandidates(candidates []ProfileCandidate, chosenURL string) ([]ProfileCandidate, bool) {
+	reviewed := make([]ProfileCandidate, len(candidates))
+	found := chosenURL == ""
+	for i, candidate := range candidates {
+		candidate.Pending = false
+		candidate.Chosen = chosenURL != "" && candidate.URL == chosenURL
+		candidate.Rejected = candidate.Rejected && !candidate.Chosen
+		found = found || candidate.Chosen
+		reviewed[i] = candidate
+	}
+	return reviewed, found
+}
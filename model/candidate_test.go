@@ -0,0 +1,36 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPendingCandidates(t *testing.T) {
+	bev := CreateBeverage("Racer 5")
+	bev.SetProfileCandidates("BA", []ProfileCandidate{
+		{Source: "BA", URL: "http://ba/1", Confidence: 0.6, Pending: true},
+		{Source: "BA", URL: "http://ba/2", Confidence: 0.4},
+	})
+	bev.SetProfileCandidates("RateBeer", []ProfileCandidate{
+		{Source: "RateBeer", URL: "http://rb/1", Confidence: 0.9, Chosen: true},
+	})
+	assert.Equal(t, []string{"BA"}, PendingSources(bev))
+	pending, ok := PendingCandidate(bev, "BA")
+	assert.True(t, ok)
+	assert.Equal(t, "http://ba/1", pending.URL)
+	_, ok = PendingCandidate(bev, "RateBeer")
+	assert.False(t, ok)
+
+	reviewed, ok := ReviewCandidates(SourceCandidates(bev, "BA"), "http://ba/2")
+	assert.True(t, ok)
+	assert.False(t, reviewed[0].Pending)
+	assert.False(t, reviewed[0].Chosen)
+	assert.True(t, reviewed[1].Chosen)
+
+	_, ok = ReviewCandidates(SourceCandidates(bev, "BA"), "http://ba/3")
+	assert.False(t, ok, "not a candidate")
+	reviewed, ok = ReviewCandidates(SourceCandidates(bev, "BA"), "")
+	assert.True(t, ok)
+	assert.False(t, reviewed[0].Pending || reviewed[0].Chosen || reviewed[1].Chosen)
+}
@@ -2,13 +2,41 @@ package model
 
 import "time"
 
+// Provider options locating the venue, for telling which breweries are
+// local to it.
+const (
+	OptionLatitude  = "latitude"
+	OptionLongitude = "longitude"
+	OptionState     = "state"
+)
+
+// LocalDistanceKm is how far from a venue a brewery may be to count as
+// local.
+const LocalDistanceKm = 80.0
+
 type MenuProvider interface {
 	ID() string
 	Name() string
 	URL() string
 	MenuFormat() string
+
+	// Options holds format-specific configuration for the provider's
+	// menu fetcher, such as the CSV column names.
+	Option(name string) string
+	Options() map[string]string
+	SetOption(name, value string)
 }
 
+// Beverage categories. An empty category means unknown.
+const (
+	CategoryBeer     = "beer"
+	CategoryCider    = "cider"
+	CategoryMead     = "mead"
+	CategoryWine     = "wine"
+	CategoryKombucha = "kombucha"
+	CategoryCocktail = "cocktail"
+)
+
 type Beverage interface {
 	ID() string
 	SetID(id string)
@@ -19,6 +47,8 @@ type Beverage interface {
 	SetSearchName(name string)
 	Type() string
 	SetType(bevType string)
+	Category() string
+	SetCategory(category string)
 
 	Name() string
 	SetName(name string)
@@ -29,13 +59,22 @@ type Beverage interface {
 	Description() string
 	SetDescription(desc string)
 
+	// AccuracyScore is the highest score of the sources that set the
+	// beverage's fields. Only legacy records go by it; see
+	// AdoptLegacyScore.
 	AccuracyScore() int
 	SetAccuracyScore(accuracy int)
 
 	Brewer() string
 	SetBrewer(brewer string)
+	// BreweryID references the Brewery that Brewer() names, once resolved.
+	BreweryID() string
+	SetBreweryID(id string)
 	Link() string
 	SetLink(link string)
+	// Image is the URL of a picture of the beverage.
+	Image() string
+	SetImage(image string)
 	Attribute(name string) string
 	Attributes() map[string]string
 	SetAttributes(attr map[string]string)
@@ -43,6 +82,34 @@ type Beverage interface {
 	SyncTime() time.Time
 	SetSyncTime(newtime time.Time)
 
+	// Servings are the pours the menu lists, in menu order.
+	Servings() []Serving
+	SetServings(servings []Serving)
+	AddServing(serving Serving)
+
+	// The beverage's place on a provider's menu: the section it is listed
+	// under, its 1-based position on the menu, and its tap number, if any.
+	// These describe one menu, and are not shared between providers that
+	// pour the same beverage.
+	MenuSection() string
+	SetMenuSection(section string)
+	MenuOrder() int
+	SetMenuOrder(order int)
+	Tap() string
+	SetTap(tap string)
+
+	// Provenance records the metadata source that set each field (see the
+	// Field constants); fields set by menus have no provenance.
+	Provenance(field string) Provenance
+	Provenances() map[string]Provenance
+	SetProvenance(field string, prov Provenance)
+
+	// ProfileCandidates are the search results metadata sources ranked
+	// when choosing the beverage's profiles, best first for each source.
+	ProfileCandidates() []ProfileCandidate
+	// SetProfileCandidates replaces the candidates of one source.
+	SetProfileCandidates(source string, candidates []ProfileCandidate)
+
 	BeverageStats
 }
 
@@ -97,11 +164,20 @@ type BeverageData struct {
 	name          string
 	description   string
 	bevType       string
+	category      string
 	brewer        string
+	breweryID     string
 	abv           float64
 	attr          map[string]string
 	ratings       []Rating
+	servings      []Serving
+	menuSection   string
+	menuOrder     int
+	tap           string
 	link          string
+	image         string
+	provenance    map[string]Provenance
+	candidates    []ProfileCandidate
 	syncTime      time.Time
 	needSync      bool
 }
@@ -225,6 +301,42 @@ func (b *BeverageData) ClearRatings() {
 	b.ratings = []Rating{}
 }
 
+func (b *BeverageData) Servings() []Serving {
+	return b.servings
+}
+
+func (b *BeverageData) SetServings(servings []Serving) {
+	b.servings = servings
+}
+
+func (b *BeverageData) AddServing(serving Serving) {
+	b.servings = append(b.servings, serving)
+}
+
+func (b *BeverageData) MenuSection() string {
+	return b.menuSection
+}
+
+func (b *BeverageData) SetMenuSection(section string) {
+	b.menuSection = section
+}
+
+func (b *BeverageData) MenuOrder() int {
+	return b.menuOrder
+}
+
+func (b *BeverageData) SetMenuOrder(order int) {
+	b.menuOrder = order
+}
+
+func (b *BeverageData) Tap() string {
+	return b.tap
+}
+
+func (b *BeverageData) SetTap(tap string) {
+	b.tap = tap
+}
+
 func (b *BeverageData) SetBrewer(brewer string) {
 	b.brewer = brewer
 }
@@ -233,6 +345,14 @@ func (b *BeverageData) Brewer() string {
 	return b.brewer
 }
 
+func (b *BeverageData) BreweryID() string {
+	return b.breweryID
+}
+
+func (b *BeverageData) SetBreweryID(id string) {
+	b.breweryID = id
+}
+
 func (b *BeverageData) DisplayName() string {
 	return b.displayName
 }
@@ -249,6 +369,14 @@ func (b *BeverageData) SetType(bevType string) {
 	b.bevType = bevType
 }
 
+func (b *BeverageData) Category() string {
+	return b.category
+}
+
+func (b *BeverageData) SetCategory(category string) {
+	b.category = category
+}
+
 func (b *BeverageData) Link() string {
 	return b.link
 }
@@ -257,6 +385,47 @@ func (b *BeverageData) SetLink(link string) {
 	b.link = link
 }
 
+func (b *BeverageData) Image() string {
+	return b.image
+}
+
+func (b *BeverageData) SetImage(image string) {
+	b.image = image
+}
+
+func (b *BeverageData) Provenance(field string) Provenance {
+	return b.provenance[field]
+}
+
+func (b *BeverageData) Provenances() map[string]Provenance {
+	return b.provenance
+}
+
+func (b *BeverageData) SetProvenance(field string, prov Provenance) {
+	if b.provenance == nil {
+		b.provenance = map[string]Provenance{}
+	}
+	b.provenance[field] = prov
+}
+
+func (b *BeverageData) ProfileCandidates() []ProfileCandidate {
+	return b.candidates
+}
+
+func (b *BeverageData) SetProfileCandidates(source string, candidates []ProfileCandidate) {
+	kept := []ProfileCandidate{}
+	for _, candidate := range b.candidates {
+		if candidate.Source != source {
+			kept = append(kept, candidate)
+		}
+	}
+	for _, candidate := range candidates {
+		candidate.Source = source
+		kept = append(kept, candidate)
+	}
+	b.candidates = kept
+}
+
 func (b *BeverageData) String() string {
 	return b.DisplayName()
 }
@@ -284,12 +453,24 @@ type menuProvider struct {
 	name       string
 	url        string
 	menuFormat string
+	options    map[string]string
 }
 
 func CreateMenuProvider(id string, name string, url string, format string) MenuProvider {
 	return &menuProvider{id: id, name: name, url: url, menuFormat: format}
 }
 
+// CreateMenuProviderAt copies provider, but with its menu at url, such as
+// a saved copy of the menu page.
+func CreateMenuProviderAt(provider MenuProvider, url string) MenuProvider {
+	copied := &menuProvider{id: provider.ID(), name: provider.Name(), url: url,
+		menuFormat: provider.MenuFormat()}
+	for name, value := range provider.Options() {
+		copied.SetOption(name, value)
+	}
+	return copied
+}
+
 func (m *menuProvider) ID() string {
 	return m.id
 }
@@ -306,6 +487,24 @@ func (m *menuProvider) MenuFormat() string {
 	return m.menuFormat
 }
 
+func (m *menuProvider) Option(name string) string {
+	if m.options == nil {
+		return ""
+	}
+	return m.options[name]
+}
+
+func (m *menuProvider) Options() map[string]string {
+	return m.options
+}
+
+func (m *menuProvider) SetOption(name, value string) {
+	if m.options == nil {
+		m.options = map[string]string{}
+	}
+	m.options[name] = value
+}
+
 func (m *menuProvider) String() string {
 	return m.id
 }
@@ -0,0 +1,220 @@
+package model
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// Staff overrides are recorded in field provenance as coming from
+// OverrideSource, with a score no metadata source reaches, so automatic
+// syncs never replace them.
+const (
+	OverrideSource = "staff"
+	OverrideScore  = 1000
+)
+
+var (
+	ErrOverrideField = errors.New("unknown override field")
+	ErrOverrideAbv   = errors.New("override ABV is not a number")
+)
+
+// Override is staff curation of a beverage that survives metadata syncs.
+type Override struct {
+	BeverageID string
+	// Fields maps Field constants to staff-set values. The ABV is given
+	// as a decimal number.
+	Fields map[string]string
+	// ProfileURLs pins the profile a metadata source fetches, by source
+	// name.
+	ProfileURLs map[string]string
+	// Blocked lists the metadata sources never to match.
+	Blocked []string
+	// RejectedProfiles lists, by source name, the profiles staff rejected
+	// as matches, never to be chosen again.
+	RejectedProfiles map[string][]string
+	// NotDuplicates lists the IDs of beverages staff split from this one,
+	// never to be merged with it again.
+	NotDuplicates []string
+	Note          string
+	UpdatedAt     time.Time
+}
+
+// Empty reports whether the override changes nothing.
+func (o *Override) Empty() bool {
+	return o == nil ||
+		(len(o.Fields) == 0 && len(o.ProfileURLs) == 0 && len(o.Blocked) == 0 &&
+			len(o.RejectedProfiles) == 0 && len(o.NotDuplicates) == 0)
+}
+
+// Validate checks that the override only sets known fields, and that its
+// ABV is a number.
+func (o *Override) Validate() error {
+	for field, value := range o.Fields {
+		if !isProvenanceField(field) {
+			return ErrOverrideField
+		}
+		if field == FieldAbv {
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return ErrOverrideAbv
+			}
+		}
+	}
+	return nil
+}
+
+func isProvenanceField(field string) bool {
+	for _, known := range ProvenanceFields {
+		if field == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Blocks reports whether metadata from source must be ignored. A nil
+// override blocks nothing.
+func (o *Override) Blocks(source string) bool {
+	if o == nil {
+		return false
+	}
+	for _, blocked := range o.Blocked {
+		if blocked == source {
+			return true
+		}
+	}
+	return false
+}
+
+// ProfileURL returns the profile pinned for source, or "".
+func (o *Override) ProfileURL(source string) string {
+	if o == nil {
+		return ""
+	}
+	return o.ProfileURLs[source]
+}
+
+// PinProfile pins the profile source fetches, unblocking source and
+// taking back any rejection of the profile.
+func (o *Override) PinProfile(source, profileURL string) {
+	if o.ProfileURLs == nil {
+		o.ProfileURLs = map[string]string{}
+	}
+	o.ProfileURLs[source] = profileURL
+	o.Blocked = without(o.Blocked, source)
+	if rejected := without(o.RejectedProfiles[source], profileURL); len(rejected) > 0 {
+		o.RejectedProfiles[source] = rejected
+	} else {
+		delete(o.RejectedProfiles, source)
+	}
+}
+
+// RejectProfile rejects profileURL as source's match, unpinning it.
+func (o *Override) RejectProfile(source, profileURL string) {
+	if o.ProfileURL(source) == profileURL {
+		delete(o.ProfileURLs, source)
+	}
+	if o.RejectedProfiles == nil {
+		o.RejectedProfiles = map[string][]string{}
+	}
+	o.RejectedProfiles[source] = append(
+		without(o.RejectedProfiles[source], profileURL), profileURL)
+}
+
+// RejectedURLs lists the profiles of source staff rejected. A nil override
+// rejects nothing.
+func (o *Override) RejectedURLs(source string) []string {
+	if o == nil {
+		return nil
+	}
+	return o.RejectedProfiles[source]
+}
+
+// Block blocks source, unpinning its profile.
+func (o *Override) Block(source string) {
+	delete(o.ProfileURLs, source)
+	o.Blocked = append(without(o.Blocked, source), source)
+}
+
+// NotDuplicateOf reports whether staff split the beverage with the given ID
+// from this one. A nil override splits nothing.
+func (o *Override) NotDuplicateOf(beverageID string) bool {
+	if o == nil {
+		return false
+	}
+	for _, id := range o.NotDuplicates {
+		if id == beverageID {
+			return true
+		}
+	}
+	return false
+}
+
+// MarkNotDuplicate records that the beverage with the given ID is not a
+// duplicate of this one.
+func (o *Override) MarkNotDuplicate(beverageID string) {
+	if !o.NotDuplicateOf(beverageID) {
+		o.NotDuplicates = append(o.NotDuplicates, beverageID)
+	}
+}
+
+// without returns values without value.
+func without(values []string, value string) []string {
+	kept := []string{}
+	for _, v := range values {
+		if v != value {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+// SourceData names the ratings and attributes a metadata source sets
+// besides the fields that record their provenance, so that they can be
+// discarded with those fields when an override blocks the source.
+type SourceData struct {
+	Ratings    []string
+	Attributes []string
+}
+
+var sourceDataRegistry = map[string]SourceData{}
+
+// RegisterSourceData records the ratings and attributes source sets.
+func RegisterSourceData(source string, data SourceData) {
+	sourceDataRegistry[source] = data
+}
+
+// SourceDataOf returns the ratings and attributes source sets, if they were
+// registered.
+func SourceDataOf(source string) SourceData {
+	return sourceDataRegistry[source]
+}
+
+// ApplyOverride sets the staff-set fields of bev, recording their provenance
+// as OverrideSource. Unknown fields and unparseable ABVs are ignored.
+func ApplyOverride(bev Beverage, o *Override) {
+	if o == nil {
+		return
+	}
+	prov := Provenance{Source: OverrideSource, Score: OverrideScore, FetchTime: o.UpdatedAt}
+	for field, value := range o.Fields {
+		if field == FieldAbv {
+			if abv, err := strconv.ParseFloat(value, 64); err == nil {
+				SetAbvField(bev, abv, prov)
+			}
+			continue
+		}
+		SetField(bev, field, value, prov)
+	}
+}
+
+// OverriddenFields lists the fields of bev that staff have set.
+func OverriddenFields(bev Beverage) []string {
+	fields := []string{}
+	for _, field := range ProvenanceFields {
+		if bev.Provenance(field).Source == OverrideSource {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,99 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyOverride(t *testing.T) {
+	bev := CreateBeverage("Oliver Draft Punk")
+	ba := CreateProvenance("BA", 10)
+	SetField(bev, FieldBrewer, "Draft Punk Brewing", ba)
+	SetAbvField(bev, 12.0, ba)
+
+	override := &Override{Fields: map[string]string{
+		FieldBrewer: "Oliver Brewing Co.",
+		FieldAbv:    "5.5",
+	}}
+	assert.Nil(t, override.Validate())
+	ApplyOverride(bev, override)
+	assert.Equal(t, "Oliver Brewing Co.", bev.Brewer())
+	assert.Equal(t, 5.5, bev.Abv())
+	assert.Equal(t, 10, bev.AccuracyScore(), "overrides leave the score alone")
+	assert.Equal(t, []string{FieldBrewer, FieldAbv}, OverriddenFields(bev))
+
+	assert.False(t, SetField(bev, FieldBrewer, "Draft Punk Brewing", ba),
+		"metadata sources cannot replace staff values")
+}
+
+func TestOverrideSources(t *testing.T) {
+	var none *Override
+	assert.True(t, none.Empty())
+	assert.False(t, none.Blocks("BA"))
+	assert.Equal(t, "", none.ProfileURL("BA"))
+
+	override := &Override{
+		ProfileURLs: map[string]string{"RateBeer": "http://www.ratebeer.com/beer/x/1/"},
+		Blocked:     []string{"BA"},
+	}
+	assert.False(t, override.Empty())
+	assert.True(t, override.Blocks("BA"))
+	assert.False(t, override.Blocks("RateBeer"))
+	assert.Equal(t, "http://www.ratebeer.com/beer/x/1/", override.ProfileURL("RateBeer"))
+}
+
+func TestValidateOverride(t *testing.T) {
+	assert.Equal(t, ErrOverrideField,
+		(&Override{Fields: map[string]string{"colour": "amber"}}).Validate())
+	assert.Equal(t, ErrOverrideAbv,
+		(&Override{Fields: map[string]string{FieldAbv: "strong"}}).Validate())
+}
+
+func TestOverridePinAndBlock(t *testing.T) {
+	override := &Override{Blocked: []string{"BA", "Untappd"}}
+	override.PinProfile("BA", "https://www.beeradvocate.com/beer/profile/1/2/")
+	assert.False(t, override.Blocks("BA"), "pinning unblocks")
+	assert.True(t, override.Blocks("Untappd"))
+	assert.Equal(t, "https://www.beeradvocate.com/beer/profile/1/2/", override.ProfileURL("BA"))
+
+	override.Block("BA")
+	override.Block("BA")
+	assert.Equal(t, []string{"Untappd", "BA"}, override.Blocked)
+	assert.Equal(t, "", override.ProfileURL("BA"), "blocking unpins")
+}
+
+func TestSourceData(t *testing.T) {
+	RegisterSourceData("test-source", SourceData{Ratings: []string{"ts"}, Attributes: []string{"tsLink"}})
+	assert.Equal(t, []string{"ts"}, SourceDataOf("test-source").Ratings)
+	assert.Empty(t, SourceDataOf("unregistered").Attributes)
+}
+
+func TestOverrideNotDuplicate(t *testing.T) {
+	var none *Override
+	assert.False(t, none.NotDuplicateOf("1"))
+
+	override := &Override{BeverageID: "2"}
+	override.MarkNotDuplicate("1")
+	override.MarkNotDuplicate("1")
+	assert.Equal(t, []string{"1"}, override.NotDuplicates)
+	assert.True(t, override.NotDuplicateOf("1"))
+	assert.False(t, override.Empty(), "splits are curation")
+}
+
+func TestOverrideRejectProfile(t *testing.T) {
+	var none *Override
+	assert.Empty(t, none.RejectedURLs("BA"))
+
+	override := &Override{}
+	override.PinProfile("BA", "https://www.beeradvocate.com/beer/profile/1/2/")
+	override.RejectProfile("BA", "https://www.beeradvocate.com/beer/profile/1/2/")
+	override.RejectProfile("BA", "https://www.beeradvocate.com/beer/profile/1/2/")
+	assert.Equal(t, []string{"https://www.beeradvocate.com/beer/profile/1/2/"}, override.RejectedURLs("BA"))
+	assert.Equal(t, "", override.ProfileURL("BA"), "rejecting unpins")
+	assert.False(t, override.Blocks("BA"), "rejecting doesn't block")
+	assert.False(t, override.Empty())
+
+	override.PinProfile("BA", "https://www.beeradvocate.com/beer/profile/1/2/")
+	assert.Empty(t, override.RejectedURLs("BA"), "pinning takes the rejection back")
+}
@@ -0,0 +1,154 @@
+package model
+
+import "time"
+
+// Beverage fields that record their provenance.
+const (
+	FieldName        = "name"
+	FieldBrewer      = "brewer"
+	FieldType        = "type"
+	FieldAbv         = "abv"
+	FieldDescription = "description"
+	FieldLink        = "link"
+	FieldImage       = "image"
+)
+
+// ProvenanceFields lists the fields that record their provenance.
+var ProvenanceFields = []string{
+	FieldName, FieldBrewer, FieldType, FieldAbv, FieldDescription, FieldLink, FieldImage,
+}
+
+// Provenance records which metadata source set a field, how much that source
+// is trusted, and when it was fetched.
+type Provenance struct {
+	Source    string
+	Score     int
+	FetchTime time.Time
+}
+
+func (p Provenance) Empty() bool { return p.Source == "" }
+
+// CreateProvenance describes a fetch from source made now.
+func CreateProvenance(source string, score int) Provenance {
+	return Provenance{Source: source, Score: score, FetchTime: time.Now()}
+}
+
+var sourceScores = map[string]int{}
+
+// SetSourceScore sets how far metadata from source is trusted. Sources
+// register their default scores; deployments may configure others.
+func SetSourceScore(source string, score int) {
+	sourceScores[source] = score
+}
+
+// SourceScore returns the score set for source, or 0.
+func SourceScore(source string) int {
+	return sourceScores[source]
+}
+
+// MenuScore is the score of fields without provenance, which menus set:
+// any metadata source may replace them.
+const MenuScore = 0
+
+// LegacySource names, in field provenance, the source of fields saved
+// before fields recorded their provenance. See AdoptLegacyScore.
+const LegacySource = "legacy"
+
+// FieldScore returns the score of the source that set field, or MenuScore
+// if no source did.
+func FieldScore(bev Beverage, field string) int {
+	if prov := bev.Provenance(field); !prov.Empty() {
+		return prov.Score
+	}
+	return MenuScore
+}
+
+// AdoptLegacyScore records the provenance of the fields of a beverage saved
+// before fields recorded their provenance, as LegacySource with the
+// beverage's AccuracyScore, which was then their score. Beverages that
+// record any provenance, or were never fetched, are left alone.
+func AdoptLegacyScore(bev Beverage) {
+	if bev.AccuracyScore() == 0 || len(bev.Provenances()) > 0 {
+		return
+	}
+	prov := Provenance{Source: LegacySource, Score: bev.AccuracyScore(), FetchTime: bev.SyncTime()}
+	for _, field := range ProvenanceFields {
+		if FieldText(bev, field) != "" || (field == FieldAbv && bev.HasAbv()) {
+			bev.SetProvenance(field, prov)
+		}
+	}
+}
+
+// FieldText returns the value of a text field as a string.
+func FieldText(bev Beverage, field string) string {
+	switch field {
+	case FieldName:
+		return bev.Name()
+	case FieldBrewer:
+		return bev.Brewer()
+	case FieldType:
+		return bev.Type()
+	case FieldDescription:
+		return bev.Description()
+	case FieldLink:
+		return bev.Link()
+	case FieldImage:
+		return bev.Image()
+	}
+	return ""
+}
+
+func setFieldText(bev Beverage, field, value string) {
+	switch field {
+	case FieldName:
+		bev.SetName(value)
+	case FieldBrewer:
+		bev.SetBrewer(value)
+	case FieldType:
+		bev.SetType(value)
+	case FieldDescription:
+		bev.SetDescription(value)
+	case FieldLink:
+		bev.SetLink(value)
+	case FieldImage:
+		bev.SetImage(value)
+	}
+}
+
+// SetField sets a text field to value on behalf of the source described by
+// prov, if value is non-empty and the field is empty or was set by a source
+// scoring no higher than prov. The beverage's AccuracyScore, which only
+// legacy records go by, is raised to the source's score, unless prov is a
+// staff override. Returns true if the field was set.
+func SetField(bev Beverage, field, value string, prov Provenance) bool {
+	if value == "" {
+		return false
+	}
+	if FieldText(bev, field) != "" && FieldScore(bev, field) > prov.Score {
+		return false
+	}
+	setFieldText(bev, field, value)
+	recordProvenance(bev, field, prov)
+	return true
+}
+
+// SetAbvField is SetField for the ABV.
+func SetAbvField(bev Beverage, abv float64, prov Provenance) bool {
+	if abv <= 0.0 {
+		return false
+	}
+	if bev.HasAbv() && FieldScore(bev, FieldAbv) > prov.Score {
+		return false
+	}
+	bev.SetAbv(abv)
+	recordProvenance(bev, FieldAbv, prov)
+	return true
+}
+
+func recordProvenance(bev Beverage, field string, prov Provenance) {
+	bev.SetProvenance(field, prov)
+	// Staff overrides vouch only for their own fields:
+	if prov.Source != OverrideSource && prov.Score > bev.AccuracyScore() {
+		bev.SetAccuracyScore(prov.Score)
+	}
+}
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetField(t *testing.T) {
+	bev := CreateBeverage("Anchor IPA")
+	bev.SetType("IPA")
+
+	rb := CreateProvenance("RateBeer", 9)
+	assert.True(t, SetField(bev, FieldType, "American IPA", rb),
+		"menu values have no provenance")
+	assert.True(t, SetField(bev, FieldName, "Anchor IPA", rb))
+	assert.True(t, SetAbvField(bev, 6.5, rb))
+	assert.Equal(t, 9, bev.AccuracyScore(), "score raised to source score")
+
+	frisco := CreateProvenance("Frisco", 2)
+	assert.False(t, SetField(bev, FieldName, "Anchor I.P.A.", frisco),
+		"lower score does not overwrite")
+	assert.False(t, SetAbvField(bev, 6.0, frisco))
+	assert.True(t, SetField(bev, FieldDescription, "Hoppy", frisco),
+		"lower score fills empty fields")
+	assert.Equal(t, 9, bev.AccuracyScore(), "score not lowered")
+
+	ba := CreateProvenance("BA", 10)
+	assert.True(t, SetField(bev, FieldDescription, "Very hoppy", ba))
+	assert.False(t, SetField(bev, FieldLink, "", ba), "empty values ignored")
+
+	assert.Equal(t, "American IPA", bev.Type())
+	assert.Equal(t, 6.5, bev.Abv())
+	assert.Equal(t, "RateBeer", bev.Provenance(FieldAbv).Source)
+	assert.Equal(t, "Very hoppy", bev.Description())
+	assert.Equal(t, 10, bev.Provenance(FieldDescription).Score)
+	assert.True(t, bev.Provenance(FieldLink).Empty())
+}
+
+func TestMenuFieldScore(t *testing.T) {
+	bev := CreateBeverage("Anchor IPA")
+	bev.SetAbv(6.0)
+
+	assert.True(t, SetField(bev, FieldDescription, "Hoppy", CreateProvenance("RateBeer", 9)))
+	assert.Equal(t, MenuScore, FieldScore(bev, FieldAbv),
+		"menu values score the same whatever else was fetched")
+	assert.True(t, SetAbvField(bev, 6.5, CreateProvenance("Catalog", 7)))
+	assert.Equal(t, 6.5, bev.Abv())
+}
+
+func TestAdoptLegacyScore(t *testing.T) {
+	bev := CreateBeverage("Anchor IPA")
+	bev.SetType("IPA")
+	bev.SetAbv(6.5)
+	bev.SetAccuracyScore(9)
+	AdoptLegacyScore(bev)
+	assert.Equal(t, Provenance{Source: LegacySource, Score: 9}, bev.Provenance(FieldAbv))
+	assert.Equal(t, 9, FieldScore(bev, FieldType))
+	assert.True(t, bev.Provenance(FieldDescription).Empty(), "empty fields")
+	assert.False(t, SetAbvField(bev, 6.0, CreateProvenance("Catalog", 7)),
+		"legacy fields keep their score")
+
+	fetched := CreateBeverage("Anchor IPA")
+	fetched.SetAbv(6.0)
+	SetField(fetched, FieldType, "American IPA", CreateProvenance("RateBeer", 9))
+	AdoptLegacyScore(fetched)
+	assert.True(t, fetched.Provenance(FieldAbv).Empty(), "records with provenance aren't legacy")
+}
@@ -0,0 +1,96 @@
+package model
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Serving is one pour of a beverage as listed on a menu: its size and price.
+// Size and Price are zero when the menu doesn't say.
+type Serving struct {
+	Name     string  // the pour as the menu labels it: "Pint", "5oz", "Gr"
+	Size     float64 // in Unit
+	Unit     string  // "oz", "ml", "cl" or "l"
+	Price    float64
+	Currency string // ISO 4217 code, such as "USD"
+}
+
+const millilitersPerOunce = 29.5735
+
+var unitMilliliters = map[string]float64{
+	"oz": millilitersPerOunce,
+	"ml": 1,
+	"cl": 10,
+	"l":  1000,
+}
+
+// Ounces returns the serving size in US fluid ounces, or 0 if unknown.
+func (s Serving) Ounces() float64 {
+	ml, ok := unitMilliliters[s.Unit]
+	if !ok {
+		return 0
+	}
+	return s.Size * ml / millilitersPerOunce
+}
+
+// PricePerOunce returns the price per US fluid ounce, or 0 if either the
+// size or the price is unknown.
+func (s Serving) PricePerOunce() float64 {
+	ounces := s.Ounces()
+	if ounces == 0 || s.Price == 0 {
+		return 0
+	}
+	return s.Price / ounces
+}
+
+var rServingSize = regexp.MustCompile(`(?i)(\d+(?:[.]\d+)?)\s*(fl\.?\s*oz|oz|ounces?|ml|cl|l|liters?|litres?)\b`)
+
+var unitNames = map[string]string{
+	"oz": "oz", "floz": "oz", "fl.oz": "oz", "ounce": "oz", "ounces": "oz",
+	"ml": "ml", "cl": "cl",
+	"l": "l", "liter": "l", "liters": "l", "litre": "l", "litres": "l",
+}
+
+// namedPourOunces holds the sizes of pours that menus name rather than
+// measure. Snifters, tulips and goblets vary too much to guess.
+var namedPourOunces = map[string]float64{
+	"pint":         16,
+	"half pint":    8,
+	"growler":      64,
+	"gr":           64,
+	"half growler": 32,
+	"howler":       32,
+	"crowler":      32,
+}
+
+var rServingPrice = regexp.MustCompile(`([$£€])?\s*(\d+(?:[.]\d+)?)\s*([A-Z]{3})?`)
+
+var currencyCodes = map[string]string{
+	"$": "USD",
+	"£": "GBP",
+	"€": "EUR",
+}
+
+// ParseServing builds a serving from a menu's pour label ("16oz", "Pint")
+// and price ("$6.50", "4 EUR"). Either may be empty.
+func ParseServing(label, price string) Serving {
+	serving := Serving{Name: strings.TrimSpace(label)}
+	if size := rServingSize.FindStringSubmatch(serving.Name); size != nil {
+		serving.Size, _ = strconv.ParseFloat(size[1], 64)
+		unit := strings.ToLower(strings.Replace(size[2], " ", "", -1))
+		serving.Unit = unitNames[unit]
+	} else if oz, ok := namedPourOunces[strings.ToLower(serving.Name)]; ok {
+		serving.Size, serving.Unit = oz, "oz"
+	}
+
+	if match := rServingPrice.FindStringSubmatch(price); match != nil {
+		serving.Price, _ = strconv.ParseFloat(match[2], 64)
+		if match[1] != "" {
+			serving.Currency = currencyCodes[match[1]]
+		} else {
+			serving.Currency = match[3]
+		}
+	}
+	return serving
+}
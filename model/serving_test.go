@@ -0,0 +1,37 @@
+package model
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseServing(t *testing.T) {
+	tests := []struct {
+		label, price string
+		expected     Serving
+	}{
+		{"16oz", "$6.50", Serving{Name: "16oz", Size: 16, Unit: "oz", Price: 6.5, Currency: "USD"}},
+		{"12 fl oz", "", Serving{Name: "12 fl oz", Size: 12, Unit: "oz"}},
+		{"Pint", "£4.20", Serving{Name: "Pint", Size: 16, Unit: "oz", Price: 4.2, Currency: "GBP"}},
+		{"0.33 l", "5 EUR", Serving{Name: "0.33 l", Size: 0.33, Unit: "l", Price: 5, Currency: "EUR"}},
+		{"Snifter", "$5", Serving{Name: "Snifter", Price: 5, Currency: "USD"}},
+	}
+	for _, test := range tests {
+		if actual := ParseServing(test.label, test.price); actual != test.expected {
+			t.Errorf("ParseServing(%#v, %#v) == %#v, want %#v",
+				test.label, test.price, actual, test.expected)
+		}
+	}
+}
+
+func TestPricePerOunce(t *testing.T) {
+	if ppo := ParseServing("16oz", "$8").PricePerOunce(); ppo != 0.5 {
+		t.Errorf("16oz for $8 PricePerOunce() == %v, want 0.5", ppo)
+	}
+	if ppo := ParseServing("500ml", "$5").PricePerOunce(); math.Abs(ppo-0.2957) > 0.001 {
+		t.Errorf("500ml for $5 PricePerOunce() == %v, want ~0.2957", ppo)
+	}
+	if ppo := ParseServing("Snifter", "$5").PricePerOunce(); ppo != 0 {
+		t.Errorf("unknown size PricePerOunce() == %v, want 0", ppo)
+	}
+}
@@ -0,0 +1,188 @@
+package pdftext
+
+import (
+	"strings"
+
+	"rsc.io/pdf"
+)
+
+type matrix [3][3]float64
+
+var ident = matrix{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+
+func (x matrix) mul(y matrix) matrix {
+	var z matrix
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				z[i][j] += x[i][k] * y[k][j]
+			}
+		}
+	}
+	return z
+}
+
+func translate(tx, ty float64) matrix {
+	return matrix{{1, 0, 0}, {0, 1, 0}, {tx, ty, 1}}
+}
+
+type gstate struct {
+	Tc, Tw, Th, Tl float64
+	Tf             *fontDecoder
+	Tfs            float64
+	Trise          float64
+	Tm, Tlm, CTM   matrix
+}
+
+// PageContent returns the text and rectangles of a page, like
+// pdf.Page.Content, but decodes text with the font's ToUnicode CMap and
+// glyph names (see fontDecoder). Each pdf.Text holds the text of a single
+// character code, which may be several letters for a ligature.
+func PageContent(page pdf.Page) pdf.Content {
+	g := gstate{Th: 1, CTM: ident}
+	decoders := map[string]*fontDecoder{}
+
+	var text []pdf.Text
+	showText := func(raw string) {
+		if g.Tf == nil {
+			return
+		}
+		fontName := g.Tf.font.BaseFont()
+		if i := strings.Index(fontName, "+"); i >= 0 {
+			fontName = fontName[i+1:]
+		}
+		for _, code := range g.Tf.codes(raw) {
+			Trm := matrix{{g.Tfs * g.Th, 0, 0}, {0, g.Tfs, 0}, {0, g.Trise, 1}}.mul(g.Tm).mul(g.CTM)
+			w0 := g.Tf.width(code)
+			decoded := g.Tf.decode(code)
+			if decoded != " " && decoded != "" {
+				text = append(text, pdf.Text{
+					Font:     fontName,
+					FontSize: Trm[0][0],
+					X:        Trm[2][0],
+					Y:        Trm[2][1],
+					W:        w0 / 1000 * Trm[0][0],
+					S:        decoded,
+				})
+			}
+			tx := w0/1000*g.Tfs + g.Tc
+			if code == " " {
+				tx += g.Tw
+			}
+			g.Tm = translate(tx*g.Th, 0).mul(g.Tm)
+		}
+	}
+
+	var rect []pdf.Rect
+	var gstack []gstate
+	pdf.Interpret(page.V.Key("Contents"), func(stk *pdf.Stack, op string) {
+		n := stk.Len()
+		args := make([]pdf.Value, n)
+		for i := n - 1; i >= 0; i-- {
+			args[i] = stk.Pop()
+		}
+		argMatrix := func() (m matrix) {
+			for i := 0; i < 6; i++ {
+				m[i/2][i%2] = args[i].Float64()
+			}
+			m[2][2] = 1
+			return m
+		}
+
+		switch op {
+		case "cm": // concatenate matrix to CTM
+			if len(args) == 6 {
+				g.CTM = argMatrix().mul(g.CTM)
+			}
+		case "re": // append rectangle to path
+			if len(args) == 4 {
+				x, y := args[0].Float64(), args[1].Float64()
+				w, h := args[2].Float64(), args[3].Float64()
+				rect = append(rect, pdf.Rect{Min: pdf.Point{X: x, Y: y}, Max: pdf.Point{X: x + w, Y: y + h}})
+			}
+		case "q": // save graphics state
+			gstack = append(gstack, g)
+		case "Q": // restore graphics state
+			if n := len(gstack) - 1; n >= 0 {
+				g = gstack[n]
+				gstack = gstack[:n]
+			}
+		case "BT": // begin text
+			g.Tm = ident
+			g.Tlm = g.Tm
+		case "T*": // move to start of next line
+			g.Tlm = translate(0, -g.Tl).mul(g.Tlm)
+			g.Tm = g.Tlm
+		case "Tc": // set character spacing
+			if len(args) == 1 {
+				g.Tc = args[0].Float64()
+			}
+		case "TD", "Td": // move text position (and set leading)
+			if len(args) == 2 {
+				if op == "TD" {
+					g.Tl = -args[1].Float64()
+				}
+				g.Tlm = translate(args[0].Float64(), args[1].Float64()).mul(g.Tlm)
+				g.Tm = g.Tlm
+			}
+		case "Tf": // set text font and size
+			if len(args) == 2 {
+				name := args[0].Name()
+				if decoders[name] == nil {
+					decoders[name] = newFontDecoder(page.Font(name))
+				}
+				g.Tf = decoders[name]
+				g.Tfs = args[1].Float64()
+			}
+		case "\"", "'", "Tj": // show text, optionally moving to the next line
+			if op == "\"" && len(args) == 3 {
+				g.Tw = args[0].Float64()
+				g.Tc = args[1].Float64()
+				args = args[2:]
+			}
+			if len(args) != 1 {
+				return
+			}
+			if op != "Tj" {
+				g.Tlm = translate(0, -g.Tl).mul(g.Tlm)
+				g.Tm = g.Tlm
+			}
+			showText(args[0].RawString())
+		case "TJ": // show text with individual glyph positioning
+			if len(args) != 1 {
+				return
+			}
+			v := args[0]
+			for i := 0; i < v.Len(); i++ {
+				x := v.Index(i)
+				if x.Kind() == pdf.String {
+					showText(x.RawString())
+				} else {
+					g.Tm = translate(-x.Float64()/1000*g.Tfs*g.Th, 0).mul(g.Tm)
+				}
+			}
+		case "TL": // set text leading
+			if len(args) == 1 {
+				g.Tl = args[0].Float64()
+			}
+		case "Tm": // set text matrix and line matrix
+			if len(args) == 6 {
+				g.Tm = argMatrix()
+				g.Tlm = g.Tm
+			}
+		case "Ts": // set text rise
+			if len(args) == 1 {
+				g.Trise = args[0].Float64()
+			}
+		case "Tw": // set word spacing
+			if len(args) == 1 {
+				g.Tw = args[0].Float64()
+			}
+		case "Tz": // set horizontal text scaling
+			if len(args) == 1 {
+				g.Th = args[0].Float64() / 100
+			}
+		}
+	})
+	return pdf.Content{Text: text, Rect: rect}
+}
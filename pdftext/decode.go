@@ -0,0 +1,435 @@
+package pdftext
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"rsc.io/pdf"
+)
+
+// fontDecoder converts the character codes of a font to text. rsc.io/pdf
+// ignores a font's ToUnicode CMap when the font also has an encoding
+// dictionary, and doesn't know ligature glyph names such as "f_i", so it
+// turns ligatures into whatever ASCII character shares their code.
+//
+// fontDecoder prefers the ToUnicode CMap, then the glyph names in the
+// encoding's Differences array, and only then falls back to rsc.io/pdf.
+type fontDecoder struct {
+	font        pdf.Font
+	toUnicode   *cmap
+	differences map[int]string
+	fallback    pdf.TextEncoding
+
+	defaultWidth float64
+	cidWidths    map[int]float64
+}
+
+func newFontDecoder(font pdf.Font) *fontDecoder {
+	d := &fontDecoder{
+		font:        font,
+		differences: encodingDifferences(font.V.Key("Encoding")),
+		fallback:    font.Encoder(),
+	}
+	if toUnicode := font.V.Key("ToUnicode"); toUnicode.Kind() == pdf.Stream {
+		d.toUnicode = readCMap(toUnicode)
+	}
+	if descendants := font.V.Key("DescendantFonts"); descendants.Len() > 0 {
+		d.readCIDWidths(descendants.Index(0))
+	}
+	return d
+}
+
+// codes splits raw string bytes into character codes.
+func (d *fontDecoder) codes(raw string) []string {
+	if d.toUnicode != nil {
+		return d.toUnicode.split(raw)
+	}
+	codes := make([]string, len(raw))
+	for i := 0; i < len(raw); i++ {
+		codes[i] = raw[i : i+1]
+	}
+	return codes
+}
+
+func (d *fontDecoder) decode(code string) string {
+	if d.toUnicode != nil {
+		if text, ok := d.toUnicode.lookup(code); ok {
+			return expandLigatures(text)
+		}
+	}
+	if len(code) == 1 {
+		if name, ok := d.differences[int(code[0])]; ok {
+			if text := glyphNameText(name); text != "" {
+				return text
+			}
+		}
+	}
+	return expandLigatures(d.fallback.Decode(code))
+}
+
+// width returns the width of code in glyph space units (1/1000 em).
+func (d *fontDecoder) width(code string) float64 {
+	if d.cidWidths == nil {
+		if len(code) != 1 {
+			return 0
+		}
+		return d.font.Width(int(code[0]))
+	}
+	cid := 0
+	for i := 0; i < len(code); i++ {
+		cid = cid<<8 | int(code[i])
+	}
+	if w, ok := d.cidWidths[cid]; ok {
+		return w
+	}
+	return d.defaultWidth
+}
+
+// readCIDWidths reads the W array of a CID font, which comes in two forms:
+// "c [w1 w2 ...]" and "cFirst cLast w".
+func (d *fontDecoder) readCIDWidths(cidFont pdf.Value) {
+	d.defaultWidth = 1000
+	if dw := cidFont.Key("DW"); dw.Kind() == pdf.Integer || dw.Kind() == pdf.Real {
+		d.defaultWidth = dw.Float64()
+	}
+	d.cidWidths = map[int]float64{}
+	w := cidFont.Key("W")
+	for i := 0; i+1 < w.Len(); {
+		first := int(w.Index(i).Int64())
+		if next := w.Index(i + 1); next.Kind() == pdf.Array {
+			for j := 0; j < next.Len(); j++ {
+				d.cidWidths[first+j] = next.Index(j).Float64()
+			}
+			i += 2
+			continue
+		}
+		if i+2 >= w.Len() {
+			break
+		}
+		last := int(w.Index(i + 1).Int64())
+		width := w.Index(i + 2).Float64()
+		for cid := first; cid <= last; cid++ {
+			d.cidWidths[cid] = width
+		}
+		i += 3
+	}
+}
+
+func encodingDifferences(encoding pdf.Value) map[int]string {
+	differences := map[int]string{}
+	diffs := encoding.Key("Differences")
+	code := -1
+	for i := 0; i < diffs.Len(); i++ {
+		item := diffs.Index(i)
+		switch item.Kind() {
+		case pdf.Integer:
+			code = int(item.Int64())
+		case pdf.Name:
+			if code >= 0 {
+				differences[code] = item.Name()
+				code++
+			}
+		}
+	}
+	return differences
+}
+
+var ligatures = map[rune]string{
+	'ﬀ': "ff",
+	'ﬁ': "fi",
+	'ﬂ': "fl",
+	'ﬃ': "ffi",
+	'ﬄ': "ffl",
+	'ﬅ': "st",
+	'ﬆ': "st",
+}
+
+// expandLigatures replaces Unicode ligature characters with their letters,
+// so that "ﬁ" matches "fi" when searching for beverages.
+func expandLigatures(text string) string {
+	if !strings.ContainsAny(text, "ﬀﬁﬂﬃﬄﬅﬆ") {
+		return text
+	}
+	var buf bytes.Buffer
+	for _, r := range text {
+		if expansion, ok := ligatures[r]; ok {
+			buf.WriteString(expansion)
+		} else {
+			buf.WriteRune(r)
+		}
+	}
+	return buf.String()
+}
+
+// glyphNames covers the glyph names that turn up in the Differences arrays
+// of menu fonts, beyond the single letters handled by glyphNameText.
+var glyphNames = map[string]string{
+	"ff": "ff", "fi": "fi", "fl": "fl", "ffi": "ffi", "ffl": "ffl",
+	"ft": "ft", "st": "st",
+	"space": " ", "exclam": "!", "quotedbl": "\"", "numbersign": "#",
+	"dollar": "$", "percent": "%", "ampersand": "&", "quotesingle": "'",
+	"parenleft": "(", "parenright": ")", "asterisk": "*", "plus": "+",
+	"comma": ",", "hyphen": "-", "period": ".", "slash": "/",
+	"colon": ":", "semicolon": ";", "less": "<", "equal": "=",
+	"greater": ">", "question": "?", "at": "@", "bar": "|",
+	"zero": "0", "one": "1", "two": "2", "three": "3", "four": "4",
+	"five": "5", "six": "6", "seven": "7", "eight": "8", "nine": "9",
+	"quoteleft": "‘", "quoteright": "’", "quotedblleft": "“",
+	"quotedblright": "”", "endash": "–", "emdash": "—", "bullet": "•",
+	"ellipsis": "…", "degree": "°", "sterling": "£", "Euro": "€",
+	"registered": "®", "trademark": "™", "copyright": "©",
+}
+
+// glyphNameText returns the text for a glyph name, including ligature names
+// such as "f_f_i" and "uniXXXX" names. It returns "" for unknown names.
+func glyphNameText(name string) string {
+	if i := strings.Index(name, "."); i > 0 {
+		name = name[:i] // drop variant suffixes such as ".alt"
+	}
+	if text, ok := glyphNames[name]; ok {
+		return text
+	}
+	if len(name) == 1 {
+		return name
+	}
+	if strings.HasPrefix(name, "uni") && len(name) >= 7 && (len(name)-3)%4 == 0 {
+		if text := hexUTF16(name[3:]); text != "" {
+			return expandLigatures(text)
+		}
+	}
+	if strings.HasPrefix(name, "u") && len(name) >= 5 && len(name) <= 7 {
+		if code, err := strconv.ParseUint(name[1:], 16, 32); err == nil {
+			return expandLigatures(string(rune(code)))
+		}
+	}
+	if strings.Contains(name, "_") {
+		var buf bytes.Buffer
+		for _, part := range strings.Split(name, "_") {
+			text := glyphNameText(part)
+			if text == "" {
+				return ""
+			}
+			buf.WriteString(text)
+		}
+		return buf.String()
+	}
+	return ""
+}
+
+func hexUTF16(hexText string) string {
+	raw, err := hex.DecodeString(hexText)
+	if err != nil {
+		return ""
+	}
+	return utf16BE(string(raw))
+}
+
+func utf16BE(raw string) string {
+	units := make([]uint16, 0, len(raw)/2)
+	for i := 0; i+1 < len(raw); i += 2 {
+		units = append(units, uint16(raw[i])<<8|uint16(raw[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+// cmap is a ToUnicode CMap: code space ranges, and the bfchar and bfrange
+// mappings from character codes to UTF-16BE text.
+type cmap struct {
+	space   [][2]string
+	bfchar  map[string]string
+	bfrange []bfrange
+}
+
+type bfrange struct {
+	lo, hi string
+	dst    string   // first destination, incremented across the range
+	dsts   []string // or one destination per code
+}
+
+func (m *cmap) split(raw string) []string {
+	codes := []string{}
+	for len(raw) > 0 {
+		n := m.codeLength(raw)
+		codes = append(codes, raw[:n])
+		raw = raw[n:]
+	}
+	return codes
+}
+
+func (m *cmap) codeLength(raw string) int {
+	for _, space := range m.space {
+		n := len(space[0])
+		if n <= len(raw) && space[0] <= raw[:n] && raw[:n] <= space[1] {
+			return n
+		}
+	}
+	return 1
+}
+
+func (m *cmap) lookup(code string) (string, bool) {
+	if text, ok := m.bfchar[code]; ok {
+		return utf16BE(text), true
+	}
+	for _, r := range m.bfrange {
+		if len(r.lo) != len(code) || code < r.lo || code > r.hi {
+			continue
+		}
+		offset := codeValue(code) - codeValue(r.lo)
+		if r.dsts != nil {
+			if offset < len(r.dsts) {
+				return utf16BE(r.dsts[offset]), true
+			}
+			return "", false
+		}
+		dst := []byte(r.dst)
+		if len(dst) == 0 {
+			return "", false
+		}
+		dst[len(dst)-1] += byte(offset)
+		return utf16BE(string(dst)), true
+	}
+	return "", false
+}
+
+func codeValue(code string) int {
+	value := 0
+	for i := 0; i < len(code); i++ {
+		value = value<<8 | int(code[i])
+	}
+	return value
+}
+
+// cmapToken is a hex string, an array of hex strings, or an operator.
+type cmapToken struct {
+	hex   string
+	array []string
+	op    string
+}
+
+func readCMap(stream pdf.Value) *cmap {
+	rd := stream.Reader()
+	defer rd.Close()
+	data, err := ioutil.ReadAll(rd)
+	if err != nil {
+		return nil
+	}
+
+	m := &cmap{bfchar: map[string]string{}}
+	var operands []cmapToken
+	for _, tok := range tokenizeCMap(data) {
+		if tok.op == "" {
+			operands = append(operands, tok)
+			continue
+		}
+		switch tok.op {
+		case "endcodespacerange":
+			for i := 0; i+1 < len(operands); i += 2 {
+				m.space = append(m.space, [2]string{operands[i].hex, operands[i+1].hex})
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				m.bfchar[operands[i].hex] = operands[i+1].hex
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				m.bfrange = append(m.bfrange, bfrange{
+					lo:   operands[i].hex,
+					hi:   operands[i+1].hex,
+					dst:  operands[i+2].hex,
+					dsts: operands[i+2].array,
+				})
+			}
+		}
+		operands = nil
+	}
+	return m
+}
+
+// tokenizeCMap splits a CMap program into hex strings, arrays of hex
+// strings, and operators, dropping everything else.
+func tokenizeCMap(data []byte) []cmapToken {
+	tokens := []cmapToken{}
+	var array []string
+	inArray := false
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case c == '%':
+			for i < len(data) && data[i] != '\n' && data[i] != '\r' {
+				i++
+			}
+		case c == '<' && i+1 < len(data) && data[i+1] == '<':
+			i += 2
+		case c == '>' && i+1 < len(data) && data[i+1] == '>':
+			i += 2
+		case c == '<':
+			end := bytes.IndexByte(data[i:], '>')
+			if end == -1 {
+				return tokens
+			}
+			hexText := strings.Map(func(r rune) rune {
+				if strings.ContainsRune(" \t\r\n", r) {
+					return -1
+				}
+				return r
+			}, string(data[i+1:i+end]))
+			if len(hexText)%2 == 1 {
+				hexText += "0"
+			}
+			raw, _ := hex.DecodeString(hexText)
+			if inArray {
+				array = append(array, string(raw))
+			} else {
+				tokens = append(tokens, cmapToken{hex: string(raw)})
+			}
+			i += end + 1
+		case c == '[':
+			inArray, array = true, []string{}
+			i++
+		case c == ']':
+			tokens = append(tokens, cmapToken{array: array})
+			inArray = false
+			i++
+		case c == '(':
+			// Literal strings only appear in the CMap header; skip them.
+			depth := 0
+			for ; i < len(data); i++ {
+				if data[i] == '\\' {
+					i++
+				} else if data[i] == '(' {
+					depth++
+				} else if data[i] == ')' {
+					depth--
+					if depth == 0 {
+						i++
+						break
+					}
+				}
+			}
+		case bytes.IndexByte([]byte(" \t\r\n\f\x00"), c) != -1:
+			i++
+		default:
+			start := i
+			for i < len(data) && bytes.IndexByte([]byte(" \t\r\n\f\x00()<>[]{}/%"), data[i]) == -1 {
+				i++
+			}
+			if i == start {
+				i++ // "/" or a brace
+				continue
+			}
+			word := string(data[start:i])
+			if start > 0 && data[start-1] == '/' {
+				continue // a name, not an operator
+			}
+			if _, err := strconv.ParseFloat(word, 64); err == nil {
+				continue
+			}
+			tokens = append(tokens, cmapToken{op: word})
+		}
+	}
+	return tokens
+}
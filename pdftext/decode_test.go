@@ -0,0 +1,69 @@
+package pdftext
+
+import (
+	"reflect"
+	"testing"
+)
+
+func scanTexts(t *testing.T, scanner *Scanner) []string {
+	texts := []string{}
+	for {
+		frag := scanner.NextText()
+		if frag == nil {
+			return texts
+		}
+		texts = append(texts, frag.Text())
+	}
+}
+
+func TestLigatureDecoding(t *testing.T) {
+	scanner, err := NewFileScanner("test/ligatures.pdf")
+	if err != nil {
+		t.Fatalf("Failed to create scanner: %s", err)
+	}
+
+	expected := []string{
+		"Whoa! Refined floral notes",
+		"Hoppy! Office favorite",
+		"Craft Drafts",
+		"Refined",
+	}
+	if texts := scanTexts(t, scanner); !reflect.DeepEqual(texts, expected) {
+		t.Errorf("NextText() texts == %#v, want %#v", texts, expected)
+	}
+}
+
+func TestLigatureRepairOptIn(t *testing.T) {
+	scanner, err := NewFileScanner("test/ligatures.pdf")
+	if err != nil {
+		t.Fatalf("Failed to create scanner: %s", err)
+	}
+	scanner.LigatureRepair = true
+
+	texts := scanTexts(t, scanner)
+	if len(texts) == 0 || texts[0] != "Whoaft Refined floral notes" {
+		t.Errorf("NextText() texts == %#v, want repaired first fragment", texts)
+	}
+}
+
+func TestGlyphNameText(t *testing.T) {
+	tests := []struct {
+		name, expected string
+	}{
+		{"a", "a"},
+		{"fi", "fi"},
+		{"f_f_i", "ffi"},
+		{"f_t", "ft"},
+		{"uniFB02", "fl"},
+		{"uni00660074", "ft"},
+		{"u1F37A", "🍺"},
+		{"exclam", "!"},
+		{"quoteright.alt", "’"},
+		{"g123", ""},
+	}
+	for _, test := range tests {
+		if actual := glyphNameText(test.name); actual != test.expected {
+			t.Errorf("glyphNameText(%#v) == %#v, want %#v", test.name, actual, test.expected)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package pdftext
+
+import (
+	"bytes"
+	"math"
+	"sort"
+	"strings"
+
+	"rsc.io/pdf"
+)
+
+// Layout controls how page text is clustered into rows and cells by
+// position, rather than by the order it appears in the PDF stream.
+type Layout struct {
+	// LineTolerance is the largest vertical offset between two pieces of text
+	// on the same line, as a fraction of the font size.
+	LineTolerance float64
+
+	// CellGap is the smallest horizontal gap between two cells on a line, as
+	// a fraction of the font size. Narrower gaps are treated as word spaces,
+	// unless a ruled vertical line runs through them.
+	CellGap float64
+
+	// ColumnGutter is the narrowest band of empty space, in points, that
+	// splits a page into side-by-side text columns. Zero disables column
+	// detection, which is what tables want.
+	ColumnGutter float64
+}
+
+// TableLayout keeps each line intact across the page, splitting it into
+// cells at wide gaps and ruled lines.
+var TableLayout = Layout{LineTolerance: 0.3, CellGap: 1.0}
+
+// ColumnLayout additionally splits pages into text columns, so that
+// multi-column menus read one column at a time.
+var ColumnLayout = Layout{LineTolerance: 0.3, CellGap: 1.0, ColumnGutter: 24}
+
+// maxRuleWidth is the widest rectangle treated as a ruled vertical line.
+const maxRuleWidth = 2.0
+
+// A Cell is a run of text on one line, separated from its neighbours by a
+// cell gap or a ruled line.
+type Cell struct {
+	Font
+	LX, RX, Y float64
+	Text      string
+}
+
+// A Row is one line of cells, in left-to-right order.
+type Row struct {
+	Page   int // 1-based page number
+	Column int // 0-based column number within the page
+	Y      float64
+	Cells  []Cell
+}
+
+// Texts returns the text of each cell in the row.
+func (r Row) Texts() []string {
+	texts := make([]string, len(r.Cells))
+	for i, cell := range r.Cells {
+		texts[i] = cell.Text
+	}
+	return texts
+}
+
+func (r Row) String() string {
+	return strings.Join(r.Texts(), " | ")
+}
+
+// Rows returns the rows of every page of the PDF, page by page, then column
+// by column, top to bottom. Rows does not affect the position of NextText.
+func (r *Scanner) Rows(layout Layout) []Row {
+	rows := []Row{}
+	for i, page := range r.Pages {
+		rows = append(rows, PageRows(i+1, page, layout)...)
+	}
+	return rows
+}
+
+// PageRows clusters the text of a single page into rows of cells.
+func PageRows(page int, content pdf.Content, layout Layout) []Row {
+	glyphs := make([]pdf.Text, 0, len(content.Text))
+	for _, glyph := range content.Text {
+		if strings.TrimSpace(glyph.S) != "" {
+			glyphs = append(glyphs, glyph)
+		}
+	}
+	rules := verticalRules(content.Rect)
+
+	rows := []Row{}
+	for column, columnGlyphs := range splitColumns(glyphs, layout.ColumnGutter) {
+		for _, line := range splitLines(columnGlyphs, layout.LineTolerance) {
+			rows = append(rows, Row{
+				Page:   page,
+				Column: column,
+				Y:      line[0].Y,
+				Cells:  splitCells(line, rules, layout.CellGap),
+			})
+		}
+	}
+	return rows
+}
+
+func verticalRules(rects []pdf.Rect) []pdf.Rect {
+	rules := []pdf.Rect{}
+	for _, rect := range rects {
+		width := math.Abs(rect.Max.X - rect.Min.X)
+		height := math.Abs(rect.Max.Y - rect.Min.Y)
+		if width <= maxRuleWidth && height > width {
+			rules = append(rules, rect)
+		}
+	}
+	return rules
+}
+
+// ruleBetween reports whether a ruled line at height y runs between the
+// horizontal positions lx and rx.
+func ruleBetween(rules []pdf.Rect, lx, rx, y float64) bool {
+	for _, rule := range rules {
+		x := (rule.Min.X + rule.Max.X) / 2
+		minY, maxY := math.Min(rule.Min.Y, rule.Max.Y), math.Max(rule.Min.Y, rule.Max.Y)
+		if x >= lx && x <= rx && y >= minY && y <= maxY {
+			return true
+		}
+	}
+	return false
+}
+
+// splitColumns divides glyphs at every vertical band of empty space at
+// least gutter points wide, returning the glyphs of each column from left to
+// right.
+func splitColumns(glyphs []pdf.Text, gutter float64) [][]pdf.Text {
+	if gutter <= 0 || len(glyphs) == 0 {
+		return [][]pdf.Text{glyphs}
+	}
+
+	spans := make([][2]float64, len(glyphs))
+	for i, glyph := range glyphs {
+		spans[i] = [2]float64{glyph.X, glyph.X + glyph.W}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+
+	boundaries := []float64{}
+	right := spans[0][1]
+	for _, span := range spans[1:] {
+		if span[0]-right >= gutter {
+			boundaries = append(boundaries, (span[0]+right)/2)
+		}
+		right = math.Max(right, span[1])
+	}
+
+	columns := make([][]pdf.Text, len(boundaries)+1)
+	for _, glyph := range glyphs {
+		column := sort.SearchFloat64s(boundaries, glyph.X)
+		columns[column] = append(columns[column], glyph)
+	}
+	return columns
+}
+
+// splitLines groups glyphs into lines, top to bottom, each sorted left to
+// right.
+func splitLines(glyphs []pdf.Text, tolerance float64) [][]pdf.Text {
+	sorted := make([]pdf.Text, len(glyphs))
+	copy(sorted, glyphs)
+	sort.Stable(pdf.TextVertical(sorted))
+
+	lines := [][]pdf.Text{}
+	for _, glyph := range sorted {
+		n := len(lines)
+		if n > 0 {
+			first := lines[n-1][0]
+			if math.Abs(first.Y-glyph.Y) <= tolerance*math.Max(first.FontSize, glyph.FontSize) {
+				lines[n-1] = append(lines[n-1], glyph)
+				continue
+			}
+		}
+		lines = append(lines, []pdf.Text{glyph})
+	}
+	for _, line := range lines {
+		sort.Stable(pdf.TextHorizontal(line))
+	}
+	return lines
+}
+
+func splitCells(line []pdf.Text, rules []pdf.Rect, cellGap float64) []Cell {
+	cells := []Cell{}
+	var cell *Cell
+	var buf bytes.Buffer
+	flush := func() {
+		if cell != nil {
+			cell.Text = buf.String()
+			cells = append(cells, *cell)
+		}
+		buf.Reset()
+	}
+
+	for _, glyph := range line {
+		if cell != nil {
+			gap := glyph.X - cell.RX
+			size := math.Max(cell.Size, glyph.FontSize)
+			if gap >= cellGap*size || ruleBetween(rules, cell.RX, glyph.X, glyph.Y) {
+				flush()
+				cell = nil
+			} else if gap >= SpaceWidthPoints {
+				buf.WriteByte(' ')
+			}
+		}
+		if cell == nil {
+			cell = &Cell{Font: FragFont(&glyph), LX: glyph.X, Y: glyph.Y}
+		}
+		buf.WriteString(glyph.S)
+		cell.RX = math.Max(cell.RX, glyph.X+glyph.W)
+	}
+	flush()
+	return cells
+}
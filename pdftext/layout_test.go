@@ -0,0 +1,80 @@
+package pdftext
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestColumnLayoutRows(t *testing.T) {
+	scanner, err := NewFileScanner("test/columns.pdf")
+	if err != nil {
+		t.Fatalf("Failed to create scanner: %s", err)
+	}
+
+	var texts []string
+	for _, row := range scanner.Rows(ColumnLayout) {
+		if row.Page == 1 {
+			texts = append(texts, row.String())
+		}
+	}
+	expected := []string{
+		"GUEST DRAFTS",
+		"Jailbreak Desserted",
+		"Chocolate Coconut Porter",
+		"Easy sipping body.",
+		"BOTTLES",
+		"Evil Twin Even More Jesus",
+		"Imperial Stout",
+		"Big, roasty and sweet.",
+	}
+	if !reflect.DeepEqual(texts, expected) {
+		t.Errorf("page 1 rows == %#v, want %#v", texts, expected)
+	}
+}
+
+func TestTableLayoutRows(t *testing.T) {
+	scanner, err := NewFileScanner("test/columns.pdf")
+	if err != nil {
+		t.Fatalf("Failed to create scanner: %s", err)
+	}
+
+	var rows [][]string
+	for _, row := range scanner.Rows(TableLayout) {
+		if row.Page == 2 {
+			rows = append(rows, row.Texts())
+		}
+	}
+	expected := [][]string{
+		{"Beer", "Style", "ABV", "Price"},
+		{"Racer 5", "American IPA", "7.5%", "$6"},
+		{"Pumking", "Imperial Pumpkin Ale Spiced Ha", "8.6%", "$7"},
+		{"Hopsation", "Cider", "6.9%", "$5.50"},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("page 2 rows == %#v, want %#v", rows, expected)
+	}
+
+	for _, row := range scanner.Rows(TableLayout) {
+		if row.Page == 1 && len(row.Cells) != 2 {
+			t.Errorf("page 1 row %v: want two cells, one per column", row.Texts())
+		}
+	}
+}
+
+func TestTableLayoutCellPositions(t *testing.T) {
+	scanner, err := NewFileScanner("test/columns.pdf")
+	if err != nil {
+		t.Fatalf("Failed to create scanner: %s", err)
+	}
+	rows := scanner.Rows(TableLayout)
+	for _, row := range rows {
+		if row.Page != 2 || row.Cells[0].Text != "Racer 5" {
+			continue
+		}
+		if row.Cells[2].LX != 374 || row.Cells[2].Size != 10 {
+			t.Errorf("ABV cell == %#v, want LX=374, Size=10", row.Cells[2])
+		}
+		return
+	}
+	t.Errorf("no Racer 5 row in %v", rows)
+}
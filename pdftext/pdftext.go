@@ -27,7 +27,10 @@ func FragFont(frag *pdf.Text) Font {
 var rFiLigatureRepair = regexp.MustCompile(`!([a-z])`)
 var rFtLigatureRepair = regexp.MustCompile(`([aeiou])!( |$)`)
 
-// The "fi" ligature gets converted to "!" by rsc.io/pdf. This is an evil hack
+// repairPDFText guesses that "!" stands for an "fi" or "ft" ligature. It
+// corrupts real exclamation marks, so it is only applied when a Scanner's
+// LigatureRepair is set, for PDFs whose fonts carry neither a ToUnicode CMap
+// nor meaningful glyph names.
 func repairPDFText(text string) string {
 	return rFtLigatureRepair.ReplaceAllString(
 		rFiLigatureRepair.ReplaceAllString(text, "fi$1"),
@@ -36,12 +39,20 @@ func repairPDFText(text string) string {
 
 type Fragment struct {
 	Font
+	Page      int // 1-based page number
 	LX, RX, Y float64
 	textBuf   *bytes.Buffer
+	repair    bool
 }
 
-func (t *Fragment) Text() string { return repairPDFText(t.textBuf.String()) }
-func (t *Fragment) Empty() bool  { return t.Font.Empty() && t.textBuf.Len() == 0 }
+func (t *Fragment) Text() string {
+	if t.repair {
+		return repairPDFText(t.textBuf.String())
+	}
+	return t.textBuf.String()
+}
+
+func (t *Fragment) Empty() bool { return t.Font.Empty() && t.textBuf.Len() == 0 }
 
 func (t *Fragment) mergeable(frag *pdf.Text) bool {
 	if t.Empty() {
@@ -94,6 +105,10 @@ type Scanner struct {
 	page, textIndex int
 	currentPage     pdf.Content
 	Pages           []pdf.Content
+
+	// LigatureRepair enables the regex guesses of repairPDFText for fragment
+	// text, for PDFs that can't be decoded properly.
+	LigatureRepair bool
 }
 
 // NewFileScanner creates a new PDF scanner from a file. Note that the entire
@@ -124,7 +139,7 @@ func NewScanner(pdfr *pdf.Reader) *Scanner {
 	pageCount := pdfr.NumPage()
 	scanner.Pages = make([]pdf.Content, pageCount)
 	for i := 1; i <= pageCount; i++ {
-		scanner.Pages[i-1] = pdfr.Page(i).Content()
+		scanner.Pages[i-1] = PageContent(pdfr.Page(i))
 	}
 	if pageCount > 0 {
 		scanner.currentPage = scanner.Pages[0]
@@ -166,7 +181,7 @@ func (r *Scanner) rewind() {
 // NextText returns the next continuous fragment of text from the underlying PDF
 // data, or nil if all fragments have been read.
 func (r *Scanner) NextText() *Fragment {
-	var pending = &Fragment{textBuf: &bytes.Buffer{}}
+	var pending = &Fragment{textBuf: &bytes.Buffer{}, repair: r.LigatureRepair}
 	result := func() *Fragment {
 		if pending.Empty() {
 			return nil
@@ -178,6 +193,9 @@ func (r *Scanner) NextText() *Fragment {
 		if frag == nil {
 			return result()
 		}
+		if pending.Empty() {
+			pending.Page = r.page + 1
+		}
 		if !pending.merge(frag) {
 			r.rewind()
 			return result()
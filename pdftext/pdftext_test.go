@@ -84,6 +84,10 @@ func TestNextText(t *testing.T) {
 		}
 	}
 
+	if len(scannedFragments) > 0 && scannedFragments[0].Page != 1 {
+		t.Errorf("NextText()#0.Page == %d, want 1", scannedFragments[0].Page)
+	}
+
 	for _, see := range mustSee {
 		if _, ok := seenText[see]; !ok {
 			t.Errorf("expected to see %#v, but did not find it", see)
@@ -2,8 +2,12 @@ package mongorepo
 
 import (
 	"github.com/bevly/bevly/model"
+	"github.com/bevly/bevly/text"
+	"gopkg.in/mgo.v2/bson"
 
 	"encoding/hex"
+	"sort"
+	"time"
 )
 
 func repoBeverageModels(repoBevs []repoBeverage) []model.Beverage {
@@ -18,17 +22,33 @@ func repoBeverageModel(repoBev *repoBeverage) model.Beverage {
 	bev := model.CreateBeverage(repoBev.DisplayName)
 	bev.SetID(hex.EncodeToString([]byte(repoBev.ID)))
 	bev.SetType(repoBev.BevType)
+	bev.SetCategory(repoBev.Category)
 	bev.SetName(repoBev.Name)
 	bev.SetDescription(repoBev.Description)
 	bev.SetBrewer(repoBev.Brewer)
+	bev.SetBreweryID(repoBev.BreweryID)
 	bev.SetLink(repoBev.Link)
+	bev.SetImage(repoBev.Image)
+	if repoBev.Image == "" {
+		// Images were once stored as an attribute:
+		bev.SetImage(repoBev.Attributes["img"])
+	}
 	bev.SetAbv(repoBev.Abv)
 	bev.SetSyncTime(repoBev.SyncTime)
 	bev.SetAttributes(repoBev.Attributes)
 	bev.SetAccuracyScore(repoBev.AccuracyScore)
+	for field, prov := range repoBev.Provenance {
+		bev.SetProvenance(field, model.Provenance{
+			Source:    prov.Source,
+			Score:     prov.Score,
+			FetchTime: prov.FetchTime,
+		})
+	}
 	for _, rating := range repoBev.Ratings {
 		bev.AddRating(model.CreateRating(rating.Source, rating.PercentageRating))
 	}
+	setModelCandidates(bev, repoBev.Candidates)
+	model.AdoptLegacyScore(bev)
 	return bev
 }
 
@@ -38,8 +58,11 @@ func beverageModelToRepo(bev model.Beverage) *repoBeverage {
 	repoBev.Name = bev.Name()
 	repoBev.Description = bev.Description()
 	repoBev.BevType = bev.Type()
+	repoBev.Category = bev.Category()
 	repoBev.Brewer = bev.Brewer()
+	repoBev.BreweryID = bev.BreweryID()
 	repoBev.Link = bev.Link()
+	repoBev.Image = bev.Image()
 	repoBev.Abv = bev.Abv()
 	repoBev.Attributes = bev.Attributes()
 	repoBev.SyncTime = bev.SyncTime()
@@ -52,10 +75,143 @@ func beverageModelToRepo(bev model.Beverage) *repoBeverage {
 				PercentageRating: rating.PercentageRating(),
 			})
 	}
+	for field, prov := range bev.Provenances() {
+		setRepoProvenance(repoBev, field, prov)
+	}
+	setRepoCandidates(repoBev, bev.ProfileCandidates())
 	return repoBev
 }
 
+func setModelCandidates(bev model.Beverage, repoCandidates []repoCandidate) {
+	sources := []string{}
+	bySource := map[string][]model.ProfileCandidate{}
+	for _, c := range repoCandidates {
+		if _, seen := bySource[c.Source]; !seen {
+			sources = append(sources, c.Source)
+		}
+		bySource[c.Source] = append(bySource[c.Source], model.ProfileCandidate{
+			Source:      c.Source,
+			URL:         c.URL,
+			Title:       c.Title,
+			Confidence:  c.Confidence,
+			NameScore:   c.NameScore,
+			BrewerScore: c.BrewerScore,
+			AbvScore:    c.AbvScore,
+			Abv:         c.Abv,
+			Explanation: c.Explanation,
+			Chosen:      c.Chosen,
+			Pending:     c.Pending,
+			Rejected:    c.Rejected,
+		})
+	}
+	for _, source := range sources {
+		bev.SetProfileCandidates(source, bySource[source])
+	}
+}
+
+// setRepoCandidates replaces the stored candidates of each source that has
+// candidates, keeping those of other sources.
+func setRepoCandidates(repoBev *repoBeverage, candidates []model.ProfileCandidate) {
+	if len(candidates) == 0 {
+		return
+	}
+	replaced := map[string]bool{}
+	for _, c := range candidates {
+		replaced[c.Source] = true
+	}
+	kept := []repoCandidate{}
+	for _, c := range repoBev.Candidates {
+		if !replaced[c.Source] {
+			kept = append(kept, c)
+		}
+	}
+	for _, c := range candidates {
+		kept = append(kept, repoCandidate{
+			Source:      c.Source,
+			URL:         c.URL,
+			Title:       c.Title,
+			Confidence:  c.Confidence,
+			NameScore:   c.NameScore,
+			BrewerScore: c.BrewerScore,
+			AbvScore:    c.AbvScore,
+			Abv:         c.Abv,
+			Explanation: c.Explanation,
+			Chosen:      c.Chosen,
+			Pending:     c.Pending,
+			Rejected:    c.Rejected,
+		})
+	}
+	repoBev.Candidates = kept
+}
+
+// setRepoProvenance records that prov set field, or forgets the field's
+// provenance if prov is empty.
+func setRepoProvenance(repoBev *repoBeverage, field string, prov model.Provenance) {
+	if prov.Empty() {
+		delete(repoBev.Provenance, field)
+		return
+	}
+	if repoBev.Provenance == nil {
+		repoBev.Provenance = map[string]repoProvenance{}
+	}
+	repoBev.Provenance[field] = repoProvenance{
+		Source:    prov.Source,
+		Score:     prov.Score,
+		FetchTime: prov.FetchTime,
+	}
+}
+
+// repoFieldScore is model.FieldScore for a stored beverage.
+func repoFieldScore(repoBev *repoBeverage, field string) int {
+	if prov, ok := repoBev.Provenance[field]; ok && prov.Source != "" {
+		return prov.Score
+	}
+	return model.MenuScore
+}
+
+// adoptRepoLegacyScore is model.AdoptLegacyScore for a stored beverage.
+func adoptRepoLegacyScore(repoBev *repoBeverage) {
+	if repoBev.AccuracyScore == 0 || len(repoBev.Provenance) > 0 {
+		return
+	}
+	for field, prov := range repoBeverageModel(repoBev).Provenances() {
+		setRepoProvenance(repoBev, field, prov)
+	}
+}
+
+func repoServings(servings []model.Serving) []repoServing {
+	var result []repoServing
+	for _, serving := range servings {
+		result = append(result, repoServing{
+			Name:     serving.Name,
+			Size:     serving.Size,
+			Unit:     serving.Unit,
+			Price:    serving.Price,
+			Currency: serving.Currency,
+		})
+	}
+	return result
+}
+
+func servingModels(repoServings []repoServing) []model.Serving {
+	var result []model.Serving
+	for _, serving := range repoServings {
+		result = append(result, model.Serving{
+			Name:     serving.Name,
+			Size:     serving.Size,
+			Unit:     serving.Unit,
+			Price:    serving.Price,
+			Currency: serving.Currency,
+		})
+	}
+	return result
+}
+
+// updateRepoBev merges bev into repoBev field by field: a stored field is
+// replaced when the new value's source scores at least as high as the source
+// of the stored value.
 func updateRepoBev(repoBev *repoBeverage, bev model.Beverage) {
+	adoptRepoLegacyScore(repoBev)
 	overwrite := bev.AccuracyScore() >= repoBev.AccuracyScore
 
 	set := func(oldVal *string, newVal string) {
@@ -63,17 +219,33 @@ func updateRepoBev(repoBev *repoBeverage, bev model.Beverage) {
 			*oldVal = newVal
 		}
 	}
+	setField := func(field string, oldVal *string) {
+		newVal := model.FieldText(bev, field)
+		if newVal == "" || (*oldVal != "" &&
+			model.FieldScore(bev, field) < repoFieldScore(repoBev, field)) {
+			return
+		}
+		*oldVal = newVal
+		setRepoProvenance(repoBev, field, bev.Provenance(field))
+	}
 
 	if !bev.SyncTime().IsZero() {
 		repoBev.SyncTime = bev.SyncTime()
 	}
-	set(&repoBev.BevType, bev.Type())
-	set(&repoBev.Name, bev.Name())
-	set(&repoBev.Description, bev.Description())
-	set(&repoBev.Brewer, bev.Brewer())
-	set(&repoBev.Link, bev.Link())
-	if bev.Abv() > 0.0 && (overwrite || repoBev.Abv == 0.0) {
+	setField(model.FieldType, &repoBev.BevType)
+	set(&repoBev.Category, bev.Category())
+	setField(model.FieldName, &repoBev.Name)
+	setField(model.FieldDescription, &repoBev.Description)
+	setField(model.FieldBrewer, &repoBev.Brewer)
+	setField(model.FieldLink, &repoBev.Link)
+	setField(model.FieldImage, &repoBev.Image)
+	if bev.BreweryID() != "" {
+		repoBev.BreweryID = bev.BreweryID()
+	}
+	if bev.Abv() > 0.0 && (repoBev.Abv == 0.0 ||
+		model.FieldScore(bev, model.FieldAbv) >= repoFieldScore(repoBev, model.FieldAbv)) {
 		repoBev.Abv = bev.Abv()
+		setRepoProvenance(repoBev, model.FieldAbv, bev.Provenance(model.FieldAbv))
 	}
 	for _, rating := range bev.Ratings() {
 		addRepoBevRating(repoBev, rating)
@@ -89,6 +261,138 @@ func updateRepoBev(repoBev *repoBeverage, bev model.Beverage) {
 	if bev.AccuracyScore() > repoBev.AccuracyScore {
 		repoBev.AccuracyScore = bev.AccuracyScore()
 	}
+	setRepoCandidates(repoBev, bev.ProfileCandidates())
+}
+
+// canonicalView shows a merged duplicate as its canonical beverage, keeping
+// the name the duplicate's menus list. canonical may be nil.
+func canonicalView(duplicate, canonical *repoBeverage) *repoBeverage {
+	if canonical == nil || canonical.ID == duplicate.ID {
+		return duplicate
+	}
+	view := *canonical
+	view.DisplayName = duplicate.DisplayName
+	return &view
+}
+
+// mergeRepoBev folds a duplicate into its canonical beverage. Fields merge by
+// provenance as in updateRepoBev, but the canonical beverage keeps its own
+// ratings, attributes, brewery and profile candidates, taking only those it
+// lacks.
+func mergeRepoBev(canonical, duplicate *repoBeverage) {
+	dup := repoBeverageModel(duplicate)
+	dup.SetSyncTime(time.Time{})
+	if canonical.BreweryID != "" {
+		dup.SetBreweryID("")
+	}
+	dup.ClearRatings()
+	for _, rating := range duplicate.Ratings {
+		if findRepoBevRating(canonical, rating.Source) == nil {
+			dup.AddRating(model.CreateRating(rating.Source, rating.PercentageRating))
+		}
+	}
+	attributes := map[string]string{}
+	for name, value := range duplicate.Attributes {
+		if _, exists := canonical.Attributes[name]; !exists {
+			attributes[name] = value
+		}
+	}
+	dup.SetAttributes(attributes)
+	for _, c := range canonical.Candidates {
+		dup.SetProfileCandidates(c.Source, nil)
+	}
+	updateRepoBev(canonical, dup)
+}
+
+// applyRepoOverride applies override to a stored beverage. Values,
+// ratings and attributes from sources it blocks are discarded, staff values
+// it no longer sets lose their staff provenance, and the beverage is queued
+// to resync with the override.
+func applyRepoOverride(repoBev *repoBeverage, override *model.Override) {
+	for field, prov := range repoBev.Provenance {
+		switch {
+		case override.Blocks(prov.Source):
+			clearRepoField(repoBev, field)
+			delete(repoBev.Provenance, field)
+		case prov.Source == model.OverrideSource && override.Fields[field] == "":
+			delete(repoBev.Provenance, field)
+		}
+	}
+	for _, source := range override.Blocked {
+		clearRepoSourceData(repoBev, model.SourceDataOf(source))
+	}
+	bev := model.CreateBeverage(repoBev.DisplayName)
+	model.ApplyOverride(bev, override)
+	updateRepoBev(repoBev, bev)
+	repoBev.SyncTime = time.Time{}
+}
+
+func clearRepoField(repoBev *repoBeverage, field string) {
+	switch field {
+	case model.FieldName:
+		repoBev.Name = ""
+	case model.FieldBrewer:
+		repoBev.Brewer = ""
+	case model.FieldType:
+		repoBev.BevType = ""
+	case model.FieldAbv:
+		repoBev.Abv = 0.0
+	case model.FieldDescription:
+		repoBev.Description = ""
+	case model.FieldLink:
+		repoBev.Link = ""
+	case model.FieldImage:
+		repoBev.Image = ""
+	}
+}
+
+// clearRepoSourceData discards a source's ratings and attributes.
+func clearRepoSourceData(repoBev *repoBeverage, data model.SourceData) {
+	ratings := []repoRating{}
+	for _, rating := range repoBev.Ratings {
+		if !containsString(data.Ratings, rating.Source) {
+			ratings = append(ratings, rating)
+		}
+	}
+	repoBev.Ratings = ratings
+	for _, attribute := range data.Attributes {
+		delete(repoBev.Attributes, attribute)
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func repoOverrideModel(repoOver *repoOverride) *model.Override {
+	return &model.Override{
+		BeverageID:       repoOver.ID.Hex(),
+		Fields:           repoOver.Fields,
+		ProfileURLs:      repoOver.ProfileURLs,
+		Blocked:          repoOver.Blocked,
+		RejectedProfiles: repoOver.RejectedProfiles,
+		NotDuplicates:    repoOver.NotDuplicates,
+		Note:             repoOver.Note,
+		UpdatedAt:        repoOver.UpdatedAt,
+	}
+}
+
+func overrideModelToRepo(override *model.Override) *repoOverride {
+	return &repoOverride{
+		ID:               bson.ObjectIdHex(override.BeverageID),
+		Fields:           override.Fields,
+		ProfileURLs:      override.ProfileURLs,
+		Blocked:          override.Blocked,
+		RejectedProfiles: override.RejectedProfiles,
+		NotDuplicates:    override.NotDuplicates,
+		Note:             override.Note,
+		UpdatedAt:        override.UpdatedAt,
+	}
 }
 
 func addRepoBevRating(repoBev *repoBeverage, rating model.Rating) {
@@ -112,3 +416,124 @@ func findRepoBevRating(repoBev *repoBeverage, source string) *repoRating {
 	}
 	return nil
 }
+
+// menuEntries records the menu placement of each beverage, numbering the
+// beverages in menu order where the fetcher didn't. beverageIDs must line up
+// with beverages.
+func menuEntries(beverages []model.Beverage, beverageIDs []bson.ObjectId) []repoMenuEntry {
+	entries := make([]repoMenuEntry, 0, len(beverageIDs))
+	for i, id := range beverageIDs {
+		bev := beverages[i]
+		order := bev.MenuOrder()
+		if order == 0 {
+			order = i + 1
+		}
+		entries = append(entries, repoMenuEntry{
+			BeverageID: id,
+			Section:    bev.MenuSection(),
+			Order:      order,
+			Tap:        bev.Tap(),
+			Servings:   repoServings(bev.Servings()),
+		})
+	}
+	return entries
+}
+
+// menuBeverageModels converts a provider's beverages to models in menu
+// order, with their menu placement and servings set, showing merged
+// duplicates as their canonical beverages. Beverages missing from the menu
+// entries (saved before entries were recorded) follow, in lookup order.
+func menuBeverageModels(menu []repoMenuEntry, repoBevs []repoBeverage, canonicals map[bson.ObjectId]*repoBeverage) []model.Beverage {
+	entries := map[bson.ObjectId]repoMenuEntry{}
+	for _, entry := range menu {
+		entries[entry.BeverageID] = entry
+	}
+
+	result := make([]model.Beverage, len(repoBevs))
+	for i := range repoBevs {
+		bev := repoBeverageModel(canonicalView(&repoBevs[i], canonicals[repoBevs[i].CanonicalID]))
+		if entry, ok := entries[repoBevs[i].ID]; ok {
+			bev.SetMenuSection(entry.Section)
+			bev.SetMenuOrder(entry.Order)
+			bev.SetTap(entry.Tap)
+			bev.SetServings(servingModels(entry.Servings))
+		}
+		result[i] = bev
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		oi, oj := result[i].MenuOrder(), result[j].MenuOrder()
+		return oi != 0 && (oj == 0 || oi < oj)
+	})
+	return result
+}
+
+func repoBreweryModels(repoBrews []repoBrewery) []model.Brewery {
+	result := make([]model.Brewery, len(repoBrews))
+	for i := range repoBrews {
+		result[i] = repoBreweryModel(&repoBrews[i])
+	}
+	return result
+}
+
+func repoBreweryModel(repoBrew *repoBrewery) model.Brewery {
+	brewery := model.CreateBrewery(repoBrew.Name)
+	brewery.SetID(repoBrew.ID.Hex())
+	brewery.SetAliases(repoBrew.Aliases)
+	brewery.SetLocation(repoBrew.Location)
+	brewery.SetLinks(repoBrew.Links)
+	if details := repoBrew.Details; details != nil {
+		brewery.SetDetails(model.BreweryDetails{
+			City:      details.City,
+			State:     details.State,
+			Country:   details.Country,
+			Website:   details.Website,
+			Type:      details.Type,
+			Latitude:  details.Latitude,
+			Longitude: details.Longitude,
+			Source:    details.Source,
+			FetchTime: details.FetchTime,
+		})
+	}
+	return brewery
+}
+
+func breweryModelToRepo(brewery model.Brewery) *repoBrewery {
+	repoBrew := &repoBrewery{
+		Name:     brewery.Name(),
+		Aliases:  brewery.Aliases(),
+		Location: brewery.Location(),
+		Links:    brewery.Links(),
+	}
+	if bson.IsObjectIdHex(brewery.ID()) {
+		repoBrew.ID = bson.ObjectIdHex(brewery.ID())
+	}
+	if details := brewery.Details(); details.Fetched() {
+		repoBrew.Details = &repoBreweryDetails{
+			City:      details.City,
+			State:     details.State,
+			Country:   details.Country,
+			Website:   details.Website,
+			Type:      details.Type,
+			Latitude:  details.Latitude,
+			Longitude: details.Longitude,
+			Source:    details.Source,
+			FetchTime: details.FetchTime,
+		}
+	}
+	keys := map[string]bool{}
+	for _, name := range append([]string{brewery.Name()}, brewery.Aliases()...) {
+		if key := text.BreweryKey(name); key != "" && !keys[key] {
+			keys[key] = true
+			repoBrew.Keys = append(repoBrew.Keys, key)
+		}
+	}
+	return repoBrew
+}
+
+func repoProviderModel(provider *repoProvider) model.MenuProvider {
+	prov := model.CreateMenuProvider(provider.ProviderID, provider.Name, provider.URL, provider.MenuFormat)
+	for name, value := range provider.Options {
+		prov.SetOption(name, value)
+	}
+	return prov
+}
@@ -6,9 +6,11 @@ import (
 	"sync"
 	"time"
 
+	"github.com/bevly/bevly/fetch/metadata/ratebeer"
 	"github.com/bevly/bevly/model"
 	"github.com/bevly/bevly/policy"
 	"github.com/bevly/bevly/repository"
+	"github.com/bevly/bevly/text"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 )
@@ -23,17 +25,35 @@ type mongoRepo struct {
 	db          *mgo.Database
 	providers   *mgo.Collection
 	beverages   *mgo.Collection
+	breweries   *mgo.Collection
+	overrides   *mgo.Collection
 	initialized bool
 	mutex       sync.Mutex
 }
 
 type repoProvider struct {
-	ID          bson.ObjectId   `bson:"_id"`
-	ProviderID  string          `bson:"providerId"`
-	Name        string          `bson:"name"`
-	URL         string          `bson:"url"`
-	MenuFormat  string          `bson:"menuFormat"`
-	BeverageIDs []bson.ObjectId `bson:"beverageIds"`
+	ID          bson.ObjectId     `bson:"_id"`
+	ProviderID  string            `bson:"providerId"`
+	Name        string            `bson:"name"`
+	URL         string            `bson:"url"`
+	MenuFormat  string            `bson:"menuFormat"`
+	Options     map[string]string `bson:"options"`
+	BeverageIDs []bson.ObjectId   `bson:"beverageIds"`
+	Menu        []repoMenuEntry   `bson:"menu"`
+	// Removed providers are kept, without their menus, so that the
+	// default providers are not seeded again.
+	Removed bool `bson:"removed,omitempty"`
+}
+
+// repoMenuEntry places a beverage on a provider's menu. Beverages are shared
+// between providers, so their menu placement and prices are stored with the
+// provider.
+type repoMenuEntry struct {
+	BeverageID bson.ObjectId `bson:"beverageId"`
+	Section    string        `bson:"section"`
+	Order      int           `bson:"order"`
+	Tap        string        `bson:"tap"`
+	Servings   []repoServing `bson:"servings"`
 }
 
 type repoBeverage struct {
@@ -42,14 +62,88 @@ type repoBeverage struct {
 	Name          string            `bson:"name"`
 	Description   string            `bson:"description"`
 	BevType       string            `bson:"bevType"`
+	Category      string            `bson:"category"`
 	Brewer        string            `bson:"brewer"`
+	BreweryID     string            `bson:"breweryId"`
 	Abv           float64           `bson:"abv"`
 	Attributes    map[string]string `bson:"attributes"`
 	Ratings       []repoRating      `bson:"ratings"`
 	Link          string            `bson:"link"`
+	Image         string            `bson:"image"`
 	UpdatedAt     time.Time         `bson:"updatedAt"`
 	SyncTime      time.Time         `bson:"syncTime"`
 	AccuracyScore int               `bson:"accuracyScore"`
+
+	Provenance map[string]repoProvenance `bson:"provenance"`
+	Candidates []repoCandidate           `bson:"candidates,omitempty"`
+	// CanonicalID is set on a duplicate merged into another beverage.
+	CanonicalID bson.ObjectId `bson:"canonicalId,omitempty"`
+}
+
+type repoProvenance struct {
+	Source    string    `bson:"source"`
+	Score     int       `bson:"score"`
+	FetchTime time.Time `bson:"fetchTime"`
+}
+
+type repoCandidate struct {
+	Source      string  `bson:"source"`
+	URL         string  `bson:"url"`
+	Title       string  `bson:"title"`
+	Confidence  float64 `bson:"confidence"`
+	NameScore   float64 `bson:"nameScore"`
+	BrewerScore float64 `bson:"brewerScore"`
+	AbvScore    float64 `bson:"abvScore"`
+	Abv         float64 `bson:"abv"`
+	Explanation string  `bson:"explanation"`
+	Chosen      bool    `bson:"chosen"`
+	Pending     bool    `bson:"pending"`
+	Rejected    bool    `bson:"rejected,omitempty"`
+}
+
+type repoServing struct {
+	Name     string  `bson:"name"`
+	Size     float64 `bson:"size"`
+	Unit     string  `bson:"unit"`
+	Price    float64 `bson:"price"`
+	Currency string  `bson:"currency"`
+}
+
+// repoBrewery stores the text.BreweryKey of the brewery's name and each of
+// its aliases in Keys, for lookup by name.
+type repoBrewery struct {
+	ID       bson.ObjectId     `bson:"_id"`
+	Name     string            `bson:"name"`
+	Aliases  []string          `bson:"aliases"`
+	Keys     []string          `bson:"keys"`
+	Location string            `bson:"location"`
+	Links    map[string]string `bson:"links"`
+
+	Details *repoBreweryDetails `bson:"details,omitempty"`
+}
+
+type repoBreweryDetails struct {
+	City      string    `bson:"city"`
+	State     string    `bson:"state"`
+	Country   string    `bson:"country"`
+	Website   string    `bson:"website"`
+	Type      string    `bson:"type"`
+	Latitude  float64   `bson:"latitude"`
+	Longitude float64   `bson:"longitude"`
+	Source    string    `bson:"source"`
+	FetchTime time.Time `bson:"fetchTime"`
+}
+
+// repoOverride is keyed by the ID of the beverage it curates.
+type repoOverride struct {
+	ID               bson.ObjectId       `bson:"_id"`
+	Fields           map[string]string   `bson:"fields"`
+	ProfileURLs      map[string]string   `bson:"profileUrls"`
+	Blocked          []string            `bson:"blocked"`
+	RejectedProfiles map[string][]string `bson:"rejectedProfiles,omitempty"`
+	NotDuplicates    []string            `bson:"notDuplicates,omitempty"`
+	Note             string              `bson:"note"`
+	UpdatedAt        time.Time           `bson:"updatedAt"`
 }
 
 type repoRating struct {
@@ -108,6 +202,8 @@ func (repo *mongoRepo) Init() error {
 	repo.db = repo.session.DB(repo.database)
 	repo.providers = repo.db.C("providers")
 	repo.beverages = repo.db.C("beverages")
+	repo.breweries = repo.db.C("breweries")
+	repo.overrides = repo.db.C("overrides")
 	repo.initialized = true
 	return nil
 }
@@ -121,10 +217,20 @@ func (repo *mongoRepo) Purge() {
 	if err != nil {
 		log.Printf("Error purging beverages collection: %s", err)
 	}
+	err = repo.breweries.DropCollection()
+	if err != nil {
+		log.Printf("Error purging breweries collection: %s", err)
+	}
+	err = repo.overrides.DropCollection()
+	if err != nil {
+		log.Printf("Error purging overrides collection: %s", err)
+	}
 }
 
 func (repo *mongoRepo) GarbageCollect() {
-	referencedBeverageIds := repo.beverageIdsReferencedInMenus()
+	// Beverages staff have curated are kept for when they return:
+	referencedBeverageIds := append(repo.beverageIdsReferencedInMenus(),
+		repo.overriddenBeverageIDs()...)
 	discardThresholdTime := policy.BeverageDiscardThresholdTime()
 	changes, err := repo.beverages.RemoveAll(
 		bson.M{
@@ -139,24 +245,94 @@ func (repo *mongoRepo) GarbageCollect() {
 }
 
 func (repo *mongoRepo) MenuProviders() []model.MenuProvider {
-	return repository.StubRepository().MenuProviders()
+	repo.seedProviders()
+	var providers []repoProvider
+	err := repo.providers.Find(bson.M{"removed": bson.M{"$ne": true}}).Sort("providerId").All(&providers)
+	if err != nil {
+		log.Printf("Error listing providers: %s\n", err)
+	}
+	result := make([]model.MenuProvider, len(providers))
+	for i := range providers {
+		result[i] = repoProviderModel(&providers[i])
+	}
+	return result
 }
 
 func (repo *mongoRepo) ProviderByID(id string) model.MenuProvider {
-	return repository.StubRepository().ProviderByID(id)
+	repo.seedProviders()
+	provider := repoProvider{}
+	err := repo.providers.Find(bson.M{"providerId": id, "removed": bson.M{"$ne": true}}).One(&provider)
+	if err != nil {
+		return nil
+	}
+	return repoProviderModel(&provider)
+}
+
+// seedProviders saves the stub repository's providers if there are no
+// providers at all.
+func (repo *mongoRepo) seedProviders() {
+	count, err := repo.providers.Count()
+	if err != nil || count > 0 {
+		return
+	}
+	for _, provider := range repository.StubRepository().MenuProviders() {
+		log.Printf("Seeding provider %s\n", provider)
+		repo.saveProvider(provider)
+	}
+}
+
+func (repo *mongoRepo) SaveProvider(prov model.MenuProvider) error {
+	repo.seedProviders()
+	return repo.saveProvider(prov)
+}
+
+func (repo *mongoRepo) saveProvider(prov model.MenuProvider) error {
+	provider, err := repo.findProvider(prov)
+	if err != nil {
+		provider = &repoProvider{ID: bson.NewObjectId(), ProviderID: prov.ID()}
+	}
+	provider.Name = prov.Name()
+	provider.URL = prov.URL()
+	provider.MenuFormat = prov.MenuFormat()
+	provider.Options = prov.Options()
+	provider.Removed = false
+	if _, err = repo.providers.UpsertId(provider.ID, provider); err != nil {
+		log.Printf("SaveProvider(%s) failed: %s", prov, err)
+	}
+	return err
+}
+
+func (repo *mongoRepo) RemoveProvider(id string) error {
+	repo.seedProviders()
+	provider := repoProvider{}
+	err := repo.providers.Find(bson.M{"providerId": id, "removed": bson.M{"$ne": true}}).One(&provider)
+	if err != nil {
+		return repository.ErrNoSuchProvider
+	}
+	provider.Removed = true
+	provider.BeverageIDs = nil
+	provider.Menu = nil
+	if _, err = repo.providers.UpsertId(provider.ID, provider); err != nil {
+		log.Printf("RemoveProvider(%s) failed: %s", id, err)
+	}
+	return err
 }
 
 func (repo *mongoRepo) ProviderBeverages(prov model.MenuProvider) []model.Beverage {
+	if prov == nil {
+		return []model.Beverage{}
+	}
 	provider, err := repo.findProvider(prov)
 	if err != nil {
 		return []model.Beverage{}
 	}
-	result, err := repo.lookupBeveragesByIDs(provider.BeverageIDs)
+	repoBevs, err := repo.lookupRepoBeveragesByIDs(provider.BeverageIDs)
 	if err != nil {
 		log.Printf("Could not look up beverages for provider %s (%s) with ids: %v\n",
 			prov.Name(), prov.ID(), provider.BeverageIDs)
+		return nil
 	}
-	return result
+	return menuBeverageModels(provider.Menu, repoBevs, repo.lookupCanonicals(repoBevs))
 }
 
 func (repo *mongoRepo) ProviderIDBeverages(id string) []model.Beverage {
@@ -169,7 +345,8 @@ func (repo *mongoRepo) BeveragesNeedingSync() []model.Beverage {
 	var beverages []repoBeverage
 	err := repo.beverages.Find(
 		bson.M{
-			"_id": bson.M{"$in": referencedBeverageIds},
+			"_id":         bson.M{"$in": referencedBeverageIds},
+			"canonicalId": bson.M{"$exists": false},
 			"$or": []interface{}{
 				bson.M{"syncTime": nil},
 				bson.M{"syncTime": bson.M{"$lt": staleUpdateTime}},
@@ -188,7 +365,7 @@ func (repo *mongoRepo) SetBeverageMenu(prov model.MenuProvider, beverages []mode
 		log.Printf("Failed to save beverages for %s: %v", prov.Name(), err)
 		return
 	}
-	err = repo.saveProviderMenu(prov, beverageIds)
+	err = repo.saveProviderMenu(prov, beverageIds, menuEntries(beverages, beverageIds))
 	if err != nil {
 		log.Printf("Failed to save provider menu for %s: %s", prov.Name(), err)
 	}
@@ -206,13 +383,233 @@ func (repo *mongoRepo) BeverageByName(name string) model.Beverage {
 	if err != nil {
 		return nil
 	}
-	return repoBeverageModel(repoBev)
+	return repoBeverageModel(canonicalView(repoBev, repo.canonicalRepoBev(repoBev)))
+}
+
+func (repo *mongoRepo) BeverageByID(id string) model.Beverage {
+	repoBev, err := repo.findBeverageByID(id)
+	if err != nil {
+		return nil
+	}
+	return repoBeverageModel(canonicalView(repoBev, repo.canonicalRepoBev(repoBev)))
+}
+
+func (repo *mongoRepo) BeveragesByProfile(profileURL string) []model.Beverage {
+	if profileURL == "" {
+		return []model.Beverage{}
+	}
+	var repoBevs []repoBeverage
+	err := repo.beverages.Find(bson.M{
+		"canonicalId": bson.M{"$exists": false},
+		"$or": []interface{}{
+			bson.M{"link": profileURL},
+			bson.M{"attributes." + ratebeer.ProfileURLProperty: profileURL},
+		},
+	}).Sort("_id").All(&repoBevs)
+	if err != nil {
+		log.Printf("Error looking up beverages with profile %s: %s\n", profileURL, err)
+	}
+	return repoBeverageModels(repoBevs)
+}
+
+func (repo *mongoRepo) BeverageDuplicates(canonicalID string) []model.Beverage {
+	if !bson.IsObjectIdHex(canonicalID) {
+		return []model.Beverage{}
+	}
+	var duplicates []repoBeverage
+	err := repo.beverages.Find(bson.M{"canonicalId": bson.ObjectIdHex(canonicalID)}).All(&duplicates)
+	if err != nil {
+		log.Printf("Error listing duplicates of %s: %s\n", canonicalID, err)
+	}
+	return repoBeverageModels(duplicates)
+}
+
+func (repo *mongoRepo) MergeBeverages(canonicalID, duplicateID string) error {
+	canonical, err := repo.findBeverageByID(canonicalID)
+	if err != nil {
+		return repository.ErrNoSuchBeverage
+	}
+	canonical = repo.canonicalRepoBev(canonical)
+	duplicate, err := repo.findBeverageByID(duplicateID)
+	if err != nil {
+		return repository.ErrNoSuchBeverage
+	}
+	if duplicate.ID == canonical.ID {
+		return repository.ErrSelfMerge
+	}
+	// A duplicate brings the beverages merged with it along:
+	duplicate = repo.canonicalRepoBev(duplicate)
+	if duplicate.ID == canonical.ID {
+		return nil
+	}
+
+	log.Printf("MergeBeverages: merging %s (%s) into %s (%s)",
+		duplicate.DisplayName, duplicate.ID, canonical.DisplayName, canonical.ID)
+	mergeRepoBev(canonical, duplicate)
+	duplicate.CanonicalID = canonical.ID
+	if _, err = repo.beverages.UpsertId(canonical.ID, canonical); err != nil {
+		return err
+	}
+	if _, err = repo.beverages.UpsertId(duplicate.ID, duplicate); err != nil {
+		return err
+	}
+	_, err = repo.beverages.UpdateAll(
+		bson.M{"canonicalId": duplicate.ID},
+		bson.M{"$set": bson.M{"canonicalId": canonical.ID}})
+	return err
+}
+
+func (repo *mongoRepo) SplitBeverage(duplicateID string) error {
+	duplicate, err := repo.findBeverageByID(duplicateID)
+	if err != nil {
+		return repository.ErrNoSuchBeverage
+	}
+	if duplicate.CanonicalID == "" {
+		return repository.ErrNotMerged
+	}
+	log.Printf("SplitBeverage: splitting %s (%s) from %s",
+		duplicate.DisplayName, duplicate.ID, duplicate.CanonicalID)
+	duplicate.CanonicalID = ""
+	duplicate.SyncTime = time.Time{}
+	_, err = repo.beverages.UpsertId(duplicate.ID, duplicate)
+	return err
+}
+
+func (repo *mongoRepo) Overrides() []*model.Override {
+	var overrides []repoOverride
+	err := repo.overrides.Find(nil).Sort("-updatedAt").All(&overrides)
+	if err != nil {
+		log.Printf("Error listing overrides: %s\n", err)
+	}
+	result := make([]*model.Override, len(overrides))
+	for i := range overrides {
+		result[i] = repoOverrideModel(&overrides[i])
+	}
+	return result
+}
+
+func (repo *mongoRepo) Override(beverageID string) *model.Override {
+	if !bson.IsObjectIdHex(beverageID) {
+		return nil
+	}
+	repoOver := &repoOverride{}
+	if err := repo.overrides.FindId(bson.ObjectIdHex(beverageID)).One(repoOver); err != nil {
+		return nil
+	}
+	return repoOverrideModel(repoOver)
+}
+
+func (repo *mongoRepo) SaveOverride(override *model.Override) error {
+	repoBev, err := repo.findBeverageByID(override.BeverageID)
+	if err != nil {
+		return repository.ErrNoSuchBeverage
+	}
+	repoBev = repo.canonicalRepoBev(repoBev)
+	override.BeverageID = repoBev.ID.Hex()
+	override.UpdatedAt = time.Now()
+	if _, err = repo.overrides.UpsertId(repoBev.ID, overrideModelToRepo(override)); err != nil {
+		log.Printf("SaveOverride(%s) failed: %s", repoBev.DisplayName, err)
+		return err
+	}
+	applyRepoOverride(repoBev, override)
+	_, err = repo.beverages.UpsertId(repoBev.ID, repoBev)
+	return err
+}
+
+func (repo *mongoRepo) DeleteOverride(beverageID string) {
+	repoBev, err := repo.findBeverageByID(beverageID)
+	if err != nil {
+		return
+	}
+	repoBev = repo.canonicalRepoBev(repoBev)
+	if err = repo.overrides.RemoveId(repoBev.ID); err != nil && err != mgo.ErrNotFound {
+		log.Printf("DeleteOverride(%s) failed: %s", repoBev.DisplayName, err)
+		return
+	}
+	applyRepoOverride(repoBev, &model.Override{})
+	if _, err = repo.beverages.UpsertId(repoBev.ID, repoBev); err != nil {
+		log.Printf("DeleteOverride(%s) failed: %s", repoBev.DisplayName, err)
+	}
+}
+
+func (repo *mongoRepo) BeveragesPendingReview() []model.Beverage {
+	var repoBevs []repoBeverage
+	err := repo.beverages.Find(bson.M{
+		"canonicalId":        bson.M{"$exists": false},
+		"candidates.pending": true,
+	}).Sort("displayName").All(&repoBevs)
+	if err != nil {
+		log.Printf("Error looking up beverages pending review: %s\n", err)
+	}
+	return repoBeverageModels(repoBevs)
+}
+
+func (repo *mongoRepo) SaveProfileCandidates(beverageID, source string, candidates []model.ProfileCandidate) error {
+	repoBev, err := repo.findBeverageByID(beverageID)
+	if err != nil {
+		return repository.ErrNoSuchBeverage
+	}
+	repoBev = repo.canonicalRepoBev(repoBev)
+	kept := []repoCandidate{}
+	for _, c := range repoBev.Candidates {
+		if c.Source != source {
+			kept = append(kept, c)
+		}
+	}
+	repoBev.Candidates = kept
+	setRepoCandidates(repoBev, candidates)
+	if _, err = repo.beverages.UpsertId(repoBev.ID, repoBev); err != nil {
+		log.Printf("SaveProfileCandidates(%s, %s) failed: %s", repoBev.DisplayName, source, err)
+	}
+	return err
 }
 
-func (repo *mongoRepo) saveProviderMenu(prov model.MenuProvider, beverageIDs []bson.ObjectId) error {
+func (repo *mongoRepo) Breweries() []model.Brewery {
+	var breweries []repoBrewery
+	err := repo.breweries.Find(nil).Sort("name").All(&breweries)
+	if err != nil {
+		log.Printf("Error listing breweries: %s\n", err)
+	}
+	return repoBreweryModels(breweries)
+}
+
+func (repo *mongoRepo) BreweryByID(id string) model.Brewery {
+	if !bson.IsObjectIdHex(id) {
+		return nil
+	}
+	repoBrew := &repoBrewery{}
+	if err := repo.breweries.FindId(bson.ObjectIdHex(id)).One(repoBrew); err != nil {
+		return nil
+	}
+	return repoBreweryModel(repoBrew)
+}
+
+func (repo *mongoRepo) BreweryByName(name string) model.Brewery {
+	repoBrew := &repoBrewery{}
+	err := repo.breweries.Find(bson.M{"keys": text.BreweryKey(name)}).One(repoBrew)
+	if err != nil {
+		return nil
+	}
+	return repoBreweryModel(repoBrew)
+}
+
+func (repo *mongoRepo) SaveBrewery(brewery model.Brewery) {
+	repoBrew := breweryModelToRepo(brewery)
+	if repoBrew.ID == "" {
+		repoBrew.ID = bson.NewObjectId()
+		brewery.SetID(repoBrew.ID.Hex())
+	}
+	if _, err := repo.breweries.UpsertId(repoBrew.ID, repoBrew); err != nil {
+		log.Printf("SaveBrewery(%s) failed: %s", brewery.Name(), err)
+	}
+}
+
+func (repo *mongoRepo) saveProviderMenu(prov model.MenuProvider, beverageIDs []bson.ObjectId, menu []repoMenuEntry) error {
 	provider, err := repo.findProvider(prov)
 	if err == nil { // menu exists
+		provider.Options = prov.Options()
 		provider.BeverageIDs = beverageIDs
+		provider.Menu = menu
 		_, err = repo.providers.UpsertId(provider.ID, provider)
 		return err
 	}
@@ -222,7 +619,9 @@ func (repo *mongoRepo) saveProviderMenu(prov model.MenuProvider, beverageIDs []b
 		Name:        prov.Name(),
 		URL:         prov.URL(),
 		MenuFormat:  prov.MenuFormat(),
+		Options:     prov.Options(),
 		BeverageIDs: beverageIDs,
+		Menu:        menu,
 	}
 	return repo.providers.Insert(provider)
 }
@@ -283,13 +682,63 @@ func (repo *mongoRepo) findBeverageByName(name string) (*repoBeverage, error) {
 	return repoBev, nil
 }
 
-func (repo *mongoRepo) lookupBeveragesByIDs(ids []bson.ObjectId) ([]model.Beverage, error) {
+func (repo *mongoRepo) findBeverageByID(id string) (*repoBeverage, error) {
+	if !bson.IsObjectIdHex(id) {
+		return nil, mgo.ErrNotFound
+	}
+	repoBev := &repoBeverage{}
+	err := repo.beverages.FindId(bson.ObjectIdHex(id)).One(repoBev)
+	if err != nil {
+		return nil, err
+	}
+	return repoBev, nil
+}
+
+// canonicalRepoBev returns the beverage repoBev was merged into, or repoBev
+// itself if it is canonical.
+func (repo *mongoRepo) canonicalRepoBev(repoBev *repoBeverage) *repoBeverage {
+	if repoBev.CanonicalID == "" {
+		return repoBev
+	}
+	canonical := &repoBeverage{}
+	if err := repo.beverages.FindId(repoBev.CanonicalID).One(canonical); err != nil {
+		log.Printf("Could not find canonical beverage %s of %s: %s\n",
+			repoBev.CanonicalID, repoBev.DisplayName, err)
+		return repoBev
+	}
+	return canonical
+}
+
+// lookupCanonicals looks up the canonical beverages of any duplicates in
+// repoBevs, by ID.
+func (repo *mongoRepo) lookupCanonicals(repoBevs []repoBeverage) map[bson.ObjectId]*repoBeverage {
+	ids := []bson.ObjectId{}
+	for _, repoBev := range repoBevs {
+		if repoBev.CanonicalID != "" {
+			ids = append(ids, repoBev.CanonicalID)
+		}
+	}
+	canonicals := map[bson.ObjectId]*repoBeverage{}
+	if len(ids) == 0 {
+		return canonicals
+	}
+	found, err := repo.lookupRepoBeveragesByIDs(ids)
+	if err != nil {
+		log.Printf("Could not look up canonical beverages %v: %s\n", ids, err)
+	}
+	for i := range found {
+		canonicals[found[i].ID] = &found[i]
+	}
+	return canonicals
+}
+
+func (repo *mongoRepo) lookupRepoBeveragesByIDs(ids []bson.ObjectId) ([]repoBeverage, error) {
 	var beverages []repoBeverage
 	err := repo.beverages.Find(bson.M{"_id": bson.M{"$in": ids}}).Limit(BeverageFetchLimit).All(&beverages)
 	if err != nil {
 		return nil, err
 	}
-	return repoBeverageModels(beverages), nil
+	return beverages, nil
 }
 
 func (repo *mongoRepo) findProvider(prov model.MenuProvider) (*repoProvider, error) {
@@ -316,5 +765,34 @@ func (repo *mongoRepo) beverageIdsReferencedInMenus() []bson.ObjectId {
 	for id, _ := range referencedBeverageIDs {
 		result = append(result, id)
 	}
+
+	// Merged beverages are referenced through their duplicates:
+	var duplicates []repoBeverage
+	err := repo.beverages.Find(bson.M{
+		"_id":         bson.M{"$in": result},
+		"canonicalId": bson.M{"$exists": true},
+	}).Select(bson.M{"canonicalId": 1}).All(&duplicates)
+	if err != nil {
+		log.Printf("Error looking up merged beverages: %s\n", err)
+	}
+	for _, duplicate := range duplicates {
+		if !referencedBeverageIDs[duplicate.CanonicalID] {
+			referencedBeverageIDs[duplicate.CanonicalID] = true
+			result = append(result, duplicate.CanonicalID)
+		}
+	}
+	return result
+}
+
+func (repo *mongoRepo) overriddenBeverageIDs() []bson.ObjectId {
+	var overrides []repoOverride
+	err := repo.overrides.Find(nil).Select(bson.M{"_id": 1}).All(&overrides)
+	if err != nil {
+		log.Printf("Error listing overridden beverages: %s\n", err)
+	}
+	result := make([]bson.ObjectId, len(overrides))
+	for i, override := range overrides {
+		result[i] = override.ID
+	}
 	return result
 }
@@ -2,10 +2,13 @@ package mongorepo
 
 import (
 	"testing"
+	"time"
 
+	"github.com/bevly/bevly/fetch/metadata/beeradvocate"
 	"github.com/bevly/bevly/model"
 	"github.com/bevly/bevly/repository"
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
 )
 
 var repo repository.Repository
@@ -98,6 +101,183 @@ func TestSaveBeverageUpdate(t *testing.T) {
 	assert.Equal(t, "IPA", bev.Type(), "type preserve")
 }
 
+func TestSaveProvenance(t *testing.T) {
+	repo.Purge()
+	bevModel := beverageInfos[0].Model()
+	frisco := model.CreateProvenance("Frisco", 2)
+	model.SetField(bevModel, model.FieldDescription, "Frisco says hi", frisco)
+	model.SetField(bevModel, model.FieldImage, "http://frisco.org/img.png", frisco)
+	repo.SaveBeverage(bevModel)
+
+	bevModel = beverageInfos[0].Model()
+	ba := model.CreateProvenance("BA", 10)
+	model.SetField(bevModel, model.FieldName, "Anchor IPA", ba)
+	repo.SaveBeverage(bevModel)
+
+	bevModel = beverageInfos[0].Model()
+	rb := model.CreateProvenance("RateBeer", 9)
+	model.SetField(bevModel, model.FieldName, "Anchor India Pale Ale", rb)
+	model.SetField(bevModel, model.FieldDescription, "RateBeer says hi", rb)
+	repo.SaveBeverage(bevModel)
+
+	bev := repo.BeverageByName(bevModel.DisplayName())
+	if assert.NotNil(t, bev, "beverage should be saved") {
+		assert.Equal(t, "Anchor IPA", bev.Name(), "BA name outranks RateBeer")
+		assert.Equal(t, "BA", bev.Provenance(model.FieldName).Source)
+		assert.Equal(t, "RateBeer says hi", bev.Description(),
+			"RateBeer description outranks Frisco")
+		assert.Equal(t, 9, bev.Provenance(model.FieldDescription).Score)
+		assert.Equal(t, "http://frisco.org/img.png", bev.Image())
+		assert.Equal(t, "Frisco", bev.Provenance(model.FieldImage).Source)
+		assert.False(t, bev.Provenance(model.FieldImage).FetchTime.IsZero(),
+			"fetch time")
+	}
+}
+
+func TestSaveCandidates(t *testing.T) {
+	repo.Purge()
+	bevModel := beverageInfos[0].Model()
+	bevModel.SetProfileCandidates("BA", []model.ProfileCandidate{
+		{URL: "http://ba/1", Confidence: 0.9, Chosen: true},
+		{URL: "http://ba/2", Confidence: 0.4, BrewerScore: -1},
+	})
+	bevModel.SetProfileCandidates("RateBeer", []model.ProfileCandidate{
+		{URL: "http://rb/1", Confidence: 0.3},
+	})
+	repo.SaveBeverage(bevModel)
+
+	bevModel = beverageInfos[0].Model()
+	bevModel.SetProfileCandidates("RateBeer", []model.ProfileCandidate{
+		{URL: "http://rb/2", Confidence: 0.8, Chosen: true},
+	})
+	repo.SaveBeverage(bevModel)
+
+	bev := repo.BeverageByName(bevModel.DisplayName())
+	if assert.NotNil(t, bev, "beverage should be saved") &&
+		assert.Equal(t, 3, len(bev.ProfileCandidates())) {
+		chosen, ok := model.ChosenCandidate(bev, "BA")
+		assert.True(t, ok)
+		assert.Equal(t, "http://ba/1", chosen.URL)
+		chosen, _ = model.ChosenCandidate(bev, "RateBeer")
+		assert.Equal(t, "http://rb/2", chosen.URL, "new candidates replace the source's old ones")
+		assert.Equal(t, -1.0, bev.ProfileCandidates()[1].BrewerScore)
+	}
+}
+
+func TestPendingReview(t *testing.T) {
+	repo.Purge()
+	bevModel := beverageInfos[0].Model()
+	bevModel.SetProfileCandidates("BA", []model.ProfileCandidate{
+		{URL: "http://ba/1", Confidence: 0.6, Pending: true},
+		{URL: "http://ba/2", Confidence: 0.4},
+	})
+	repo.SaveBeverage(bevModel)
+	repo.SaveBeverage(beverageInfos[1].Model())
+
+	pending := repo.BeveragesPendingReview()
+	if !assert.Equal(t, 1, len(pending)) {
+		return
+	}
+	assert.Equal(t, bevModel.DisplayName(), pending[0].DisplayName())
+	assert.Equal(t, []string{"BA"}, model.PendingSources(pending[0]))
+
+	reviewed, _ := model.ReviewCandidates(model.SourceCandidates(pending[0], "BA"), "http://ba/2")
+	assert.Nil(t, repo.SaveProfileCandidates(pending[0].ID(), "BA", reviewed))
+	assert.Equal(t, 0, len(repo.BeveragesPendingReview()))
+	chosen, ok := model.ChosenCandidate(repo.BeverageByID(pending[0].ID()), "BA")
+	assert.True(t, ok)
+	assert.Equal(t, "http://ba/2", chosen.URL)
+
+	assert.Equal(t, repository.ErrNoSuchBeverage,
+		repo.SaveProfileCandidates(bson.NewObjectId().Hex(), "BA", nil))
+}
+
+func TestSaveOverride(t *testing.T) {
+	repo.Purge()
+	bevModel := beverageInfos[1].Model()
+	ba := model.CreateProvenance("BA", 10)
+	model.SetField(bevModel, model.FieldName, "Racer 5 IPA", ba)
+	model.SetField(bevModel, model.FieldDescription, "Wrong beer", ba)
+	model.SetField(bevModel, model.FieldBrewer, "Bear Republic", model.CreateProvenance("RateBeer", 9))
+	bevModel.AddRating(model.CreateRating("BA", 88))
+	bevModel.AddRating(model.CreateRating("BAbro", 90))
+	bevModel.AddRating(model.CreateRating("rb", 97))
+	bevModel.SetAttribute(beeradvocate.DescriptionProperty, "Wrong beer")
+	repo.SaveBeverage(bevModel)
+	saved := repo.BeverageByName(bevModel.DisplayName())
+
+	override := &model.Override{
+		BeverageID: saved.ID(),
+		Fields:     map[string]string{model.FieldName: "Racer V"},
+		Blocked:    []string{"BA"},
+	}
+	assert.Nil(t, repo.SaveOverride(override))
+	assert.Equal(t, repository.ErrNoSuchBeverage,
+		repo.SaveOverride(&model.Override{BeverageID: "nope"}))
+
+	bev := repo.BeverageByID(saved.ID())
+	assert.Equal(t, "Racer V", bev.Name(), "staff name")
+	assert.Equal(t, "", bev.Description(), "blocked source discarded")
+	assert.Equal(t, "Bear Republic", bev.Brewer(), "other sources kept")
+	if assert.Equal(t, 1, len(bev.Ratings()), "blocked source's ratings discarded") {
+		assert.Equal(t, "rb", bev.Ratings()[0].Source())
+	}
+	assert.Equal(t, "", bev.Attribute(beeradvocate.DescriptionProperty), "blocked source's attributes discarded")
+	assert.Equal(t, []string{model.FieldName}, model.OverriddenFields(bev))
+	assert.True(t, repo.Override(saved.ID()).Blocks("BA"))
+	assert.Equal(t, 1, len(repo.Overrides()))
+
+	model.SetField(bevModel, model.FieldName, "Racer 5 IPA", ba)
+	repo.SaveBeverage(bevModel)
+	assert.Equal(t, "Racer V", repo.BeverageByID(saved.ID()).Name(),
+		"sync does not replace staff name")
+
+	repo.DeleteOverride(saved.ID())
+	assert.Nil(t, repo.Override(saved.ID()))
+	bev = repo.BeverageByID(saved.ID())
+	assert.Equal(t, "Racer V", bev.Name(), "staff name kept")
+	assert.Empty(t, model.OverriddenFields(bev))
+}
+
+func TestMergeBeverages(t *testing.T) {
+	repo.Purge()
+	pdfBev := model.CreateBeverage("Oliver Bmore Gold")
+	pdfBev.AddServing(model.ParseServing("Pint", "$6"))
+	repo.SetBeverageMenu(repo.ProviderByID("ale_house"), []model.Beverage{pdfBev})
+
+	otherBev := model.CreateBeverage("Oliver Brewing Bmore Gold")
+	rb := model.CreateProvenance("RateBeer", 9)
+	model.SetField(otherBev, model.FieldLink, "http://www.ratebeer.com/beer/oliver-bmore-gold/1/", rb)
+	model.SetAbvField(otherBev, 5.2, rb)
+	otherBev.AddRating(model.CreateRating("rb", 60))
+	repo.SetBeverageMenu(repo.ProviderByID("frisco"), []model.Beverage{otherBev})
+
+	canonical := repo.BeverageByName("Oliver Brewing Bmore Gold")
+	duplicate := repo.BeverageByName("Oliver Bmore Gold")
+	assert.Equal(t, 1, len(repo.BeveragesByProfile(canonical.Link())))
+	assert.Equal(t, repository.ErrSelfMerge, repo.MergeBeverages(canonical.ID(), canonical.ID()))
+	assert.Nil(t, repo.MergeBeverages(canonical.ID(), duplicate.ID()))
+
+	bevs := repo.ProviderIDBeverages("ale_house")
+	if assert.Equal(t, 1, len(bevs)) {
+		assert.Equal(t, canonical.ID(), bevs[0].ID(), "menu shows canonical beverage")
+		assert.Equal(t, "Oliver Bmore Gold", bevs[0].DisplayName(), "menu keeps its name")
+		assert.Equal(t, 5.2, bevs[0].Abv(), "canonical metadata")
+		assert.Equal(t, 1, len(bevs[0].Servings()), "menu servings")
+	}
+	duplicates := repo.BeverageDuplicates(canonical.ID())
+	if assert.Equal(t, 1, len(duplicates)) {
+		assert.Equal(t, duplicate.ID(), duplicates[0].ID())
+	}
+	for _, bev := range repo.BeveragesNeedingSync() {
+		assert.NotEqual(t, duplicate.ID(), bev.ID(), "duplicates are not synced")
+	}
+
+	assert.Nil(t, repo.SplitBeverage(duplicate.ID()))
+	assert.Equal(t, repository.ErrNotMerged, repo.SplitBeverage(duplicate.ID()))
+	assert.Equal(t, duplicate.ID(), repo.ProviderIDBeverages("ale_house")[0].ID())
+}
+
 func TestSaveMenu(t *testing.T) {
 	repo.Purge()
 	frisco := repo.ProviderByID("frisco")
@@ -111,3 +291,98 @@ func TestSaveMenu(t *testing.T) {
 	assert.Equal(t, 3, len(savedBevs), "three beverages should be saved")
 	assert.Equal(t, "Bear Republic Racer V", savedBevs[1].DisplayName())
 }
+
+func TestProviders(t *testing.T) {
+	repo.Purge()
+	assert.Equal(t, 2, len(repo.MenuProviders()), "default providers are seeded")
+
+	provider := model.CreateMenuProvider("pub", "The Pub", "http://pub.example.com/menu.csv", "csv")
+	provider.SetOption("csvDelimiter", ";")
+	assert.Nil(t, repo.SaveProvider(provider))
+	saved := repo.ProviderByID("pub")
+	if assert.NotNil(t, saved) {
+		assert.Equal(t, "The Pub", saved.Name())
+		assert.Equal(t, "csv", saved.MenuFormat())
+		assert.Equal(t, ";", saved.Option("csvDelimiter"))
+	}
+
+	repo.SetBeverageMenu(repo.ProviderByID("frisco"), []model.Beverage{beverageInfos[0].Model()})
+	assert.Nil(t, repo.RemoveProvider("frisco"))
+	assert.Nil(t, repo.ProviderByID("frisco"))
+	assert.Equal(t, 0, len(repo.ProviderIDBeverages("frisco")), "the menu is removed")
+	assert.Equal(t, 2, len(repo.MenuProviders()), "removed providers are not seeded again")
+	assert.Equal(t, repository.ErrNoSuchProvider, repo.RemoveProvider("frisco"))
+}
+
+func TestSaveMenuSections(t *testing.T) {
+	repo.Purge()
+	frisco := repo.ProviderByID("frisco")
+	bevs := make([]model.Beverage, len(beverageInfos))
+	for i, bevInfo := range beverageInfos {
+		bevs[i] = bevInfo.Model()
+		bevs[i].SetMenuSection("Drafts")
+		bevs[i].SetMenuOrder(len(beverageInfos) - i)
+	}
+	bevs[2].SetMenuSection("Cask")
+	bevs[2].SetTap("7")
+	repo.SetBeverageMenu(frisco, bevs)
+
+	savedBevs := repo.ProviderIDBeverages("frisco")
+	if assert.Equal(t, 3, len(savedBevs), "three beverages should be saved") {
+		assert.Equal(t, "Jolly Pumpkin Oro de Calabaza", savedBevs[0].DisplayName(),
+			"beverages should be in menu order")
+		assert.Equal(t, "Cask", savedBevs[0].MenuSection())
+		assert.Equal(t, "7", savedBevs[0].Tap())
+		assert.Equal(t, "Drafts", savedBevs[2].MenuSection())
+		assert.Equal(t, 3, savedBevs[2].MenuOrder())
+	}
+}
+
+func TestSaveMenuServings(t *testing.T) {
+	repo.Purge()
+	friscoBev := model.CreateBeverage("Bear Republic Racer 5")
+	friscoBev.AddServing(model.ParseServing("Pint", "$6"))
+	repo.SetBeverageMenu(repo.ProviderByID("frisco"), []model.Beverage{friscoBev})
+	aleHouseBev := model.CreateBeverage("Bear Republic Racer 5")
+	aleHouseBev.AddServing(model.ParseServing("Pint", "$7.50"))
+	repo.SetBeverageMenu(repo.ProviderByID("ale_house"), []model.Beverage{aleHouseBev})
+
+	friscoBevs := repo.ProviderIDBeverages("frisco")
+	aleHouseBevs := repo.ProviderIDBeverages("ale_house")
+	if assert.Equal(t, 1, len(friscoBevs)) && assert.Equal(t, 1, len(aleHouseBevs)) {
+		assert.Equal(t, friscoBevs[0].ID(), aleHouseBevs[0].ID(), "beverage is shared")
+		if assert.Equal(t, 1, len(friscoBevs[0].Servings())) {
+			assert.Equal(t, 6.0, friscoBevs[0].Servings()[0].Price, "each menu keeps its own prices")
+		}
+		if assert.Equal(t, 1, len(aleHouseBevs[0].Servings())) {
+			assert.Equal(t, 7.5, aleHouseBevs[0].Servings()[0].Price)
+		}
+	}
+}
+
+func TestSaveBrewery(t *testing.T) {
+	repo.Purge()
+	brewery := model.CreateBrewery("Dogfish Head")
+	brewery.AddAlias("Dogfish Head Craft Brewery")
+	brewery.SetLocation("Milton, DE")
+	brewery.SetDetails(model.BreweryDetails{
+		City: "Milton", State: "Delaware", Type: "regional",
+		Latitude: 38.7776, Longitude: -75.3099,
+		Source: "OpenBreweryDB", FetchTime: time.Now(),
+	})
+	repo.SaveBrewery(brewery)
+	if !assert.NotEqual(t, "", brewery.ID(), "saved brewery should get an ID") {
+		return
+	}
+
+	found := repo.BreweryByName("- Dogfish Head")
+	if assert.NotNil(t, found, "brewery should be found by name variant") {
+		assert.Equal(t, brewery.ID(), found.ID())
+		assert.Equal(t, "Milton, DE", found.Location())
+		assert.Equal(t, "regional", found.Details().Type)
+		assert.Equal(t, 38.7776, found.Details().Latitude)
+		assert.True(t, found.Details().Fetched())
+	}
+	assert.NotNil(t, repo.BreweryByID(brewery.ID()), "brewery should be found by ID")
+	assert.Nil(t, repo.BreweryByName("Stone"), "unknown brewery")
+}
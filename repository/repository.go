@@ -1,24 +1,90 @@
 package repository
 
 import (
+	"errors"
 	"log"
 
 	"github.com/bevly/bevly/model"
 )
 
+var (
+	ErrNoSuchBeverage = errors.New("no such beverage")
+	ErrSelfMerge      = errors.New("cannot merge a beverage into itself")
+	ErrNotMerged      = errors.New("beverage is not merged")
+	ErrNoSuchProvider = errors.New("no such provider")
+)
+
 type Repository interface {
+	// Menu providers are configured in the repository. A repository with
+	// no providers is seeded with the stub repository's.
 	MenuProviders() []model.MenuProvider
 	ProviderByID(id string) model.MenuProvider
+	// SaveProvider adds a provider, or reconfigures the provider with its
+	// ID, keeping its menu.
+	SaveProvider(provider model.MenuProvider) error
+	// RemoveProvider removes a provider and its menu. Returns
+	// ErrNoSuchProvider if there is no provider with the ID.
+	RemoveProvider(id string) error
 	ProviderBeverages(provider model.MenuProvider) []model.Beverage
 	ProviderIDBeverages(providerName string) []model.Beverage
 	BeveragesNeedingSync() []model.Beverage
 	BeverageByName(name string) model.Beverage
+	BeverageByID(id string) model.Beverage
+
+	// Beverages listed under different names can be merged into one
+	// canonical beverage. Menus keep listing a duplicate under its own name,
+	// but every lookup returns the canonical beverage's metadata and ID, and
+	// only canonical beverages need sync.
+	//
+	// BeveragesByProfile finds the canonical beverages whose link or
+	// RateBeer profile is profileURL, oldest first.
+	BeveragesByProfile(profileURL string) []model.Beverage
+	// BeverageDuplicates lists the beverages merged into a canonical
+	// beverage, each with its own ID.
+	BeverageDuplicates(canonicalID string) []model.Beverage
+	// MergeBeverages makes duplicate an alias of canonical, which takes the
+	// metadata, ratings and history it lacks from duplicate.
+	MergeBeverages(canonicalID, duplicateID string) error
+	// SplitBeverage undoes the merge of duplicateID, which resyncs on its
+	// own. Metadata the canonical beverage took from it is kept. See
+	// sync.SplitBeverage, which also keeps the two from being merged again.
+	SplitBeverage(duplicateID string) error
 
 	// TODO
 	SetBeverageMenu(provider model.MenuProvider, menu []model.Beverage)
 	SaveBeverage(beverage model.Beverage)
 	// BeverageByName(name string)
 
+	Breweries() []model.Brewery
+	BreweryByID(id string) model.Brewery
+	// BreweryByName finds the brewery whose name or one of whose aliases
+	// matches name, as compared by text.BreweryKey.
+	BreweryByName(name string) model.Brewery
+	// SaveBrewery inserts or updates brewery, setting its ID if new.
+	SaveBrewery(brewery model.Brewery)
+
+	// Overrides are staff curation of beverages; Override returns nil if
+	// the beverage has none.
+	Overrides() []*model.Override
+	Override(beverageID string) *model.Override
+	// SaveOverride stores override and applies it to the stored beverage
+	// at once, discarding values that came from sources it blocks. Returns
+	// ErrNoSuchBeverage if the beverage is not in the repository.
+	SaveOverride(override *model.Override) error
+	// DeleteOverride removes a beverage's override. Values staff set are
+	// kept until a metadata source replaces them.
+	DeleteOverride(beverageID string)
+
+	// Metadata matches below profile.ReviewConfidence are parked as
+	// pending candidates instead of fetched, for staff to review.
+	// BeveragesPendingReview lists the canonical beverages with a pending
+	// candidate, by name.
+	BeveragesPendingReview() []model.Beverage
+	// SaveProfileCandidates replaces the candidates of source kept on a
+	// beverage, such as once they are reviewed. Returns ErrNoSuchBeverage
+	// if the beverage is not in the repository.
+	SaveProfileCandidates(beverageID, source string, candidates []model.ProfileCandidate) error
+
 	// Discard unreferenced beverages
 	GarbageCollect()
 
@@ -36,11 +102,22 @@ var _ Repository = &stubRepository{}
 
 func (s *stubRepository) MenuProviders() []model.MenuProvider {
 	return []model.MenuProvider{
-		model.CreateMenuProvider("frisco", "Frisco", "http://www.friscogrille.com/cmobile-alt.php", "frisco"),
-		model.CreateMenuProvider("ale_house", "Ale House", "http://www.thealehousecolumbia.com/menu/", "ale_house"),
+		locatedProvider(
+			model.CreateMenuProvider("frisco", "Frisco", "http://www.friscogrille.com/cmobile-alt.php", "frisco"),
+			"39.1836", "-76.8079", "Maryland"),
+		locatedProvider(
+			model.CreateMenuProvider("ale_house", "Ale House", "http://www.thealehousecolumbia.com/menu/", "ale_house"),
+			"39.2018", "-76.8266", "Maryland"),
 	}
 }
 
+func locatedProvider(provider model.MenuProvider, latitude, longitude, state string) model.MenuProvider {
+	provider.SetOption(model.OptionLatitude, latitude)
+	provider.SetOption(model.OptionLongitude, longitude)
+	provider.SetOption(model.OptionState, state)
+	return provider
+}
+
 func (s *stubRepository) ProviderByID(id string) model.MenuProvider {
 	log.Printf("Looking for provider named \"%s\"\n", id)
 	for _, prov := range s.MenuProviders() {
@@ -51,6 +128,14 @@ func (s *stubRepository) ProviderByID(id string) model.MenuProvider {
 	return nil
 }
 
+func (*stubRepository) SaveProvider(provider model.MenuProvider) error {
+	return nil
+}
+
+func (*stubRepository) RemoveProvider(id string) error {
+	return ErrNoSuchProvider
+}
+
 func (s *stubRepository) ProviderBeverages(prov model.MenuProvider) []model.Beverage {
 	if prov == nil {
 		return []model.Beverage{}
@@ -98,3 +183,61 @@ func (*stubRepository) Purge() {
 func (*stubRepository) BeverageByName(name string) model.Beverage {
 	return nil
 }
+
+func (*stubRepository) BeverageByID(id string) model.Beverage {
+	return nil
+}
+
+func (*stubRepository) BeveragesByProfile(profileURL string) []model.Beverage {
+	return []model.Beverage{}
+}
+
+func (*stubRepository) BeverageDuplicates(canonicalID string) []model.Beverage {
+	return []model.Beverage{}
+}
+
+func (*stubRepository) MergeBeverages(canonicalID, duplicateID string) error {
+	return ErrNoSuchBeverage
+}
+
+func (*stubRepository) SplitBeverage(duplicateID string) error {
+	return ErrNoSuchBeverage
+}
+
+func (*stubRepository) Overrides() []*model.Override {
+	return []*model.Override{}
+}
+
+func (*stubRepository) Override(beverageID string) *model.Override {
+	return nil
+}
+
+func (*stubRepository) SaveOverride(override *model.Override) error {
+	return ErrNoSuchBeverage
+}
+
+func (*stubRepository) DeleteOverride(beverageID string) {
+}
+
+func (*stubRepository) BeveragesPendingReview() []model.Beverage {
+	return []model.Beverage{}
+}
+
+func (*stubRepository) SaveProfileCandidates(beverageID, source string, candidates []model.ProfileCandidate) error {
+	return ErrNoSuchBeverage
+}
+
+func (*stubRepository) Breweries() []model.Brewery {
+	return []model.Brewery{}
+}
+
+func (*stubRepository) BreweryByID(id string) model.Brewery {
+	return nil
+}
+
+func (*stubRepository) BreweryByName(name string) model.Brewery {
+	return nil
+}
+
+func (*stubRepository) SaveBrewery(brewery model.Brewery) {
+}
@@ -0,0 +1,71 @@
+package style
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/bevly/bevly/model"
+)
+
+var categorySectionRules = []struct {
+	pattern  *regexp.Regexp
+	category string
+}{
+	{regexp.MustCompile(`\b(?:ciders?|cidre|perry)\b`), model.CategoryCider},
+	{regexp.MustCompile(`\bmeads?\b`), model.CategoryMead},
+	{regexp.MustCompile(`\bkombuchas?\b`), model.CategoryKombucha},
+	{regexp.MustCompile(`\b(?:wines?|vino|sparkling|bubbles)\b|(?:^|\W)rosé(?:$|\W)`), model.CategoryWine},
+	{regexp.MustCompile(`\b(?:cocktails?|mixed drinks|spirits)\b`), model.CategoryCocktail},
+	{regexp.MustCompile(`\b(?:beers?|drafts?|draughts?|taps?|cask|ales?|lagers?|brewing|brewery)\b`), model.CategoryBeer},
+}
+
+// Go's \b only knows ASCII word characters, so "rosé" is bounded by hand.
+var rWine = regexp.MustCompile(`\b(?:wine|malbec|cabernet|merlot|pinot|chardonnay|riesling|sauvignon|syrah|shiraz|zinfandel|tempranillo|sangiovese|grenache|moscato|prosecco|champagne|cava|red blend)\b|(?:^|\W)rosé(?:$|\W)`)
+var rKombucha = regexp.MustCompile(`\bkombucha\b`)
+var rCocktail = regexp.MustCompile(`\b(?:cocktail|margarita|old fashioned|martini|negroni|spritz|mule|mojito|manhattan|sangria)\b`)
+
+// styleCategory places a style string, or "" if it says nothing about the
+// category. Beer styles are tried first, since "barley wine" is a beer.
+func styleCategory(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	switch canonical := Canonical(raw); {
+	case canonical.Family == FamilyCider:
+		return model.CategoryCider
+	case canonical.Family == FamilyMead:
+		return model.CategoryMead
+	case !canonical.Empty():
+		return model.CategoryBeer
+	}
+	lower := strings.ToLower(raw)
+	switch {
+	case rKombucha.MatchString(lower):
+		return model.CategoryKombucha
+	case rWine.MatchString(lower):
+		return model.CategoryWine
+	case rCocktail.MatchString(lower):
+		return model.CategoryCocktail
+	}
+	return ""
+}
+
+// DetectCategory guesses what kind of beverage bev is from its menu section,
+// then its style, then its name, returning "" if none of them say.
+func DetectCategory(bev model.Beverage) string {
+	section := strings.ToLower(bev.MenuSection())
+	for _, rule := range categorySectionRules {
+		if rule.pattern.MatchString(section) {
+			return rule.category
+		}
+	}
+	if category := styleCategory(bev.Type()); category != "" {
+		return category
+	}
+	// Names are full of words like "Ale" and "Orchard", so only trust them
+	// for the non-beer categories.
+	if category := styleCategory(bev.DisplayName()); category != model.CategoryBeer {
+		return category
+	}
+	return ""
+}
@@ -0,0 +1,34 @@
+package style
+
+import (
+	"testing"
+
+	"github.com/bevly/bevly/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectCategory(t *testing.T) {
+	tests := []struct {
+		name, section, bevType, category string
+	}{
+		{"Bold Rock Apple", "Ciders", "", model.CategoryCider},
+		{"Jailbreak Desserted", "Guest Drafts", "Chocolate Coconut Porter", model.CategoryBeer},
+		{"Catena", "Wine by the Glass", "", model.CategoryWine},
+		{"Catena", "", "Malbec", model.CategoryWine},
+		{"Whispering Angel", "", "Rosé", model.CategoryWine},
+		{"Whispering Angel", "Dry Rosé", "", model.CategoryWine},
+		{"Whispering Angel Rosé", "", "", model.CategoryWine},
+		{"Dogfish Head Olde School", "", "American Barleywine", model.CategoryBeer},
+		{"Superstition Lemongrass", "", "Traditional Mead", model.CategoryMead},
+		{"Blue Dragon Kombucha", "", "", model.CategoryKombucha},
+		{"Angry Orchard Crisp Apple Cider", "", "", model.CategoryCider},
+		{"Oliver Draft Punk", "", "", ""},
+		{"Weasel Ale", "", "", ""},
+	}
+	for _, test := range tests {
+		bev := model.CreateBeverage(test.name)
+		bev.SetMenuSection(test.section)
+		bev.SetType(test.bevType)
+		assert.Equal(t, test.category, DetectCategory(bev), test.name)
+	}
+}
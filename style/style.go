@@ -0,0 +1,291 @@
+// Package style maps the style strings that menus and metadata sources use
+// ("American Double / Imperial IPA", "India Pale Ale (IPA)", "Chocolate
+// Coconut Porter") to a small built-in taxonomy of canonical styles grouped
+// into families, loosely following the BJCP guidelines.
+package style
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/bevly/bevly/text"
+)
+
+// Style families.
+const (
+	FamilyIPA       = "IPA"
+	FamilyPaleAle   = "Pale Ale"
+	FamilyStout     = "Stout"
+	FamilyPorter    = "Porter"
+	FamilyLager     = "Lager"
+	FamilyWheat     = "Wheat Beer"
+	FamilyBelgian   = "Belgian Ale"
+	FamilyFarmhouse = "Farmhouse Ale"
+	FamilySour      = "Sour"
+	FamilyBrown     = "Brown Ale"
+	FamilyAmber     = "Amber & Red Ale"
+	FamilyBlonde    = "Blonde & Golden Ale"
+	FamilyHybrid    = "Hybrid Beer"
+	FamilyStrong    = "Strong Ale"
+	FamilySpecialty = "Specialty Beer"
+	FamilyCider     = "Cider"
+	FamilyMead      = "Mead"
+)
+
+// Style is a canonical beverage style.
+type Style struct {
+	ID     string
+	Name   string
+	Family string
+}
+
+// Empty reports whether s is the zero Style, which stands for "unknown".
+func (s Style) Empty() bool { return s.ID == "" }
+
+func (s Style) String() string { return s.Name }
+
+// Styles lists the taxonomy, family by family.
+var Styles = []Style{
+	{"american-ipa", "American IPA", FamilyIPA},
+	{"imperial-ipa", "Imperial IPA", FamilyIPA},
+	{"new-england-ipa", "New England IPA", FamilyIPA},
+	{"session-ipa", "Session IPA", FamilyIPA},
+	{"black-ipa", "Black IPA", FamilyIPA},
+	{"belgian-ipa", "Belgian IPA", FamilyIPA},
+	{"english-ipa", "English IPA", FamilyIPA},
+
+	{"american-pale-ale", "American Pale Ale", FamilyPaleAle},
+	{"english-pale-ale", "English Pale Ale", FamilyPaleAle},
+	{"english-bitter", "English Bitter", FamilyPaleAle},
+
+	{"stout", "Stout", FamilyStout},
+	{"imperial-stout", "Imperial Stout", FamilyStout},
+	{"oatmeal-stout", "Oatmeal Stout", FamilyStout},
+	{"sweet-stout", "Sweet Stout", FamilyStout},
+	{"irish-stout", "Irish Stout", FamilyStout},
+
+	{"porter", "Porter", FamilyPorter},
+	{"baltic-porter", "Baltic Porter", FamilyPorter},
+
+	{"lager", "Lager", FamilyLager},
+	{"american-lager", "American Lager", FamilyLager},
+	{"pilsner", "Pilsner", FamilyLager},
+	{"helles", "Helles", FamilyLager},
+	{"marzen", "Märzen", FamilyLager},
+	{"vienna-lager", "Vienna Lager", FamilyLager},
+	{"dunkel", "Munich Dunkel", FamilyLager},
+	{"schwarzbier", "Schwarzbier", FamilyLager},
+	{"bock", "Bock", FamilyLager},
+	{"doppelbock", "Doppelbock", FamilyLager},
+	{"kellerbier", "Kellerbier", FamilyLager},
+	{"rauchbier", "Rauchbier", FamilyLager},
+
+	{"hefeweizen", "Hefeweizen", FamilyWheat},
+	{"dunkelweizen", "Dunkelweizen", FamilyWheat},
+	{"weizenbock", "Weizenbock", FamilyWheat},
+	{"witbier", "Witbier", FamilyWheat},
+	{"american-wheat", "American Wheat", FamilyWheat},
+
+	{"belgian-blonde", "Belgian Blonde", FamilyBelgian},
+	{"belgian-pale-ale", "Belgian Pale Ale", FamilyBelgian},
+	{"dubbel", "Dubbel", FamilyBelgian},
+	{"tripel", "Tripel", FamilyBelgian},
+	{"quadrupel", "Quadrupel", FamilyBelgian},
+	{"belgian-strong-dark", "Belgian Strong Dark Ale", FamilyBelgian},
+	{"belgian-strong-golden", "Belgian Strong Golden Ale", FamilyBelgian},
+
+	{"saison", "Saison", FamilyFarmhouse},
+	{"biere-de-garde", "Bière de Garde", FamilyFarmhouse},
+
+	{"gose", "Gose", FamilySour},
+	{"berliner-weisse", "Berliner Weisse", FamilySour},
+	{"flanders-red", "Flanders Red Ale", FamilySour},
+	{"lambic", "Lambic", FamilySour},
+	{"gueuze", "Gueuze", FamilySour},
+	{"wild-ale", "Wild Ale", FamilySour},
+
+	{"american-brown-ale", "American Brown Ale", FamilyBrown},
+	{"english-brown-ale", "English Brown Ale", FamilyBrown},
+
+	{"amber-ale", "Amber Ale", FamilyAmber},
+	{"irish-red-ale", "Irish Red Ale", FamilyAmber},
+
+	{"blonde-ale", "Blonde Ale", FamilyBlonde},
+	{"cream-ale", "Cream Ale", FamilyBlonde},
+
+	{"kolsch", "Kölsch", FamilyHybrid},
+	{"altbier", "Altbier", FamilyHybrid},
+	{"california-common", "California Common", FamilyHybrid},
+
+	{"american-barleywine", "American Barleywine", FamilyStrong},
+	{"english-barleywine", "English Barleywine", FamilyStrong},
+	{"wheatwine", "Wheatwine", FamilyStrong},
+	{"old-ale", "Old Ale", FamilyStrong},
+	{"scotch-ale", "Scotch Ale", FamilyStrong},
+	{"scottish-ale", "Scottish Ale", FamilyAmber},
+	{"strong-ale", "Strong Ale", FamilyStrong},
+	{"winter-warmer", "Winter Warmer", FamilyStrong},
+
+	{"fruit-beer", "Fruit Beer", FamilySpecialty},
+	{"spiced-beer", "Spiced Beer", FamilySpecialty},
+	{"pumpkin-ale", "Pumpkin Ale", FamilySpecialty},
+	{"rye-beer", "Rye Beer", FamilySpecialty},
+
+	{"cider", "Cider", FamilyCider},
+	{"perry", "Perry", FamilyCider},
+	{"mead", "Mead", FamilyMead},
+}
+
+var stylesByID = map[string]Style{}
+
+func init() {
+	for _, s := range Styles {
+		stylesByID[s.ID] = s
+	}
+}
+
+// ByID returns the style with the given ID, or the zero Style.
+func ByID(id string) Style {
+	return stylesByID[id]
+}
+
+// sourceStyles maps source style strings, normalized by normalizeRaw, that
+// the keyword rules would get wrong or can't place.
+var sourceStyles = map[string]string{
+	// BeerAdvocate
+	"american black ale":            "black-ipa",
+	"american amber red lager":      "vienna-lager",
+	"belgian dark ale":              "belgian-strong-dark",
+	"bière de champagne bière brut": "belgian-strong-golden",
+	"sour red brown":                "flanders-red",
+	"low alcohol beer":              "american-lager",
+
+	// RateBeer
+	"spice herb vegetable": "spiced-beer",
+	"specialty grain":      "rye-beer",
+}
+
+// styleRules place styles by keyword, most specific first.
+var styleRules = []struct {
+	pattern *regexp.Regexp
+	id      string
+}{
+	{regexp.MustCompile(`\b(?:black ipa|cascadian dark|india black)\b`), "black-ipa"},
+	{regexp.MustCompile(`\b(?:new england|neipa|ne ipa|hazy|juicy)\b`), "new-england-ipa"},
+	{regexp.MustCompile(`\bsession (?:ipa|india)\b`), "session-ipa"},
+	{regexp.MustCompile(`\bbelgian (?:ipa|india)\b`), "belgian-ipa"},
+	{regexp.MustCompile(`\benglish (?:ipa|india)\b`), "english-ipa"},
+	{regexp.MustCompile(`\b(?:imperial|double|triple)\b.*\b(?:ipa|india pale)\b|\bipa\b.*\b(?:imperial|double)\b|\bdipa\b|\biipa\b`), "imperial-ipa"},
+	{regexp.MustCompile(`\bipa\b|\bindia pale\b`), "american-ipa"},
+
+	{regexp.MustCompile(`\b(?:imperial|double)\b.*\bstout\b|\bstout\b.*\bimperial\b`), "imperial-stout"},
+	{regexp.MustCompile(`\boatmeal stout\b`), "oatmeal-stout"},
+	{regexp.MustCompile(`\b(?:milk|sweet|cream) stout\b`), "sweet-stout"},
+	{regexp.MustCompile(`\b(?:dry|irish) stout\b`), "irish-stout"},
+	{regexp.MustCompile(`\bstout\b`), "stout"},
+	{regexp.MustCompile(`\bbaltic porter\b`), "baltic-porter"},
+	{regexp.MustCompile(`\bporter\b`), "porter"},
+
+	{regexp.MustCompile(`\bpumpkin\b`), "pumpkin-ale"},
+	{regexp.MustCompile(`\bgose\b`), "gose"},
+	{regexp.MustCompile(`\bberliner\b`), "berliner-weisse"},
+	{regexp.MustCompile(`\b(?:flanders|oud bruin)\b`), "flanders-red"},
+	{regexp.MustCompile(`\b(?:gueuze|geuze)\b`), "gueuze"},
+	{regexp.MustCompile(`\b(?:lambic|kriek|framboise|faro)\b`), "lambic"},
+	{regexp.MustCompile(`\b(?:wild|sour|brett|brettanomyces)\b`), "wild-ale"},
+	{regexp.MustCompile(`\b(?:saison|farmhouse)\b`), "saison"},
+	{regexp.MustCompile(`\bbi[eè]re de garde\b`), "biere-de-garde"},
+
+	{regexp.MustCompile(`\bdubbel\b`), "dubbel"},
+	{regexp.MustCompile(`\btripel\b`), "tripel"},
+	{regexp.MustCompile(`\bquad(?:rupel)?\b`), "quadrupel"},
+	{regexp.MustCompile(`\bbelgian (?:strong dark|dark strong)\b`), "belgian-strong-dark"},
+	{regexp.MustCompile(`\bbelgian (?:strong golden|golden strong|strong pale)\b`), "belgian-strong-golden"},
+	{regexp.MustCompile(`\bbelgian pale\b`), "belgian-pale-ale"},
+	{regexp.MustCompile(`\bbelgian blonde?\b`), "belgian-blonde"},
+
+	{regexp.MustCompile(`\bweizenbock\b`), "weizenbock"},
+	{regexp.MustCompile(`\bdunkelweizen\b|\bdunkel weizen\b`), "dunkelweizen"},
+	{regexp.MustCompile(`\b(?:witbier|wit|white ale|belgian white|bière blanche)\b`), "witbier"},
+	{regexp.MustCompile(`\b(?:hefeweizen|hefe|weissbier|weizen|hefeweisse)\b`), "hefeweizen"},
+	{regexp.MustCompile(`\b(?:wheatwine|wheat wine)\b`), "wheatwine"},
+	{regexp.MustCompile(`\bwheat\b`), "american-wheat"},
+
+	{regexp.MustCompile(`\b(?:doppelbock|eisbock)\b`), "doppelbock"},
+	{regexp.MustCompile(`\b(?:maibock|helles bock|bock)\b`), "bock"},
+	{regexp.MustCompile(`\b(?:märzen|marzen|oktoberfest|festbier)\b`), "marzen"},
+	{regexp.MustCompile(`\bhelles\b`), "helles"},
+	{regexp.MustCompile(`\bschwarzbier\b|\bblack lager\b`), "schwarzbier"},
+	{regexp.MustCompile(`\bdunkel\b`), "dunkel"},
+	{regexp.MustCompile(`\bvienna\b`), "vienna-lager"},
+	{regexp.MustCompile(`\b(?:rauchbier|smoked)\b`), "rauchbier"},
+	{regexp.MustCompile(`\b(?:kellerbier|zwickel)\b`), "kellerbier"},
+	{regexp.MustCompile(`\bpils(?:ner|ener)?\b`), "pilsner"},
+	{regexp.MustCompile(`\b(?:adjunct|light|american|mexican) lager\b`), "american-lager"},
+	{regexp.MustCompile(`\blager\b`), "lager"},
+
+	{regexp.MustCompile(`\b(?:kölsch|kolsch)\b`), "kolsch"},
+	{regexp.MustCompile(`\balt(?:bier)?\b`), "altbier"},
+	{regexp.MustCompile(`\bcream ale\b`), "cream-ale"},
+	{regexp.MustCompile(`\b(?:california common|steam beer)\b`), "california-common"},
+
+	{regexp.MustCompile(`\benglish barley ?wine\b`), "english-barleywine"},
+	{regexp.MustCompile(`\bbarley ?wine\b`), "american-barleywine"},
+	{regexp.MustCompile(`\bold ale\b`), "old-ale"},
+	{regexp.MustCompile(`\b(?:scotch ale|wee heavy)\b`), "scotch-ale"},
+	{regexp.MustCompile(`\bscottish\b`), "scottish-ale"},
+	{regexp.MustCompile(`\bwinter warmer\b`), "winter-warmer"},
+	{regexp.MustCompile(`\bstrong ale\b`), "strong-ale"},
+
+	{regexp.MustCompile(`\benglish brown\b`), "english-brown-ale"},
+	{regexp.MustCompile(`\bbrown ale\b`), "american-brown-ale"},
+	{regexp.MustCompile(`\birish red\b`), "irish-red-ale"},
+	{regexp.MustCompile(`\b(?:amber|red ale)\b`), "amber-ale"},
+	{regexp.MustCompile(`\b(?:esb|extra special|bitter)\b`), "english-bitter"},
+	{regexp.MustCompile(`\benglish pale\b`), "english-pale-ale"},
+	{regexp.MustCompile(`\bpale ale\b|\bapa\b`), "american-pale-ale"},
+	{regexp.MustCompile(`\b(?:blonde?|golden) ale\b`), "blonde-ale"},
+
+	{regexp.MustCompile(`\b(?:rye|roggenbier)\b`), "rye-beer"},
+	{regexp.MustCompile(`\b(?:fruit|vegetable)\b`), "fruit-beer"},
+	{regexp.MustCompile(`\b(?:herb|herbed|spice|spiced|chile)\b`), "spiced-beer"},
+
+	{regexp.MustCompile(`\bperry\b`), "perry"},
+	{regexp.MustCompile(`\b(?:cider|cidre|sidra)\b`), "cider"},
+	{regexp.MustCompile(`\b(?:mead|melomel|metheglin|braggot|cyser|pyment)\b`), "mead"},
+}
+
+var rStylePunctuation = regexp.MustCompile(`[^\pL\pN]+`)
+
+func normalizeRaw(raw string) string {
+	return text.Normalize(rStylePunctuation.ReplaceAllString(strings.ToLower(raw), " "))
+}
+
+// Canonical maps a source's style string to a canonical style, returning the
+// zero Style if the string can't be placed.
+func Canonical(raw string) Style {
+	normalized := normalizeRaw(raw)
+	if normalized == "" {
+		return Style{}
+	}
+	if id, ok := sourceStyles[normalized]; ok {
+		return stylesByID[id]
+	}
+	for _, rule := range styleRules {
+		if rule.pattern.MatchString(normalized) {
+			return stylesByID[rule.id]
+		}
+	}
+	return Style{}
+}
+
+// Matches reports whether s is the style or belongs to the family named by
+// query, ignoring case. This is what style filters compare against.
+func (s Style) Matches(query string) bool {
+	if s.Empty() {
+		return false
+	}
+	query = strings.ToLower(strings.TrimSpace(query))
+	return query == s.ID || query == strings.ToLower(s.Name) ||
+		query == strings.ToLower(s.Family)
+}
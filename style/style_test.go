@@ -0,0 +1,68 @@
+package style
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		raw, id string
+	}{
+		// BeerAdvocate
+		{"American Double / Imperial IPA", "imperial-ipa"},
+		{"American IPA", "american-ipa"},
+		{"Russian Imperial Stout", "imperial-stout"},
+		{"American Black Ale", "black-ipa"},
+		{"Märzen / Oktoberfest", "marzen"},
+		{"Fruit / Vegetable Beer", "fruit-beer"},
+		{"Extra Special / Strong Bitter (ESB)", "english-bitter"},
+		{"Sour Red/Brown", "flanders-red"},
+		{"Weizenbock", "weizenbock"},
+		// RateBeer
+		{"India Pale Ale (IPA)", "american-ipa"},
+		{"Imperial Stout", "imperial-stout"},
+		{"Pilsener", "pilsner"},
+		{"Sour/Wild Ale", "wild-ale"},
+		{"Golden Ale/Blond Ale", "blonde-ale"},
+		{"Amber Lager/Vienna", "vienna-lager"},
+		// Frisco and menu style lines
+		{"Double IPA", "imperial-ipa"},
+		{"Chocolate Coconut Porter", "porter"},
+		{"Imperial Pumpkin Ale", "pumpkin-ale"},
+		{"Belgian-Style Tripel", "tripel"},
+		{"Hazy IPA", "new-england-ipa"},
+		{"Dry Cider", "cider"},
+		{"Traditional Mead", "mead"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.id, Canonical(test.raw).ID, test.raw)
+	}
+
+	assert.True(t, Canonical("").Empty(), "empty style")
+	assert.True(t, Canonical("Malbec").Empty(), "not a beer style")
+}
+
+func TestMatches(t *testing.T) {
+	s := Canonical("American Double / Imperial IPA")
+	assert.True(t, s.Matches("Imperial IPA"), "style name")
+	assert.True(t, s.Matches("ipa"), "family, ignoring case")
+	assert.True(t, s.Matches("imperial-ipa"), "style ID")
+	assert.False(t, s.Matches("Stout"))
+	assert.False(t, Style{}.Matches(""), "unknown style matches nothing")
+}
+
+func TestTaxonomyIDs(t *testing.T) {
+	seen := map[string]bool{}
+	for _, s := range Styles {
+		assert.False(t, seen[s.ID], "duplicate style ID "+s.ID)
+		seen[s.ID] = true
+	}
+	for _, id := range sourceStyles {
+		assert.True(t, seen[id], "source style maps to unknown ID "+id)
+	}
+	for _, rule := range styleRules {
+		assert.True(t, seen[rule.id], "rule maps to unknown ID "+rule.id)
+	}
+}
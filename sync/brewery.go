@@ -0,0 +1,50 @@
+package sync
+
+import (
+	"log"
+	"regexp"
+
+	"github.com/bevly/bevly/fetch/metadata/openbrewerydb"
+	"github.com/bevly/bevly/model"
+	"github.com/bevly/bevly/repository"
+	"github.com/bevly/bevly/text"
+)
+
+var rBrewerLeadingDash = regexp.MustCompile(`^\s*-\s*`)
+
+// ResolveBrewery links a beverage to the brewery its Brewer() names,
+// creating the brewery if no known brewery answers to that name, and
+// recording new spellings as aliases. The beverage's brewer is replaced with
+// the brewery's canonical name. Breweries not yet looked up are enriched
+// from lookup, unless it is nil. Returns true if the beverage changed.
+func ResolveBrewery(repo repository.Repository, bev model.Beverage, lookup openbrewerydb.Lookup) bool {
+	brewer := text.Normalize(rBrewerLeadingDash.ReplaceAllString(bev.Brewer(), ""))
+	if brewer == "" {
+		return false
+	}
+
+	brewery := repo.BreweryByName(brewer)
+	if brewery == nil {
+		log.Printf("ResolveBrewery(%s): new brewery %s\n", bev, brewer)
+		brewery = model.CreateBrewery(brewer)
+		repo.SaveBrewery(brewery)
+	} else if brewery.AddAlias(brewer) {
+		log.Printf("ResolveBrewery(%s): %s is also known as %s\n", bev, brewery.Name(), brewer)
+		repo.SaveBrewery(brewery)
+	}
+	if brewery.ID() == "" {
+		return false
+	}
+	if lookup != nil && !brewery.Details().Fetched() {
+		if _, err := openbrewerydb.Enrich(brewery, lookup); err != nil {
+			log.Printf("ResolveBrewery(%s): enriching %s failed: %s\n", bev, brewery.Name(), err)
+		} else {
+			repo.SaveBrewery(brewery)
+		}
+	}
+
+	changed := bev.BreweryID() != brewery.ID() || bev.Brewer() != brewery.Name()
+	bev.SetBreweryID(brewery.ID())
+	bev.SetBrewer(brewery.Name())
+	return changed
+}
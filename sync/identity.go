@@ -0,0 +1,158 @@
+package sync
+
+import (
+	"log"
+
+	"github.com/bevly/bevly/fetch/metadata/ratebeer"
+	"github.com/bevly/bevly/model"
+	"github.com/bevly/bevly/repository"
+	"github.com/bevly/bevly/text"
+)
+
+// IdentityMatchConfidence is how closely, by text.NameIdentityConfidence,
+// the names of two beverages from the same brewery must match for them to
+// be merged without sharing a metadata profile.
+const IdentityMatchConfidence = 0.8
+
+// ResolveIdentity merges a saved beverage into a beverage known under
+// another name: one that resolved to the same metadata profile, or failing
+// that, one of candidates from the same brewery whose name matches closely.
+// The beverage already known becomes the canonical one. Close matches whose
+// breweries aren't both resolved are left for staff; see SuggestDuplicates.
+// Beverages staff split apart are never merged again. Returns true if the
+// beverage was merged.
+func ResolveIdentity(repo repository.Repository, bev model.Beverage, candidates []model.Beverage) bool {
+	for _, profile := range profileURLs(bev) {
+		for _, match := range repo.BeveragesByProfile(profile) {
+			if match.ID() != bev.ID() && !splitByStaff(repo, match, bev) {
+				log.Printf("ResolveIdentity(%s): same profile as %s: %s\n", bev, match, profile)
+				return mergeInto(repo, match, bev)
+			}
+		}
+	}
+
+	for _, candidate := range candidates {
+		if candidate.ID() == bev.ID() || splitByStaff(repo, candidate, bev) {
+			continue
+		}
+		confidence := text.NameIdentityConfidence(candidate.DisplayName(), bev.DisplayName())
+		if confidence < IdentityMatchConfidence {
+			continue
+		}
+		if !sameBrewery(candidate, bev) {
+			if mayBeSameBrewery(candidate, bev) {
+				log.Printf("ResolveIdentity(%s): possibly %s (confidence: %.2f%%), left for review\n",
+					bev, candidate, confidence*100)
+			}
+			continue
+		}
+		log.Printf("ResolveIdentity(%s): same as %s (confidence: %.2f%%)\n",
+			bev, candidate, confidence*100)
+		return mergeInto(repo, candidate, bev)
+	}
+	return false
+}
+
+// DuplicateSuggestion is a pair of beverages whose names match closely, but
+// that can't be merged automatically since either's brewery is unresolved.
+type DuplicateSuggestion struct {
+	Beverage   model.Beverage
+	Duplicate  model.Beverage
+	Confidence float64
+}
+
+// SuggestDuplicates lists the pairs of menu beverages that may be the same
+// beverage, for staff to merge with repository.MergeBeverages.
+func SuggestDuplicates(repo repository.Repository) []DuplicateSuggestion {
+	beverages := menuBeverages(repo)
+	suggestions := []DuplicateSuggestion{}
+	for i, bev := range beverages {
+		for _, other := range beverages[i+1:] {
+			if !mayBeSameBrewery(bev, other) || sameBrewery(bev, other) ||
+				splitByStaff(repo, bev, other) {
+				continue
+			}
+			confidence := text.NameIdentityConfidence(bev.DisplayName(), other.DisplayName())
+			if confidence >= IdentityMatchConfidence {
+				suggestions = append(suggestions, DuplicateSuggestion{
+					Beverage: bev, Duplicate: other, Confidence: confidence})
+			}
+		}
+	}
+	return suggestions
+}
+
+// SplitBeverage undoes the merge of a duplicate, recording in its override
+// that it is not a duplicate of the beverage it was merged into, so that
+// ResolveIdentity doesn't merge it back when it resyncs.
+func SplitBeverage(repo repository.Repository, duplicateID string) error {
+	canonical := repo.BeverageByID(duplicateID)
+	if canonical == nil {
+		return repository.ErrNoSuchBeverage
+	}
+	if err := repo.SplitBeverage(duplicateID); err != nil {
+		return err
+	}
+	override := repo.Override(duplicateID)
+	if override == nil {
+		override = &model.Override{BeverageID: duplicateID}
+	}
+	override.MarkNotDuplicate(canonical.ID())
+	return repo.SaveOverride(override)
+}
+
+// splitByStaff reports whether staff split a and b apart.
+func splitByStaff(repo repository.Repository, a, b model.Beverage) bool {
+	return repo.Override(a.ID()).NotDuplicateOf(b.ID()) ||
+		repo.Override(b.ID()).NotDuplicateOf(a.ID())
+}
+
+// profileURLs lists the metadata profiles a beverage resolved to. Links from
+// menus are not profiles, and may be shared by everything on a menu; legacy
+// links may have come from either.
+func profileURLs(bev model.Beverage) []string {
+	profiles := []string{}
+	linkProv := bev.Provenance(model.FieldLink)
+	if link := bev.Link(); link != "" && !linkProv.Empty() && linkProv.Source != model.LegacySource {
+		profiles = append(profiles, link)
+	}
+	if rbLink := bev.Attribute(ratebeer.ProfileURLProperty); rbLink != "" && rbLink != bev.Link() {
+		profiles = append(profiles, rbLink)
+	}
+	return profiles
+}
+
+// sameBrewery reports whether two beverages are known to come from the same
+// brewery.
+func sameBrewery(a, b model.Beverage) bool {
+	return a.BreweryID() != "" && a.BreweryID() == b.BreweryID()
+}
+
+// mayBeSameBrewery reports whether two beverages aren't known to come from
+// different breweries.
+func mayBeSameBrewery(a, b model.Beverage) bool {
+	return a.BreweryID() == "" || b.BreweryID() == "" || a.BreweryID() == b.BreweryID()
+}
+
+func mergeInto(repo repository.Repository, canonical, duplicate model.Beverage) bool {
+	if err := repo.MergeBeverages(canonical.ID(), duplicate.ID()); err != nil {
+		log.Printf("ResolveIdentity(%s): merge into %s failed: %s\n", duplicate, canonical, err)
+		return false
+	}
+	return true
+}
+
+// menuBeverages lists the beverages on every provider's menu, once each.
+func menuBeverages(repo repository.Repository) []model.Beverage {
+	seen := map[string]bool{}
+	result := []model.Beverage{}
+	for _, provider := range repo.MenuProviders() {
+		for _, bev := range repo.ProviderBeverages(provider) {
+			if !seen[bev.ID()] {
+				seen[bev.ID()] = true
+				result = append(result, bev)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,73 @@
+package sync
+
+import (
+	"testing"
+
+	"github.com/bevly/bevly/model"
+	"github.com/bevly/bevly/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveIdentityProfile(t *testing.T) {
+	rb := model.CreateProvenance("RateBeer", 9)
+	known := testBeverage("1", "Oliver Brewing Bmore Gold", "")
+	model.SetField(known, model.FieldLink, "http://www.ratebeer.com/beer/oliver-bmore-gold/1/", rb)
+	bev := testBeverage("2", "Bmore Gold", "")
+	model.SetField(bev, model.FieldLink, "http://www.ratebeer.com/beer/oliver-bmore-gold/1/", rb)
+	repo := newTestRepository(known, bev)
+
+	assert.True(t, ResolveIdentity(repo, bev, nil), "same profile")
+	assert.Equal(t, map[string]string{"2": "1"}, repo.merges)
+}
+
+func TestResolveIdentityName(t *testing.T) {
+	known := testBeverage("1", "Flying Dog Raging Bitch Belgian IPA", "flying-dog")
+	bev := testBeverage("2", "Flying Dog Raging Bitch IPA", "flying-dog")
+	repo := newTestRepository(known, bev)
+
+	assert.True(t, ResolveIdentity(repo, bev, []model.Beverage{bev, known}), "close name, same brewery")
+	assert.Equal(t, map[string]string{"2": "1"}, repo.merges)
+	assert.Empty(t, SuggestDuplicates(repo), "pairs from the same brewery are merged, not suggested")
+}
+
+func TestResolveIdentityNearMiss(t *testing.T) {
+	known := testBeverage("1", "Flying Dog Raging Bitch Belgian IPA", "flying-dog")
+	otherBrewery := testBeverage("2", "Flying Dog Raging Bitch IPA", "flying-fish")
+	unresolved := testBeverage("3", "Flying Dog Raging Bitch IPA", "")
+	otherBeer := testBeverage("4", "Flying Dog Gonzo Imperial Porter", "flying-dog")
+	repo := newTestRepository(known, otherBrewery, unresolved, otherBeer)
+	candidates := []model.Beverage{known}
+
+	assert.False(t, ResolveIdentity(repo, otherBrewery, candidates), "different breweries")
+	assert.False(t, ResolveIdentity(repo, unresolved, candidates), "unresolved brewery")
+	assert.False(t, ResolveIdentity(repo, otherBeer, candidates), "different name")
+	assert.Empty(t, repo.merges)
+
+	suggestions := SuggestDuplicates(repo)
+	if assert.Equal(t, 2, len(suggestions), "only pairs with an unresolved brewery") {
+		for _, suggestion := range suggestions {
+			assert.Contains(t, []string{suggestion.Beverage.ID(), suggestion.Duplicate.ID()}, "3")
+			assert.True(t, suggestion.Confidence >= IdentityMatchConfidence)
+		}
+	}
+}
+
+func TestSplitBeverage(t *testing.T) {
+	rb := model.CreateProvenance("RateBeer", 9)
+	known := testBeverage("1", "Flying Dog Raging Bitch Belgian IPA", "flying-dog")
+	model.SetField(known, model.FieldLink, "http://www.ratebeer.com/beer/raging-bitch/1/", rb)
+	bev := testBeverage("2", "Flying Dog Raging Bitch IPA", "flying-dog")
+	model.SetField(bev, model.FieldLink, "http://www.ratebeer.com/beer/raging-bitch/1/", rb)
+	repo := newTestRepository(known, bev)
+	candidates := []model.Beverage{known, bev}
+
+	assert.True(t, ResolveIdentity(repo, bev, candidates))
+	assert.Nil(t, SplitBeverage(repo, "2"))
+	assert.Empty(t, repo.merges, "split")
+	assert.Equal(t, repository.ErrNotMerged, SplitBeverage(repo, "2"))
+
+	assert.False(t, ResolveIdentity(repo, bev, candidates), "resync keeps the split")
+	assert.False(t, ResolveIdentity(repo, known, candidates), "from either side")
+	assert.Empty(t, repo.merges)
+	assert.True(t, repo.Override("2").NotDuplicateOf("1"))
+}
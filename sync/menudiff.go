@@ -0,0 +1,115 @@
+package sync
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/bevly/bevly/model"
+	"github.com/bevly/bevly/repository"
+)
+
+// MenuDiff compares a freshly fetched menu with the stored one. Beverages
+// are matched by display name, as for discovery times.
+type MenuDiff struct {
+	// Added and Removed are in the order of the fetched and the stored
+	// menu.
+	Added     []model.Beverage
+	Removed   []model.Beverage
+	Changed   []BeverageChange
+	Unchanged int
+}
+
+// Empty reports whether the fetched menu matches the stored one.
+func (d MenuDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// BeverageChange lists how the menu's listing of a beverage changed.
+type BeverageChange struct {
+	Name    string
+	Changes []FieldChange
+}
+
+// FieldChange is a change of a field the menu lists, as text.
+type FieldChange struct {
+	Field   string
+	Stored  string
+	Fetched string
+}
+
+// PreviewMenu fetches a provider's menu and compares it with the stored
+// menu, saving nothing and syncing no metadata. The diff is empty if the
+// fetch failed.
+func PreviewMenu(repo repository.Repository, provider model.MenuProvider) (MenuFetch, MenuDiff) {
+	fetched := FetchMenus([]model.MenuProvider{provider})[0]
+	if fetched.Err != nil {
+		return fetched, MenuDiff{}
+	}
+	return fetched, DiffMenu(repo.ProviderBeverages(provider), fetched.Beverages)
+}
+
+// AbvTolerance is the least difference in ABV that is a change.
+const AbvTolerance = 0.05
+
+// DiffMenu compares a fetched menu with the stored menu. A beverage's ABV
+// and style count as changed only if the fetched menu lists them and the
+// stored values came from a menu: values from metadata sources needn't
+// agree with the menu, and replace what it listed.
+func DiffMenu(stored, fetched []model.Beverage) MenuDiff {
+	diff := MenuDiff{Added: []model.Beverage{}, Removed: []model.Beverage{}, Changed: []BeverageChange{}}
+	storedByName := map[string]model.Beverage{}
+	for _, bev := range stored {
+		storedByName[bev.DisplayName()] = bev
+	}
+	fetchedNames := map[string]bool{}
+	for _, bev := range fetched {
+		fetchedNames[bev.DisplayName()] = true
+		prior := storedByName[bev.DisplayName()]
+		if prior == nil {
+			diff.Added = append(diff.Added, bev)
+			continue
+		}
+		if changes := menuChanges(prior, bev); len(changes) > 0 {
+			diff.Changed = append(diff.Changed, BeverageChange{Name: bev.DisplayName(), Changes: changes})
+		} else {
+			diff.Unchanged++
+		}
+	}
+	for _, bev := range stored {
+		if !fetchedNames[bev.DisplayName()] {
+			diff.Removed = append(diff.Removed, bev)
+		}
+	}
+	return diff
+}
+
+func menuChanges(stored, fetched model.Beverage) []FieldChange {
+	changes := []FieldChange{}
+	if fetched.Abv() > 0 && fromMenu(stored, model.FieldAbv) &&
+		math.Abs(fetched.Abv()-stored.Abv()) >= AbvTolerance {
+		changes = append(changes, FieldChange{Field: model.FieldAbv,
+			Stored: formatAbv(stored.Abv()), Fetched: formatAbv(fetched.Abv())})
+	}
+	if fetched.Type() != "" && fromMenu(stored, model.FieldType) && fetched.Type() != stored.Type() {
+		changes = append(changes, FieldChange{Field: model.FieldType,
+			Stored: stored.Type(), Fetched: fetched.Type()})
+	}
+	if fetched.MenuSection() != stored.MenuSection() {
+		changes = append(changes, FieldChange{Field: "section",
+			Stored: stored.MenuSection(), Fetched: fetched.MenuSection()})
+	}
+	return changes
+}
+
+// fromMenu reports whether a stored beverage's field was set by a menu,
+// which records no provenance, rather than by a metadata source.
+func fromMenu(bev model.Beverage, field string) bool {
+	return bev.Provenance(field).Empty()
+}
+
+func formatAbv(abv float64) string {
+	if abv <= 0 {
+		return ""
+	}
+	return strconv.FormatFloat(abv, 'f', -1, 64)
+}
@@ -0,0 +1,81 @@
+package sync
+
+import (
+	"testing"
+
+	"github.com/bevly/bevly/httpfilestub"
+	"github.com/bevly/bevly/model"
+	"github.com/bevly/bevly/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func menuBeverage(name string, abv float64, bevType string) model.Beverage {
+	bev := model.CreateBeverage(name)
+	bev.SetAbv(abv)
+	bev.SetType(bevType)
+	bev.SetMenuSection("Drafts")
+	return bev
+}
+
+func TestDiffMenu(t *testing.T) {
+	stored := []model.Beverage{
+		menuBeverage("Racer 5", 7.5, "American IPA"),
+		menuBeverage("Pliny the Elder", 8.0, "Double IPA"),
+		menuBeverage("Old Rasputin", 9.0, "Russian Imperial Stout"),
+	}
+	fetched := []model.Beverage{
+		menuBeverage("Racer 5", 7.0, "American IPA"),
+		menuBeverage("Old Rasputin", 0, ""),
+		menuBeverage("Heady Topper", 8.0, "Double IPA"),
+	}
+	diff := DiffMenu(stored, fetched)
+	assert.False(t, diff.Empty())
+	if assert.Equal(t, 1, len(diff.Added)) {
+		assert.Equal(t, "Heady Topper", diff.Added[0].DisplayName())
+	}
+	if assert.Equal(t, 1, len(diff.Removed)) {
+		assert.Equal(t, "Pliny the Elder", diff.Removed[0].DisplayName())
+	}
+	assert.Equal(t, []BeverageChange{{Name: "Racer 5", Changes: []FieldChange{
+		{Field: model.FieldAbv, Stored: "7.5", Fetched: "7"},
+	}}}, diff.Changed)
+	assert.Equal(t, 1, diff.Unchanged, "values the menu doesn't list are not changes")
+
+	assert.True(t, DiffMenu(stored, stored).Empty())
+}
+
+func TestDiffMenuSourceValues(t *testing.T) {
+	synced := menuBeverage("Racer 5", 0, "")
+	model.SetAbvField(synced, 7.0, model.CreateProvenance("RateBeer", 9))
+	model.SetField(synced, model.FieldType, "IPA", model.CreateProvenance("BA", 10))
+
+	diff := DiffMenu([]model.Beverage{synced}, []model.Beverage{menuBeverage("Racer 5", 7.5, "American IPA")})
+	assert.True(t, diff.Empty(), "values from metadata sources are not menu changes")
+	assert.Equal(t, 1, diff.Unchanged)
+}
+
+func TestPreviewMenu(t *testing.T) {
+	ts := httpfilestub.Server("menudiff_test.csv")
+	defer ts.Close()
+	repo := repository.StubRepository()
+	provider := model.CreateMenuProviderAt(
+		model.CreateMenuProvider("frisco", "Frisco", "http://www.example.com/menu.csv", "csv"), ts.URL)
+
+	fetched, diff := PreviewMenu(repo, provider)
+	if !assert.Nil(t, fetched.Err) {
+		return
+	}
+	assert.Equal(t, 3, len(fetched.Beverages))
+	if assert.Equal(t, 1, len(diff.Added)) {
+		assert.Equal(t, "Oliver Draft Punk", diff.Added[0].DisplayName())
+	}
+	if assert.Equal(t, 1, len(diff.Removed)) {
+		assert.Equal(t, "Blue Point Toasted Lager", diff.Removed[0].DisplayName())
+	}
+	assert.Equal(t, []BeverageChange{
+		{Name: "Mikkeller Beer Geek Brunch Weasel", Changes: []FieldChange{
+			{Field: model.FieldType, Stored: "", Fetched: "Imperial Stout"}}},
+		{Name: "Dogfish Head Olde School Barleywine", Changes: []FieldChange{
+			{Field: model.FieldAbv, Stored: "15", Fetched: "14"}}},
+	}, diff.Changed)
+}
@@ -0,0 +1,102 @@
+package sync
+
+import (
+	"github.com/bevly/bevly/model"
+	"github.com/bevly/bevly/repository"
+)
+
+// testRepository keeps beverages and overrides in memory, recording merges,
+// and otherwise behaves as the stub repository.
+type testRepository struct {
+	repository.Repository
+	beverages map[string]model.Beverage
+	overrides map[string]*model.Override
+	// merges maps the IDs of merged duplicates to their canonical IDs.
+	merges map[string]string
+}
+
+func newTestRepository(beverages ...model.Beverage) *testRepository {
+	repo := &testRepository{
+		Repository: repository.StubRepository(),
+		beverages:  map[string]model.Beverage{},
+		overrides:  map[string]*model.Override{},
+		merges:     map[string]string{},
+	}
+	for _, bev := range beverages {
+		repo.beverages[bev.ID()] = bev
+	}
+	return repo
+}
+
+// testBeverage creates a beverage with an ID, from a brewery with the given
+// ID if it is not "".
+func testBeverage(id, name, breweryID string) model.Beverage {
+	bev := model.CreateBeverage(name)
+	bev.SetID(id)
+	bev.SetBreweryID(breweryID)
+	return bev
+}
+
+// ProviderBeverages lists every beverage, as if on each menu.
+func (r *testRepository) ProviderBeverages(provider model.MenuProvider) []model.Beverage {
+	beverages := []model.Beverage{}
+	for _, bev := range r.beverages {
+		beverages = append(beverages, bev)
+	}
+	return beverages
+}
+
+// BeverageByID shows merged duplicates as their canonical beverages.
+func (r *testRepository) BeverageByID(id string) model.Beverage {
+	if canonicalID, merged := r.merges[id]; merged {
+		return r.beverages[canonicalID]
+	}
+	return r.beverages[id]
+}
+
+func (r *testRepository) BeveragesByProfile(profileURL string) []model.Beverage {
+	matches := []model.Beverage{}
+	for _, bev := range r.beverages {
+		if bev.Link() == profileURL {
+			matches = append(matches, bev)
+		}
+	}
+	return matches
+}
+
+func (r *testRepository) MergeBeverages(canonicalID, duplicateID string) error {
+	if canonicalID == duplicateID {
+		return repository.ErrSelfMerge
+	}
+	r.merges[duplicateID] = canonicalID
+	return nil
+}
+
+func (r *testRepository) SplitBeverage(duplicateID string) error {
+	if _, merged := r.merges[duplicateID]; !merged {
+		return repository.ErrNotMerged
+	}
+	delete(r.merges, duplicateID)
+	return nil
+}
+
+func (r *testRepository) Override(beverageID string) *model.Override {
+	return r.overrides[beverageID]
+}
+
+func (r *testRepository) SaveOverride(override *model.Override) error {
+	if r.beverages[override.BeverageID] == nil {
+		return repository.ErrNoSuchBeverage
+	}
+	r.overrides[override.BeverageID] = override
+	return nil
+}
+
+func (r *testRepository) SaveProfileCandidates(beverageID, source string, candidates []model.ProfileCandidate) error {
+	bev := r.beverages[beverageID]
+	if bev == nil {
+		return repository.ErrNoSuchBeverage
+	}
+	bev.SetProfileCandidates(source, candidates)
+	return nil
+}
@@ -0,0 +1,87 @@
+package sync
+
+import (
+	"errors"
+	"log"
+
+	"github.com/bevly/bevly/model"
+	"github.com/bevly/bevly/repository"
+)
+
+var (
+	ErrNoPendingReview = errors.New("no match pending review")
+	ErrNotCandidate    = errors.New("not a candidate for the beverage")
+)
+
+// AcceptMatch settles the review of a beverage's pending match from source
+// by pinning profileURL in the beverage's override: the pending candidate's
+// profile if profileURL is "", or another of source's candidates. Saving
+// the override resyncs the beverage, fetching the pinned profile.
+func AcceptMatch(repo repository.Repository, beverageID, source, profileURL string) error {
+	bev, pending, err := pendingMatch(repo, beverageID, source)
+	if err != nil {
+		return err
+	}
+	if profileURL == "" {
+		profileURL = pending.URL
+	}
+	return reviewMatch(repo, bev, source, profileURL)
+}
+
+// RejectMatch settles the review of a beverage's pending match from source
+// by rejecting its profile in the beverage's override, so it is never
+// chosen again. Saving the override resyncs the beverage, which may match
+// another of source's profiles. Staff block a source altogether by editing
+// the override.
+func RejectMatch(repo repository.Repository, beverageID, source string) error {
+	bev, pending, err := pendingMatch(repo, beverageID, source)
+	if err != nil {
+		return err
+	}
+	override := overrideOf(repo, bev)
+	override.RejectProfile(source, pending.URL)
+	if err := repo.SaveOverride(override); err != nil {
+		log.Printf("RejectMatch(%s, %s): saving override failed: %s\n", bev, source, err)
+		return err
+	}
+	log.Printf("RejectMatch(%s, %s): rejected %q\n", bev, source, pending.URL)
+	model.RejectCandidates(bev, source, []string{pending.URL})
+	return repo.SaveProfileCandidates(bev.ID(), source, model.SourceCandidates(bev, source))
+}
+
+func pendingMatch(repo repository.Repository, beverageID, source string) (model.Beverage, model.ProfileCandidate, error) {
+	bev := repo.BeverageByID(beverageID)
+	if bev == nil {
+		return nil, model.ProfileCandidate{}, repository.ErrNoSuchBeverage
+	}
+	pending, ok := model.PendingCandidate(bev, source)
+	if !ok {
+		return nil, model.ProfileCandidate{}, ErrNoPendingReview
+	}
+	return bev, pending, nil
+}
+
+// reviewMatch records a review decision in the override store, pinning
+// chosenURL, then marks source's candidates reviewed.
+func reviewMatch(repo repository.Repository, bev model.Beverage, source, chosenURL string) error {
+	reviewed, ok := model.ReviewCandidates(model.SourceCandidates(bev, source), chosenURL)
+	if !ok {
+		return ErrNotCandidate
+	}
+	override := overrideOf(repo, bev)
+	override.PinProfile(source, chosenURL)
+	if err := repo.SaveOverride(override); err != nil {
+		log.Printf("reviewMatch(%s, %s): saving override failed: %s\n", bev, source, err)
+		return err
+	}
+	log.Printf("reviewMatch(%s, %s): pinned %q\n", bev, source, chosenURL)
+	return repo.SaveProfileCandidates(bev.ID(), source, reviewed)
+}
+
+// overrideOf returns the beverage's override, or a new one.
+func overrideOf(repo repository.Repository, bev model.Beverage) *model.Override {
+	if override := repo.Override(bev.ID()); override != nil {
+		return override
+	}
+	return &model.Override{BeverageID: bev.ID()}
+}
@@ -0,0 +1,75 @@
+package sync
+
+import (
+	"testing"
+
+	"github.com/bevly/bevly/model"
+	"github.com/bevly/bevly/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	pendingProfile = "https://www.ratebeer.com/beer/bear-republic-racer-5/3837/"
+	otherProfile   = "https://www.ratebeer.com/beer/bear-republic-racer-x/9999/"
+)
+
+func pendingBeverage() model.Beverage {
+	bev := testBeverage("1", "Racer 5", "")
+	bev.SetProfileCandidates("RateBeer", []model.ProfileCandidate{
+		{Source: "RateBeer", URL: pendingProfile, Confidence: 0.6, Pending: true},
+		{Source: "RateBeer", URL: otherProfile, Confidence: 0.55},
+	})
+	return bev
+}
+
+func TestAcceptMatch(t *testing.T) {
+	bev := pendingBeverage()
+	repo := newTestRepository(bev)
+
+	assert.Nil(t, AcceptMatch(repo, "1", "RateBeer", ""))
+	assert.Equal(t, pendingProfile, repo.Override("1").ProfileURL("RateBeer"), "pending profile pinned")
+	chosen, ok := model.ChosenCandidate(bev, "RateBeer")
+	assert.True(t, ok && chosen.URL == pendingProfile, "pending candidate chosen")
+	assert.Empty(t, model.PendingSources(bev), "review settled")
+	assert.Equal(t, ErrNoPendingReview, AcceptMatch(repo, "1", "RateBeer", ""))
+
+	bev = pendingBeverage()
+	repo = newTestRepository(bev)
+	assert.Nil(t, AcceptMatch(repo, "1", "RateBeer", otherProfile))
+	assert.Equal(t, otherProfile, repo.Override("1").ProfileURL("RateBeer"), "runner-up pinned")
+
+	repo = newTestRepository(pendingBeverage())
+	assert.Equal(t, ErrNotCandidate, AcceptMatch(repo, "1", "RateBeer", "https://www.ratebeer.com/beer/x/1/"))
+	assert.Nil(t, repo.Override("1"), "nothing pinned")
+	assert.Equal(t, repository.ErrNoSuchBeverage, AcceptMatch(repo, "2", "RateBeer", ""))
+	assert.Equal(t, ErrNoPendingReview, AcceptMatch(repo, "1", "BA", ""))
+}
+
+func TestRejectMatch(t *testing.T) {
+	bev := pendingBeverage()
+	repo := newTestRepository(bev)
+	repo.overrides["1"] = &model.Override{BeverageID: "1", Note: "Racer 5 on cask"}
+
+	assert.Nil(t, RejectMatch(repo, "1", "RateBeer"))
+	override := repo.Override("1")
+	assert.False(t, override.Blocks("RateBeer"), "only the match is rejected")
+	assert.Equal(t, []string{pendingProfile}, override.RejectedURLs("RateBeer"))
+	assert.Equal(t, "Racer 5 on cask", override.Note, "existing override kept")
+	_, chosen := model.ChosenCandidate(bev, "RateBeer")
+	assert.False(t, chosen)
+	rejected := model.RejectedCandidates(bev, "RateBeer")
+	if assert.Equal(t, 1, len(rejected)) {
+		assert.Equal(t, pendingProfile, rejected[0].URL)
+	}
+	assert.Empty(t, model.PendingSources(bev), "review settled")
+	assert.Equal(t, ErrNoPendingReview, RejectMatch(repo, "1", "RateBeer"))
+
+	assert.Nil(t, repo.SaveProfileCandidates("1", "RateBeer", []model.ProfileCandidate{
+		{Source: "RateBeer", URL: otherProfile, Confidence: 0.55, Pending: true},
+		rejected[0],
+	}))
+	assert.Nil(t, AcceptMatch(repo, "1", "RateBeer", pendingProfile), "staff may change their minds")
+	assert.Equal(t, pendingProfile, repo.Override("1").ProfileURL("RateBeer"))
+	assert.Empty(t, repo.Override("1").RejectedURLs("RateBeer"))
+	assert.Empty(t, model.RejectedCandidates(bev, "RateBeer"))
+}
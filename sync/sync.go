@@ -6,8 +6,11 @@ import (
 
 	"github.com/bevly/bevly/fetch/menu"
 	"github.com/bevly/bevly/fetch/metadata"
+	"github.com/bevly/bevly/fetch/metadata/openbrewerydb"
 	"github.com/bevly/bevly/model"
 	"github.com/bevly/bevly/repository"
+	"github.com/bevly/bevly/style"
+	"github.com/bevly/bevly/text"
 )
 
 type Syncer struct {
@@ -48,34 +51,110 @@ func (s *Syncer) TriggerSync(blocking bool) {
 }
 
 func Sync(repo repository.Repository) []error {
-	errors := []error{}
 	log.Println("Syncing all providers")
-	for _, provider := range repo.MenuProviders() {
-		log.Printf("Syncing provider: %s\n", provider)
-		beverages, err := menu.FetchMenu(provider)
-		if err != nil {
-			errors = append(errors, err)
+	return SyncProviders(repo, repo.MenuProviders())
+}
+
+// SyncProviders syncs the menus of providers, then the metadata of the
+// beverages on them that need sync.
+func SyncProviders(repo repository.Repository, providers []model.MenuProvider) []error {
+	errors := []error{}
+	for _, fetched := range FetchMenus(providers) {
+		if fetched.Err != nil {
+			errors = append(errors, fetched.Err)
 			continue
 		}
-
-		priorBeverages := repo.ProviderBeverages(provider)
-		SetBeverageDiscoverTimes(provider, beverages, priorBeverages)
-		repo.SetBeverageMenu(provider, beverages)
+		priorBeverages := repo.ProviderBeverages(fetched.Provider)
+		SetBeverageDiscoverTimes(fetched.Provider, fetched.Beverages, priorBeverages)
+		repo.SetBeverageMenu(fetched.Provider, fetched.Beverages)
 	}
 
-	for _, beverage := range repo.BeveragesNeedingSync() {
-		beverage.SetNeedSync(false)
-		err := metadata.FetchMetadata(beverage)
-		if err != nil {
-			errors = append(errors, err)
+	fetcher := metadata.DefaultFetcher()
+	candidates := menuBeverages(repo)
+	learnBeverageNames(candidates)
+	breweryLookup, err := openbrewerydb.Default()
+	if err != nil {
+		log.Printf("Brewery enrichment disabled: %s\n", err)
+		errors = append(errors, err)
+	}
+	onMenus := map[string]bool{}
+	for _, provider := range providers {
+		for _, beverage := range repo.ProviderBeverages(provider) {
+			onMenus[beverage.ID()] = true
 		}
-		if beverage.NeedSync() {
-			repo.SaveBeverage(beverage)
+	}
+	for _, beverage := range repo.BeveragesNeedingSync() {
+		if !onMenus[beverage.ID()] {
+			continue
 		}
+		report := SyncBeverage(repo, fetcher, beverage, breweryLookup)
+		errors = append(errors, report.Errors()...)
+		ResolveIdentity(repo, beverage, candidates)
 	}
 	return errors
 }
 
+// learnBeverageNames teaches text.DefaultMatcher the names of beverages,
+// so that profile matches weight the words rare among them.
+func learnBeverageNames(beverages []model.Beverage) {
+	names := make([]string, 0, len(beverages))
+	for _, bev := range beverages {
+		names = append(names, bev.DisplayName())
+	}
+	text.DefaultMatcher.LearnNames(names)
+}
+
+// MenuFetch is the menu fetched from a provider, or the error fetching it.
+type MenuFetch struct {
+	Provider  model.MenuProvider
+	Beverages []model.Beverage
+	Err       error
+}
+
+// FetchMenus fetches the menus of providers, detecting the category of
+// their beverages, without saving anything.
+func FetchMenus(providers []model.MenuProvider) []MenuFetch {
+	fetches := []MenuFetch{}
+	for _, provider := range providers {
+		log.Printf("Syncing provider: %s\n", provider)
+		beverages, err := menu.FetchMenu(provider)
+		for _, beverage := range beverages {
+			if beverage.Category() == "" {
+				beverage.SetCategory(style.DetectCategory(beverage))
+			}
+		}
+		fetches = append(fetches, MenuFetch{Provider: provider, Beverages: beverages, Err: err})
+	}
+	return fetches
+}
+
+// SyncBeverage fetches a beverage's metadata, applies its override and
+// resolves its brewery, saving the beverage if anything changed.
+// breweryLookup may be nil.
+func SyncBeverage(repo repository.Repository, fetcher *metadata.Fetcher, beverage model.Beverage, breweryLookup openbrewerydb.Lookup) *metadata.Report {
+	beverage.SetNeedSync(false)
+	override := repo.Override(beverage.ID())
+	report := fetcher.Fetch(beverage, override)
+	if !override.Empty() {
+		// Staff curation has the last word over fetched metadata:
+		model.ApplyOverride(beverage, override)
+		beverage.SetNeedSync(true)
+	}
+	if ResolveBrewery(repo, beverage, breweryLookup) {
+		beverage.SetNeedSync(true)
+	}
+	if beverage.Category() == "" {
+		if category := style.DetectCategory(beverage); category != "" {
+			beverage.SetCategory(category)
+			beverage.SetNeedSync(true)
+		}
+	}
+	if beverage.NeedSync() {
+		repo.SaveBeverage(beverage)
+	}
+	return report
+}
+
 // SetBeverageDiscoverTimes sets the discovery time for each beverage
 // that was not in provider's prior menu.
 func SetBeverageDiscoverTimes(provider model.MenuProvider, bevs []model.Beverage, priorBevs []model.Beverage) {
@@ -0,0 +1,23 @@
+package sync
+
+import (
+	"testing"
+
+	"github.com/bevly/bevly/model"
+	"github.com/bevly/bevly/text"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLearnBeverageNames(t *testing.T) {
+	defer text.DefaultMatcher.LearnNames(nil)
+	untrained := text.MatchName("Founders Porter", "", "Founders Centennial").Confidence
+
+	beverages := []model.Beverage{}
+	for _, name := range []string{"Founders Breakfast Stout", "Founders Porter",
+		"Founders Centennial", "Founders Red's Rye", "Sierra Nevada Torpedo"} {
+		beverages = append(beverages, model.CreateBeverage(name))
+	}
+	learnBeverageNames(beverages)
+	assert.True(t, text.MatchName("Founders Porter", "", "Founders Centennial").Confidence < untrained,
+		"words common on menus weigh less")
+}
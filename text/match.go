@@ -0,0 +1,369 @@
+package text
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+)
+
+// Matcher scores how well a candidate name, such as a search result title,
+// names a beverage. Unlike NameMatchConfidence it:
+//
+// - folds accents and punctuation, so "Kölsch" is "kolsch";
+// - ignores stopwords such as "brewing", "co" and "ale";
+// - accepts misspelled words, by their edit distance;
+// - weights rare words above words common in beer names, such as "ipa";
+// - scores the brewer apart from the rest of the name, when it is known.
+//
+// A Matcher that has Learned a set of names also weights words by how rarely
+// they appear in it.
+type Matcher struct {
+	mutex       sync.RWMutex
+	frequencies map[string]int
+	names       int
+}
+
+func NewMatcher() *Matcher {
+	return &Matcher{frequencies: map[string]int{}}
+}
+
+// DefaultMatcher weights words by CommonWords only until it learns names;
+// syncs teach it the names of the beverages on menus.
+var DefaultMatcher = NewMatcher()
+
+// Learn counts the words of a name toward word rarity.
+func (m *Matcher) Learn(name string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.names++
+	seen := map[string]bool{}
+	for _, token := range MatchTokens(name) {
+		if !seen[token] {
+			seen[token] = true
+			m.frequencies[token]++
+		}
+	}
+}
+
+// LearnNames replaces the names the matcher has learned with names.
+func (m *Matcher) LearnNames(names []string) {
+	learned := NewMatcher()
+	for _, name := range names {
+		learned.Learn(name)
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.frequencies, m.names = learned.frequencies, learned.names
+}
+
+// MatchStopwords never count toward a match.
+var MatchStopwords = wordSet("a an and the of from by de la le et ale ales beer beers " +
+	"brewing brewery breweries brewers brewer brewhouse brewpub brewco " +
+	"craft company co corp inc llc ltd")
+
+// CommonWords are so common in beer names that they say little about which
+// beer is meant; they weigh CommonWordWeight.
+var CommonWords = wordSet("ipa india pale lager stout porter imperial double " +
+	"triple tripel dubbel quad amber red brown golden blonde blond wheat " +
+	"white black light dark session hazy juicy sour saison pilsner pils " +
+	"bock barleywine cider mead hard dry sweet new american english " +
+	"belgian german style series reserve special edition original")
+
+const CommonWordWeight = 0.4
+
+// TokenSimilarity is the least similarity, one less the edit distance over
+// the longer word's length, at which two words match. Numbers and words of
+// three letters or fewer must match exactly.
+const TokenSimilarity = 0.75
+
+func wordSet(words string) map[string]bool {
+	set := map[string]bool{}
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
+
+var accentFolds = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a",
+	'æ': "ae", 'ç': "c", 'č': "c", 'ć': "c", 'ď': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ě': "e", 'ę': "e",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'ł': "l",
+	'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'œ': "oe",
+	'ř': "r", 'š': "s", 'ś': "s", 'ß': "ss", 'ť': "t", 'þ': "th",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u",
+	'ý': "y", 'ÿ': "y", 'ž': "z", 'ź': "z", 'ż': "z",
+}
+
+// FoldAccents lowercases text and replaces accented Latin letters with their
+// unaccented forms.
+func FoldAccents(text string) string {
+	var folded strings.Builder
+	for _, r := range strings.ToLower(text) {
+		if fold, ok := accentFolds[r]; ok {
+			folded.WriteString(fold)
+		} else {
+			folded.WriteRune(r)
+		}
+	}
+	return folded.String()
+}
+
+// MatchTokens splits a name into the words a Matcher compares: folded,
+// without punctuation, and without stopwords. Apostrophes are dropped
+// rather than splitting words, so "Bell's" is "bells".
+func MatchTokens(name string) []string {
+	name = strings.NewReplacer("'", "", "’", "").Replace(FoldAccents(name))
+	tokens := []string{}
+	for _, word := range strings.Fields(rNonAlnum.ReplaceAllString(name, " ")) {
+		if !MatchStopwords[word] {
+			tokens = append(tokens, word)
+		}
+	}
+	return tokens
+}
+
+// tokenSimilarity is 1 for identical words, and otherwise one less their
+// edit distance over the longer's length, or 0 if that is below
+// TokenSimilarity.
+func tokenSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) <= 3 || len(rb) <= 3 || rNumber.MatchString(a) || rNumber.MatchString(b) {
+		return 0
+	}
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	similarity := 1 - float64(editDistance(ra, rb))/float64(longest)
+	if similarity < TokenSimilarity {
+		return 0
+	}
+	return similarity
+}
+
+// editDistance is the Levenshtein distance between two words.
+func editDistance(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func minInt(values ...int) int {
+	min := values[0]
+	for _, value := range values[1:] {
+		if value < min {
+			min = value
+		}
+	}
+	return min
+}
+
+// weight is how much a word says about which beverage is meant.
+func (m *Matcher) weight(token string) float64 {
+	weight := 1.0
+	if CommonWords[token] {
+		weight = CommonWordWeight
+	}
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	if m.names > 1 {
+		// Scale by inverse document frequency, from 1 for words seen in
+		// one name down to a quarter for words seen in all of them:
+		idf := math.Log(float64(m.names)/float64(m.frequencies[token]+1)+1) /
+			math.Log(float64(m.names)+1)
+		weight *= math.Max(0.25, math.Min(1, idf))
+	}
+	return weight
+}
+
+// TokenMatch explains how one word of the beverage's name matched.
+type TokenMatch struct {
+	Token      string
+	Matched    string
+	Similarity float64
+	Weight     float64
+}
+
+// NameMatch explains a Matcher's confidence in a candidate.
+type NameMatch struct {
+	Query     string
+	Candidate string
+	// Confidence, from 0 to 1, combines the name and brewer scores.
+	Confidence float64
+	// NameScore is the weighted F1 of the name's and the candidate's words
+	// matching each other.
+	NameScore float64
+	// BrewerScore is the weighted fraction of the brewer's words the
+	// candidate has, or -1 if the brewer is unknown.
+	BrewerScore float64
+	// Tokens explains how each word of the name, excluding the brewer's,
+	// matched.
+	Tokens []TokenMatch
+	// Unmatched lists the candidate's words that matched nothing.
+	Unmatched []string
+	// NumberMismatch is set if the name and the candidate both have
+	// numbers, but not the same ones: "60 Minute" is not "90 Minute".
+	NumberMismatch bool
+}
+
+func (m NameMatch) String() string {
+	parts := []string{}
+	for _, token := range m.Tokens {
+		if token.Matched == "" {
+			parts = append(parts, fmt.Sprintf("%s missing", token.Token))
+		} else if token.Matched == token.Token {
+			parts = append(parts, fmt.Sprintf("%s (weight %.2f)", token.Token, token.Weight))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s~%s %.2f (weight %.2f)",
+				token.Token, token.Matched, token.Similarity, token.Weight))
+		}
+	}
+	if len(m.Unmatched) > 0 {
+		parts = append(parts, "extra "+strings.Join(m.Unmatched, " "))
+	}
+	if m.NumberMismatch {
+		parts = append(parts, "numbers differ")
+	}
+	brewer := "unknown"
+	if m.BrewerScore >= 0 {
+		brewer = fmt.Sprintf("%.2f", m.BrewerScore)
+	}
+	return fmt.Sprintf("%q ~ %q: confidence %.2f (name %.2f, brewer %s): %s",
+		m.Query, m.Candidate, m.Confidence, m.NameScore, brewer,
+		strings.Join(parts, ", "))
+}
+
+// Match scores candidate as a name for the beverage called name, made by
+// brewer. The brewer may be "" if unknown, and name may include it. The
+// brewer's words are scored apart from the name's, wherever they appear in
+// the candidate; a candidate naming the right beer from the wrong brewer
+// loses half its confidence.
+func (m *Matcher) Match(name, brewer, candidate string) NameMatch {
+	result := NameMatch{Query: name, Candidate: candidate, BrewerScore: -1}
+	nameTokens := MatchTokens(name)
+	candidateTokens := MatchTokens(candidate)
+	// How each candidate word matched: by the brewer, or by the name with
+	// the given similarity. Unmatched words are neither.
+	byBrewer := make([]bool, len(candidateTokens))
+	similarities := make([]float64, len(candidateTokens))
+	used := func(i int) bool { return byBrewer[i] || similarities[i] > 0 }
+
+	if brewerTokens := MatchTokens(brewer); len(brewerTokens) > 0 {
+		nameTokens = withoutTokens(nameTokens, brewerTokens)
+		found, total := 0.0, 0.0
+		for _, token := range brewerTokens {
+			weight := m.weight(token)
+			total += weight
+			if i, similarity := bestToken(token, candidateTokens, used); i >= 0 {
+				byBrewer[i] = true
+				found += weight * similarity
+			}
+		}
+		result.BrewerScore = found / total
+	}
+
+	queryWeight, queryFound := 0.0, 0.0
+	for _, token := range nameTokens {
+		match := TokenMatch{Token: token, Weight: m.weight(token)}
+		if i, similarity := bestToken(token, candidateTokens, used); i >= 0 {
+			similarities[i] = similarity
+			match.Matched = candidateTokens[i]
+			match.Similarity = similarity
+		}
+		queryWeight += match.Weight
+		queryFound += match.Weight * match.Similarity
+		result.Tokens = append(result.Tokens, match)
+	}
+	candidateWeight, candidateFound := 0.0, 0.0
+	candidateNumbers := []string{}
+	for i, token := range candidateTokens {
+		if byBrewer[i] {
+			continue
+		}
+		if rNumber.MatchString(token) {
+			candidateNumbers = append(candidateNumbers, token)
+		}
+		weight := m.weight(token)
+		candidateWeight += weight
+		candidateFound += weight * similarities[i]
+		if similarities[i] == 0 {
+			result.Unmatched = append(result.Unmatched, token)
+		}
+	}
+
+	nameNumbers := numberWords(nameTokens)
+	result.NumberMismatch = len(nameNumbers) > 0 && len(candidateNumbers) > 0 &&
+		strings.Join(nameNumbers, " ") != strings.Join(candidateNumbers, " ")
+	if queryFound > 0 && !result.NumberMismatch {
+		recall := queryFound / queryWeight
+		precision := candidateFound / candidateWeight
+		result.NameScore = 2 * recall * precision / (recall + precision)
+	}
+	result.Confidence = result.NameScore
+	if result.BrewerScore >= 0 {
+		result.Confidence *= 0.5 + 0.5*result.BrewerScore
+	}
+	return result
+}
+
+// MatchName is DefaultMatcher's match of candidate as a name for the
+// beverage called name, made by brewer.
+func MatchName(name, brewer, candidate string) NameMatch {
+	return DefaultMatcher.Match(name, brewer, candidate)
+}
+
+// bestToken finds the unused candidate word most similar to token.
+func bestToken(token string, candidates []string, used func(int) bool) (int, float64) {
+	best, bestSimilarity := -1, 0.0
+	for i, candidate := range candidates {
+		if used(i) {
+			continue
+		}
+		if similarity := tokenSimilarity(token, candidate); similarity > bestSimilarity {
+			best, bestSimilarity = i, similarity
+		}
+	}
+	return best, bestSimilarity
+}
+
+// withoutTokens removes the brewer's words from a name that starts or ends
+// with them.
+func withoutTokens(tokens, remove []string) []string {
+	n := len(remove)
+	if len(tokens) > n && tokensEqual(tokens[:n], remove) {
+		return tokens[n:]
+	}
+	if len(tokens) > n && tokensEqual(tokens[len(tokens)-n:], remove) {
+		return tokens[:len(tokens)-n]
+	}
+	return tokens
+}
+
+func tokensEqual(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
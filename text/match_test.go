@@ -0,0 +1,68 @@
+package text
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchTokens(t *testing.T) {
+	assert.Equal(t, []string{"bells", "two", "hearted"},
+		MatchTokens("Bell's Brewery - Two Hearted Ale"))
+	assert.Equal(t, []string{"reissdorf", "kolsch"}, MatchTokens("Reissdorf Kölsch"))
+}
+
+func TestMatchName(t *testing.T) {
+	const threshold = 0.5
+	accept := func(name, brewer, candidate string) {
+		match := MatchName(name, brewer, candidate)
+		assert.True(t, match.Confidence >= threshold, match.String())
+	}
+	reject := func(name, brewer, candidate string) {
+		match := MatchName(name, brewer, candidate)
+		assert.True(t, match.Confidence < threshold, match.String())
+	}
+
+	accept("Victory Golden Monkey", "", "Victory Golden Monkey")
+	accept("Baird Suruga Bay Imperial IPA", "", "Baird Suruga Bay Imperial IPA - RateBeer")
+	accept("Bear Republic Racr 5", "", "Racer 5 India Pale Ale - Bear Republic Brewing Co.")
+	accept("Reissdorf Kolsch", "", "Reissdorf Kölsch")
+	reject("Bud", "", "Budweiser Bud Light Lime")
+	reject("Dogfish Head 60 Minute IPA", "", "Dogfish Head 90 Minute IPA")
+	reject("Push Ob\"session\"", "", "Green Flash Hop Head Red Ale - Beer Advocate")
+
+	// The brewer is scored apart:
+	match := MatchName("Racer 5", "Bear Republic Brewing Co.", "Racer 5 India Pale Ale - Bear Republic")
+	assert.Equal(t, 1.0, match.BrewerScore)
+	assert.Equal(t, []string{"india", "pale"}, match.Unmatched)
+	assert.True(t, match.Confidence >= threshold, match.String())
+	wrongBrewer := MatchName("Racer 5", "Bear Republic", "Racer 5 - Homebrew Heroes")
+	assert.Equal(t, 0.0, wrongBrewer.BrewerScore)
+	assert.True(t, wrongBrewer.Confidence < match.Confidence, wrongBrewer.String())
+
+	// Common words weigh less than distinctive ones:
+	assert.True(t,
+		MatchName("Stone Enjoy By IPA", "", "Stone Enjoy By").Confidence >
+			MatchName("Stone Enjoy By IPA", "", "Stone IPA").Confidence)
+}
+
+func TestMatcherLearn(t *testing.T) {
+	matcher := NewMatcher()
+	for _, name := range []string{"Founders Breakfast Stout", "Founders Porter",
+		"Founders Centennial", "Founders Red's Rye", "Sierra Nevada Torpedo"} {
+		matcher.Learn(name)
+	}
+	// "founders" is in most names, so it says little:
+	assert.True(t,
+		matcher.Match("Founders Porter", "", "Founders Centennial").Confidence <
+			DefaultMatcher.Match("Founders Porter", "", "Founders Centennial").Confidence)
+}
+
+func TestMatcherLearnNames(t *testing.T) {
+	matcher := NewMatcher()
+	matcher.Learn("Sierra Nevada Torpedo")
+	matcher.LearnNames([]string{"Founders Breakfast Stout", "Founders Porter", "Founders Centennial"})
+	assert.Equal(t, 3, matcher.names, "learned names are replaced")
+	assert.Equal(t, 0, matcher.frequencies["torpedo"])
+	assert.Equal(t, 3, matcher.frequencies["founders"])
+}
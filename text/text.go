@@ -64,3 +64,68 @@ func NameMatchConfidence(a, b string) float64 {
 func SplitWords(text string) []string {
 	return rWhitespace.Split(text, -1)
 }
+
+var rBreweryNoise = regexp.MustCompile(`\b(?:craft|brewing|brewery|breweries|brewers|brewhouse|beer|beers|company|co|inc|llc|ltd)\b`)
+var rNonAlnum = regexp.MustCompile(`[^\pL\pN]+`)
+
+// BreweryKey reduces a brewery name to a key that different spellings of
+// the same brewery share: "Dogfish Head", "Dogfish Head Craft Brewery" and
+// "- Dogfish Head" all have the key "dogfish head".
+func BreweryKey(name string) string {
+	name = strings.ToLower(rNonAlnum.ReplaceAllString(name, " "))
+	key := Normalize(rBreweryNoise.ReplaceAllString(name, " "))
+	if key == "" {
+		return Normalize(name)
+	}
+	return key
+}
+
+// NameIdentityConfidence returns how likely two beverage names are to name
+// the same beverage: the smaller of the fractions of each name's words that
+// the other shares. Unlike NameMatchConfidence, a name is not matched by a
+// longer name containing it, brewery noise words such as "Brewing" are
+// ignored, and names with different numbers never match ("60 Minute" is not
+// "90 Minute").
+func NameIdentityConfidence(a, b string) float64 {
+	wordsA := identityWords(a)
+	wordsB := identityWords(b)
+	if len(wordsA) == 0 || len(wordsB) == 0 ||
+		strings.Join(numberWords(wordsA), " ") != strings.Join(numberWords(wordsB), " ") {
+		return 0
+	}
+
+	setB := map[string]bool{}
+	for _, word := range wordsB {
+		setB[word] = true
+	}
+	nIntersect := 0
+	for _, word := range wordsA {
+		if setB[word] {
+			nIntersect++
+		}
+	}
+	return math.Min(
+		float64(nIntersect)/float64(len(wordsA)),
+		float64(nIntersect)/float64(len(wordsB)))
+}
+
+func identityWords(name string) []string {
+	name = strings.ToLower(rNonAlnum.ReplaceAllString(name, " "))
+	name = Normalize(rBreweryNoise.ReplaceAllString(name, " "))
+	if name == "" {
+		return nil
+	}
+	return strings.Split(name, " ")
+}
+
+var rNumber = regexp.MustCompile(`^\pN+$`)
+
+func numberWords(words []string) []string {
+	numbers := []string{}
+	for _, word := range words {
+		if rNumber.MatchString(word) {
+			numbers = append(numbers, word)
+		}
+	}
+	return numbers
+}
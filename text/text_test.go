@@ -52,3 +52,19 @@ func TestStripNonNumeric(t *testing.T) {
 		}
 	}
 }
+
+func TestBreweryKey(t *testing.T) {
+	assert.Equal(t, "dogfish head", BreweryKey("Dogfish Head"))
+	assert.Equal(t, "dogfish head", BreweryKey("Dogfish Head Craft Brewery"))
+	assert.Equal(t, "dogfish head", BreweryKey("- Dogfish Head"))
+	assert.Equal(t, "oliver", BreweryKey("OLIVER BREWING CO."))
+	assert.Equal(t, "brewery", BreweryKey("Brewery"), "all noise words")
+}
+
+func TestNameIdentityConfidence(t *testing.T) {
+	assert.Equal(t, 1.0, NameIdentityConfidence("Oliver Bmore Gold", "Oliver Brewing Bmore Gold"))
+	assert.Equal(t, 0.8, NameIdentityConfidence("Heavy Seas Loose Cannon", "Heavy Seas Loose Cannon IPA"))
+	assert.True(t, NameIdentityConfidence("Oliver IPA", "Oliver Double IPA") < 0.7)
+	assert.Equal(t, 0.0, NameIdentityConfidence("Dogfish Head 60 Minute IPA", "Dogfish Head 90 Minute IPA"))
+	assert.Equal(t, 0.0, NameIdentityConfidence("", "Brewing"))
+}
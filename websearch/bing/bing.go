@@ -29,15 +29,20 @@ type BingSearch struct {
 	auth        string
 }
 
+// DefaultSearch searches with the API key BING_API_KEY names, panicking if
+// it can't be read.
 func DefaultSearch() websearch.Search {
-	bing := &BingSearch{
-		BaseURL:     SearchBaseURL,
-		ApiKey:      DefaultApiKey(),
-		ResultCount: 10,
-		agent:       httpagent.New(),
+	return SearchWithURLKey(SearchBaseURL, DefaultApiKey())
+}
+
+// ConfiguredSearch searches as DefaultSearch does, but fails with a
+// websearch.UnavailableError if the API key can't be read.
+func ConfiguredSearch() (websearch.Search, error) {
+	apiKey, err := ReadApiKey()
+	if err != nil {
+		return nil, &websearch.UnavailableError{Backend: "bing", Err: err}
 	}
-	bing.init()
-	return bing
+	return SearchWithURLKey(SearchBaseURL, apiKey), nil
 }
 
 func SearchWithURLKey(url, apiKey string) websearch.Search {
@@ -91,8 +96,9 @@ type bingContainer struct {
 }
 
 type bingResult struct {
-	URL  string `json:"Url"`
-	Text string `json:"Title"`
+	URL         string `json:"Url"`
+	Text        string `json:"Title"`
+	Description string `json:"Description"`
 }
 
 func (b *BingSearch) parseResult(res *http.Response) ([]websearch.Result, error) {
@@ -115,8 +121,9 @@ func (b *BingSearch) parseResult(res *http.Response) ([]websearch.Result, error)
 	webresults := make([]websearch.Result, 0, len(results))
 	for _, res := range results {
 		webresults = append(webresults, websearch.Result{
-			URL:  res.URL,
-			Text: res.Text,
+			URL:     res.URL,
+			Text:    res.Text,
+			Snippet: res.Description,
 		})
 	}
 	return webresults, nil
@@ -132,10 +139,22 @@ func (b *BingSearch) SearchURL(terms string) string {
 }
 
 func DefaultApiKey() string {
+	apiKey, err := ReadApiKey()
+	if err != nil {
+		panic(err)
+	}
+	return apiKey
+}
+
+// ReadApiKey reads the API key from the file BING_API_KEY names.
+func ReadApiKey() (string, error) {
 	file := os.Getenv("BING_API_KEY")
+	if file == "" {
+		return "", errors.New("BING_API_KEY is not set")
+	}
 	content, err := ioutil.ReadFile(file)
 	if err != nil {
-		panic(fmt.Errorf("could not read Bing API key from %s: %s", file, err))
+		return "", fmt.Errorf("could not read Bing API key from %s: %s", file, err)
 	}
-	return strings.TrimSpace(string(content))
+	return strings.TrimSpace(string(content)), nil
 }
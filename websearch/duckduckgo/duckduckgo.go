@@ -82,9 +82,11 @@ func jsonResults(json []interface{}) []websearch.Result {
 				url := jsonMap["u"].(string)
 				text := html.Text(jsonMap["t"].(string))
 				if url != "" && text != "" {
+					snippet, _ := jsonMap["a"].(string)
 					results = append(results, websearch.Result{
-						URL:  url,
-						Text: text,
+						URL:     url,
+						Text:    text,
+						Snippet: html.Text(snippet),
 					})
 				}
 			}
@@ -1,11 +1,25 @@
 package websearch
 
+// Result is a search result: a link and its title. Snippet is any other
+// text listed with it, such as a summary of the page.
 type Result struct {
-	URL  string
-	Text string
+	URL     string
+	Text    string
+	Snippet string
 }
 
 type Search interface {
 	Search(terms string) ([]Result, error)
 	SearchURL(terms string) string
 }
+
+// UnavailableError reports a search backend that can't be used, such as one
+// missing its API key.
+type UnavailableError struct {
+	Backend string
+	Err     error
+}
+
+func (e *UnavailableError) Error() string {
+	return "web search unavailable: " + e.Backend + ": " + e.Err.Error()
+}